@@ -0,0 +1,167 @@
+package main
+
+import (
+        "context"
+        "flag"
+        "fmt"
+        "os"
+        "text/tabwriter"
+
+        "khatabook-go-backend/internal/models"
+        "khatabook-go-backend/internal/services"
+        "khatabook-go-backend/pkg/audit"
+        "khatabook-go-backend/pkg/logger"
+
+        "gorm.io/gorm"
+)
+
+// runCommand runs a maintenance subcommand and returns the process exit code
+func runCommand(db *gorm.DB, args []string) int {
+        switch args[0] {
+        case "backfill":
+                return runBackfill(db, args[1:])
+        case "reconcile":
+                return runReconcile(db, args[1:])
+        case "verify-chain":
+                return runVerifyChain(db, args[1:])
+        default:
+                fmt.Fprintf(os.Stderr, "Unknown command %q\nUsage: main [backfill|reconcile|verify-chain]\n", args[0])
+                return 2
+        }
+}
+
+// runBackfill brings data recorded before the journal and the ledger hash
+// chain existed into them, reporting stored balances the transactions do not
+// account for
+func runBackfill(db *gorm.DB, args []string) int {
+        fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+        bookOpenings := fs.Bool("book-openings", false, "book unexplained balances as opening transactions")
+        if err := fs.Parse(args); err != nil {
+                return 2
+        }
+
+        // Openings booked by the backfill are audited as the system
+        db = db.WithContext(audit.WithActor(context.Background(), audit.System))
+
+        report, err := services.NewJournalService(db).Backfill(*bookOpenings)
+        if err != nil {
+                fmt.Fprintf(os.Stderr, "Journal backfill failed: %v\n", err)
+                return 1
+        }
+        if err := services.NewChainService(db).Backfill(); err != nil {
+                fmt.Fprintf(os.Stderr, "Chain backfill failed: %v\n", err)
+                return 1
+        }
+
+        w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+        fmt.Fprintln(w, "PARTY\tNAME\tSTORED\tFROM TRANSACTIONS\tDIFFERENCE\tBOOKED")
+        for _, d := range report.Differences {
+                fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\n",
+                        d.PartyID, d.PartyName, d.StoredBalance, d.FromTransactions, d.Difference, d.Booked)
+        }
+        w.Flush()
+
+        fmt.Printf("Posted %d transactions, %d parties with unexplained balances\n", report.Posted, len(report.Differences))
+        if len(report.Differences) > 0 && !*bookOpenings {
+                return 1
+        }
+        return 0
+}
+
+// warnPendingBackfill logs when there are transactions the backfill command
+// has not yet brought into the journal or the hash chain
+func warnPendingBackfill(db *gorm.DB) {
+        var unposted, unchained int64
+        posted := db.Model(&models.JournalEntry{}).Select("transaction_id").Where("transaction_id IS NOT NULL")
+        if err := db.Model(&models.Transaction{}).Where("id NOT IN (?)", posted).Count(&unposted).Error; err != nil {
+                logger.Errorf("Checking for unposted transactions failed: %v", err)
+                return
+        }
+        if err := db.Unscoped().Model(&models.Transaction{}).Where("chain_seq IS NULL").Count(&unchained).Error; err != nil {
+                logger.Errorf("Checking for unchained transactions failed: %v", err)
+                return
+        }
+        if unposted > 0 || unchained > 0 {
+                logger.Warnf("%d transactions are not in the journal and %d are not in the hash chain; run the backfill command", unposted, unchained)
+        }
+}
+
+// runReconcile reports party balance drift and optionally repairs it
+func runReconcile(db *gorm.DB, args []string) int {
+        fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+        repair := fs.Bool("repair", false, "rewrite drifted balances inside a DB transaction")
+        userID := fs.String("user", "", "only reconcile parties of this user ID")
+        if err := fs.Parse(args); err != nil {
+                return 2
+        }
+
+        report, appErr := services.NewReconcileService(db).Reconcile(*userID, *repair)
+        if appErr != nil {
+                fmt.Fprintf(os.Stderr, "Reconcile failed: %v\n", appErr)
+                return 1
+        }
+
+        w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+        fmt.Fprintln(w, "PARTY\tNAME\tSTORED\tPOSTED\tCOMPUTED\tDRIFT\tRUNNING ERRORS\tREPAIRED")
+        for _, d := range report.Drifts {
+                fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%t\n",
+                        d.PartyID, d.PartyName, d.StoredBalance, d.PostedBalance, d.ComputedBalance, d.Drift, d.RunningBalanceErrors, d.Repaired)
+        }
+        w.Flush()
+
+        fmt.Printf("Checked %d parties, %d with drift\n", report.PartiesChecked, report.PartiesDrifted)
+        if report.PartiesDrifted > 0 && !*repair {
+                return 1
+        }
+        return 0
+}
+
+// runVerifyChain walks the ledger hash chain of one user, or of every user,
+// and reports the first break in each
+func runVerifyChain(db *gorm.DB, args []string) int {
+        fs := flag.NewFlagSet("verify-chain", flag.ContinueOnError)
+        userID := fs.String("user", "", "only verify the chain of this user ID")
+        if err := fs.Parse(args); err != nil {
+                return 2
+        }
+
+        chain := services.NewChainService(db)
+        users := []string{*userID}
+        if *userID == "" {
+                var err error
+                if users, err = chain.ChainUsers(); err != nil {
+                        fmt.Fprintf(os.Stderr, "Verify failed: %v\n", err)
+                        return 1
+                }
+        }
+
+        w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+        fmt.Fprintln(w, "USER\tLINKS\tTRANSACTIONS\tBREAKS\tFIRST BREAK")
+        broken := 0
+        for _, id := range users {
+                report, appErr := chain.Verify(id)
+                if appErr != nil {
+                        w.Flush()
+                        fmt.Fprintf(os.Stderr, "Verify failed: %v\n", appErr)
+                        return 1
+                }
+
+                firstBreak := "-"
+                if report.FirstBreak != nil {
+                        broken++
+                        firstBreak = fmt.Sprintf("link %d", report.FirstBreak.Seq)
+                        if report.FirstBreak.TransactionID != "" {
+                                firstBreak += " transaction " + report.FirstBreak.TransactionID
+                        }
+                        firstBreak += ": " + report.FirstBreak.Reason
+                }
+                fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\n", report.UserID, report.Links, report.Transactions, report.Breaks, firstBreak)
+        }
+        w.Flush()
+
+        fmt.Printf("Verified %d chains, %d broken\n", len(users), broken)
+        if broken > 0 {
+                return 1
+        }
+        return 0
+}
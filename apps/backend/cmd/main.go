@@ -30,8 +30,21 @@ func main() {
                 log.Fatalf("Failed to initialize database: %v", err)
         }
 
+        // Run a maintenance subcommand instead of the server when one is given
+        if len(os.Args) > 1 {
+                os.Exit(runCommand(db, os.Args[1:]))
+        }
+
+        // Data recorded before the journal or the hash chain existed is only
+        // migrated by the backfill command
+        warnPendingBackfill(db)
+
         // Create handler with dependencies
-        h := handlers.NewHandler(db, cfg.JWTSecret)
+        dispatcher, err := newReminderDispatcher(db, cfg)
+        if err != nil {
+                log.Fatalf("Failed to set up notifications: %v", err)
+        }
+        h := handlers.NewHandler(db, cfg.JWTSecret, dispatcher)
 
         // Setup Gin router with middleware
         router := setupRouter(h, cfg)
@@ -45,6 +58,11 @@ func main() {
                 IdleTimeout:  60 * time.Second,
         }
 
+        // Start background jobs; they stop when the server shuts down
+        workerCtx, stopWorkers := context.WithCancel(context.Background())
+        defer stopWorkers()
+        startWorkers(workerCtx, db, dispatcher, cfg)
+
         // Start server in goroutine
         go func() {
                 logger.Info(fmt.Sprintf("Starting server on port %s", cfg.Port))
@@ -59,6 +77,7 @@ func main() {
         <-quit
 
         logger.Info("Shutting down server...")
+        stopWorkers()
         ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
         defer cancel()
 
@@ -90,6 +109,16 @@ func setupRouter(h *handlers.Handler, cfg *config.Config) *gin.Engine {
                 auth.POST("/logout", h.LogoutUser)
         }
 
+        // Admin routes (X-Admin-Token)
+        admin := router.Group("/api/admin")
+        admin.Use(middleware.AdminRequired(cfg.AdminToken))
+        {
+                admin.POST("/reconcile", h.ReconcileBalances)
+        }
+
+        // Reminder calendar feed (token query parameter, for calendar apps)
+        router.GET("/api/reminders/calendar.ics", middleware.FeedTokenRequired(h.CalendarFeedUser), h.GetReminderCalendar)
+
         // Protected routes
         api := router.Group("/api")
         api.Use(middleware.AuthRequired(cfg.JWTSecret))
@@ -110,6 +139,17 @@ func setupRouter(h *handlers.Handler, cfg *config.Config) *gin.Engine {
                         parties.GET("/:id", h.GetParty)
                         parties.PUT("/:id", h.UpdateParty)
                         parties.DELETE("/:id", h.DeleteParty)
+                        parties.POST("/:id/restore", h.RestoreParty)
+                        parties.POST("/:id/archive", h.ArchiveParty)
+                        parties.POST("/:id/unarchive", h.UnarchiveParty)
+                        parties.GET("/:id/statement.pdf", h.GetPartyStatementPDF)
+                        parties.GET("/:id/bills", h.GetPartyBills)
+                        parties.POST("/:id/allocate", h.AutoAllocateParty)
+                        parties.GET("/:id/interest-terms", h.GetInterestTerms)
+                        parties.PUT("/:id/interest-terms", h.SetInterestTerms)
+                        parties.DELETE("/:id/interest-terms", h.DeleteInterestTerms)
+                        parties.GET("/:id/interest", h.PreviewInterest)
+                        parties.POST("/:id/interest", h.PostInterest)
                 }
 
                 // Transaction routes
@@ -119,16 +159,28 @@ func setupRouter(h *handlers.Handler, cfg *config.Config) *gin.Engine {
                         transactions.POST("", h.CreateTransaction)
                         transactions.GET("/:id", h.GetTransaction)
                         transactions.PUT("/:id", h.UpdateTransaction)
+                        transactions.GET("/:id/allocations", h.GetTransactionAllocations)
+                        transactions.POST("/:id/allocations", h.AllocatePayment)
+                        transactions.POST("/:id/restore", h.RestoreTransaction)
+                        transactions.POST("/:id/reverse", h.ReverseTransaction)
                 }
 
                 // Reminder routes
                 reminders := api.Group("/reminders")
                 {
                         reminders.GET("", h.GetReminders)
+                        reminders.GET("/calendar-token", h.GetCalendarToken)
+                        reminders.POST("/calendar-token", h.CreateCalendarToken)
+                        reminders.DELETE("/calendar-token", h.RevokeCalendarToken)
                         reminders.POST("", h.CreateReminder)
                         reminders.GET("/:id", h.GetReminder)
                         reminders.PUT("/:id", h.UpdateReminder)
                         reminders.DELETE("/:id", h.DeleteReminder)
+                        reminders.POST("/:id/restore", h.RestoreReminder)
+                        reminders.POST("/:id/send", h.SendReminder)
+                        reminders.GET("/:id/deliveries", h.GetReminderDeliveries)
+                        reminders.POST("/:id/snooze", h.SnoozeReminder)
+                        reminders.GET("/:id/history", h.GetReminderHistory)
                 }
 
                 // Sync routes
@@ -141,6 +193,78 @@ func setupRouter(h *handlers.Handler, cfg *config.Config) *gin.Engine {
                         reports.GET("/summary", h.GetReportSummary)
                         reports.GET("/daily", h.GetDailyReport)
                         reports.GET("/party-wise", h.GetPartyWiseReport)
+                        reports.GET("/fx", h.GetFXReport)
+                        reports.GET("/aging", h.GetAgingReport)
+                }
+
+                // Invoice routes
+                invoices := api.Group("/invoices")
+                {
+                        invoices.GET("", h.GetInvoices)
+                        invoices.POST("", h.CreateInvoice)
+                        invoices.GET("/:id", h.GetInvoice)
+                        invoices.PUT("/:id", h.UpdateInvoice)
+                        invoices.DELETE("/:id", h.DeleteInvoice)
+                        invoices.POST("/:id/post", h.PostInvoice)
+                        invoices.POST("/:id/cancel", h.CancelInvoice)
+                }
+
+                // Reminder template routes
+                reminderTemplates := api.Group("/reminder-templates")
+                {
+                        reminderTemplates.GET("", h.GetReminderTemplates)
+                        reminderTemplates.POST("", h.CreateReminderTemplate)
+                        reminderTemplates.POST("/preview", h.PreviewReminderTemplate)
+                        reminderTemplates.GET("/:id", h.GetReminderTemplate)
+                        reminderTemplates.PUT("/:id", h.UpdateReminderTemplate)
+                        reminderTemplates.DELETE("/:id", h.DeleteReminderTemplate)
+                }
+
+                // Reminder rule routes
+                reminderRules := api.Group("/reminder-rules")
+                {
+                        reminderRules.GET("", h.GetReminderRules)
+                        reminderRules.POST("", h.CreateReminderRule)
+                        reminderRules.PUT("/:id", h.UpdateReminderRule)
+                        reminderRules.DELETE("/:id", h.DeleteReminderRule)
+                }
+
+                // Recurring transaction routes
+                recurring := api.Group("/recurring")
+                {
+                        recurring.GET("", h.GetRecurring)
+                        recurring.POST("", h.CreateRecurring)
+                        recurring.GET("/:id", h.GetRecurringByID)
+                        recurring.PUT("/:id", h.UpdateRecurring)
+                        recurring.DELETE("/:id", h.DeleteRecurring)
+                        recurring.GET("/:id/occurrences", h.GetRecurringOccurrences)
+                }
+
+                // Trash routes
+                api.GET("/trash", h.GetTrash)
+
+                // Audit log routes
+                api.GET("/audit", h.GetAuditLog)
+
+                // Ledger hash chain routes
+                api.GET("/ledger/verify", h.VerifyLedgerChain)
+
+                // Allocation routes
+                api.DELETE("/allocations/:id", h.DeleteAllocation)
+
+                // Exchange rate routes
+                exchangeRates := api.Group("/exchange-rates")
+                {
+                        exchangeRates.GET("", h.GetExchangeRates)
+                        exchangeRates.POST("", h.SetExchangeRate)
+                        exchangeRates.DELETE("/:id", h.DeleteExchangeRate)
+                }
+
+                // Journal routes
+                journal := api.Group("/journal")
+                {
+                        journal.GET("/accounts", h.GetJournalAccounts)
+                        journal.GET("/entries", h.GetJournalEntries)
                 }
 
                 // Delete transaction route
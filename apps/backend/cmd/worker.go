@@ -0,0 +1,130 @@
+package main
+
+import (
+        "context"
+        "io"
+        "time"
+
+        "khatabook-go-backend/internal/config"
+        "khatabook-go-backend/internal/services"
+        "khatabook-go-backend/pkg/audit"
+        "khatabook-go-backend/pkg/logger"
+        "khatabook-go-backend/pkg/notify"
+
+        "gorm.io/gorm"
+)
+
+// startWorkers starts the background jobs. They run once straight away, to
+// catch up on anything due while the server was down, and then every
+// configured interval until ctx is cancelled.
+func startWorkers(ctx context.Context, db *gorm.DB, dispatcher *services.ReminderDispatcher, cfg *config.Config) {
+        interval := cfg.WorkerInterval
+
+        // Changes made by the jobs are audited as the system
+        ctx = audit.WithActor(ctx, audit.System)
+        db = db.WithContext(ctx)
+
+        recurring := services.NewRecurringService(db)
+        go runPeriodically(ctx, "recurring transactions", interval, func() error {
+                created, err := recurring.RunDue(time.Now().Format("2006-01-02"))
+                if created > 0 {
+                        logger.Infof("Created %d recurring transactions", created)
+                }
+                return err
+        })
+
+        rules := services.NewReminderRuleService(db)
+        go runPeriodically(ctx, "reminder rules", interval, func() error {
+                created, err := rules.RunRules(time.Now().Format("2006-01-02"))
+                if created > 0 {
+                        logger.Infof("Created %d reminders from rules", created)
+                }
+                return err
+        })
+
+        go runPeriodically(ctx, "reminder delivery", interval, func() error {
+                sent, err := dispatcher.DispatchDue(ctx, time.Now())
+                if sent > 0 {
+                        logger.Infof("Sent %d reminders", sent)
+                }
+                return err
+        })
+
+        trash := services.NewTrashService(db)
+        go runPeriodically(ctx, "trash purge", interval, func() error {
+                purged, err := trash.Purge(time.Now().AddDate(0, 0, -cfg.TrashRetentionDays))
+                if purged > 0 {
+                        logger.Infof("Purged %d items from the trash", purged)
+                }
+                return err
+        })
+}
+
+// newReminderDispatcher sets up a notifier for every configured channel.
+// Outside production the remaining channels are logged instead, so that
+// reminders can be tried without provider accounts.
+func newReminderDispatcher(db *gorm.DB, cfg *config.Config) (*services.ReminderDispatcher, error) {
+        notifiers := map[string]notify.Notifier{}
+        if cfg.SMTPHost != "" {
+                notifiers[notify.Email] = notify.NewSMTP(notify.SMTPConfig{
+                        Host:     cfg.SMTPHost,
+                        Port:     cfg.SMTPPort,
+                        Username: cfg.SMTPUsername,
+                        Password: cfg.SMTPPassword,
+                        From:     cfg.SMTPFrom,
+                })
+        }
+        if cfg.SMSGatewayURL != "" {
+                notifiers[notify.SMS] = notify.NewSMS(notify.SMSConfig{
+                        URL:    cfg.SMSGatewayURL,
+                        APIKey: cfg.SMSGatewayKey,
+                        Sender: cfg.SMSSender,
+                })
+        }
+        if cfg.WhatsAppToken != "" {
+                notifiers[notify.WhatsApp] = notify.NewWhatsApp(notify.WhatsAppConfig{
+                        URL:           cfg.WhatsAppURL,
+                        Token:         cfg.WhatsAppToken,
+                        PhoneNumberID: cfg.WhatsAppPhoneID,
+                        Template:      cfg.WhatsAppTemplate,
+                        Language:      cfg.WhatsAppLanguage,
+                })
+        }
+
+        if cfg.Environment != "production" {
+                var w io.Writer
+                if cfg.NotifyLogFile != "" {
+                        file, err := notify.OpenLogFile(cfg.NotifyLogFile)
+                        if err != nil {
+                                return nil, err
+                        }
+                        w = file
+                }
+                for _, channel := range notify.Channels {
+                        if _, ok := notifiers[channel]; !ok {
+                                notifiers[channel] = notify.NewLog(channel, w)
+                        }
+                }
+        }
+
+        return services.NewReminderDispatcher(db, notifiers, cfg.NotifyChannel), nil
+}
+
+// runPeriodically calls job every interval until ctx is cancelled, logging
+// its errors
+func runPeriodically(ctx context.Context, name string, interval time.Duration, job func() error) {
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+
+        for {
+                if err := job(); err != nil {
+                        logger.Errorf("Background job %s failed: %v", name, err)
+                }
+
+                select {
+                case <-ctx.Done():
+                        return
+                case <-ticker.C:
+                }
+        }
+}
@@ -2,6 +2,8 @@ package config
 
 import (
         "os"
+        "strconv"
+        "time"
 
         "github.com/joho/godotenv"
 )
@@ -14,6 +16,33 @@ type Config struct {
         CORSOrigins string
         LogLevel    string
         Environment string
+        AdminToken  string
+
+        // WorkerInterval is how often background jobs such as recurring
+        // transactions run
+        WorkerInterval time.Duration
+
+        // TrashRetentionDays is how long deleted parties, transactions and
+        // reminders can be restored before they are purged
+        TrashRetentionDays int
+
+        // Reminder delivery. A channel without settings is logged instead of
+        // sent outside production, to NotifyLogFile when it is set.
+        NotifyChannel    string
+        NotifyLogFile    string
+        SMTPHost         string
+        SMTPPort         string
+        SMTPUsername     string
+        SMTPPassword     string
+        SMTPFrom         string
+        SMSGatewayURL    string
+        SMSGatewayKey    string
+        SMSSender        string
+        WhatsAppURL      string
+        WhatsAppToken    string
+        WhatsAppPhoneID  string
+        WhatsAppTemplate string
+        WhatsAppLanguage string
 }
 
 // LoadConfig loads configuration from environment variables
@@ -28,6 +57,26 @@ func LoadConfig() *Config {
                 CORSOrigins: getEnv("CORS_ORIGINS", "http://localhost:5000,http://localhost:3000"),
                 LogLevel:    getEnv("LOG_LEVEL", "info"),
                 Environment: getEnv("ENVIRONMENT", "development"),
+                AdminToken:  getEnv("ADMIN_TOKEN", ""),
+
+                WorkerInterval:     getDuration("WORKER_INTERVAL", 5*time.Minute),
+                TrashRetentionDays: getInt("TRASH_RETENTION_DAYS", 30),
+
+                NotifyChannel:    getEnv("NOTIFY_CHANNEL", "sms"),
+                NotifyLogFile:    getEnv("NOTIFY_LOG_FILE", ""),
+                SMTPHost:         getEnv("SMTP_HOST", ""),
+                SMTPPort:         getEnv("SMTP_PORT", "587"),
+                SMTPUsername:     getEnv("SMTP_USERNAME", ""),
+                SMTPPassword:     getEnv("SMTP_PASSWORD", ""),
+                SMTPFrom:         getEnv("SMTP_FROM", ""),
+                SMSGatewayURL:    getEnv("SMS_GATEWAY_URL", ""),
+                SMSGatewayKey:    getEnv("SMS_GATEWAY_KEY", ""),
+                SMSSender:        getEnv("SMS_SENDER", ""),
+                WhatsAppURL:      getEnv("WHATSAPP_API_URL", ""),
+                WhatsAppToken:    getEnv("WHATSAPP_TOKEN", ""),
+                WhatsAppPhoneID:  getEnv("WHATSAPP_PHONE_NUMBER_ID", ""),
+                WhatsAppTemplate: getEnv("WHATSAPP_TEMPLATE", "payment_reminder"),
+                WhatsAppLanguage: getEnv("WHATSAPP_LANGUAGE", "en"),
         }
 }
 
@@ -37,3 +86,21 @@ func getEnv(key, defaultValue string) string {
         }
         return defaultValue
 }
+
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+        if value := os.Getenv(key); value != "" {
+                if d, err := time.ParseDuration(value); err == nil && d > 0 {
+                        return d
+                }
+        }
+        return defaultValue
+}
+
+func getInt(key string, defaultValue int) int {
+        if value := os.Getenv(key); value != "" {
+                if n, err := strconv.Atoi(value); err == nil && n > 0 {
+                        return n
+                }
+        }
+        return defaultValue
+}
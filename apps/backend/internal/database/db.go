@@ -42,11 +42,139 @@ func InitDB(databaseURL string) (*gorm.DB, error) {
 
 // runMigrations applies all database migrations
 func runMigrations(db *gorm.DB) error {
-        return db.AutoMigrate(
+        if err := migrateMoneyColumns(db); err != nil {
+                return err
+        }
+
+        if err := db.AutoMigrate(
                 &models.User{},
                 &models.Party{},
                 &models.Transaction{},
                 &models.Reminder{},
                 &models.SyncLog{},
-        )
+                &models.Account{},
+                &models.JournalEntry{},
+                &models.Posting{},
+                &models.ExchangeRate{},
+                &models.Invoice{},
+                &models.InvoiceItem{},
+                &models.Allocation{},
+                &models.InterestTerms{},
+                &models.RecurringTransaction{},
+                &models.RecurringOccurrence{},
+                &models.ReminderDelivery{},
+                &models.ReminderEvent{},
+                &models.ReminderSettlement{},
+                &models.ReminderRule{},
+                &models.ReminderTemplate{},
+                &models.CalendarToken{},
+                &models.AuditLog{},
+                &models.LedgerLink{},
+        ); err != nil {
+                return err
+        }
+
+        if err := uniqueInvoiceNumbers(db); err != nil {
+                return err
+        }
+        if err := uniqueReminderRules(db); err != nil {
+                return err
+        }
+        return protectAppendOnly(db)
+}
+
+// uniqueInvoiceNumbers enforces the numbering rules in the database: a sale
+// invoice number is unique per user, a purchase bill number per supplier
+func uniqueInvoiceNumbers(db *gorm.DB) error {
+        return db.Transaction(func(tx *gorm.DB) error {
+                if err := tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_invoices_sale_number
+                        ON invoices (user_id, invoice_number) WHERE invoice_type = 'sale'`).Error; err != nil {
+                        return err
+                }
+                return tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_invoices_purchase_number
+                        ON invoices (user_id, party_id, invoice_number) WHERE invoice_type = 'purchase'`).Error
+        })
+}
+
+// uniqueReminderRules enforces one reminder rule per party and one user-wide
+// rule per user in the database. Rules trashed with their party do not count.
+func uniqueReminderRules(db *gorm.DB) error {
+        return db.Transaction(func(tx *gorm.DB) error {
+                if err := tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_reminder_rules_party
+                        ON reminder_rules (user_id, party_id) WHERE party_id IS NOT NULL AND deleted_at IS NULL`).Error; err != nil {
+                        return err
+                }
+                return tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_reminder_rules_user
+                        ON reminder_rules (user_id) WHERE party_id IS NULL AND deleted_at IS NULL`).Error
+        })
+}
+
+// appendOnlyTables are only ever appended to: the audit log and the ledger
+// hash chain
+var appendOnlyTables = []string{"audit_logs", "ledger_links"}
+
+// protectAppendOnly makes the append-only tables so in the database itself,
+// so their rows cannot be changed or removed by any client
+func protectAppendOnly(db *gorm.DB) error {
+        return db.Transaction(func(tx *gorm.DB) error {
+                if err := tx.Exec(`CREATE OR REPLACE FUNCTION reject_append_only_change() RETURNS trigger AS $$
+                        BEGIN
+                                RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+                        END
+                        $$ LANGUAGE plpgsql`).Error; err != nil {
+                        return err
+                }
+                for _, table := range appendOnlyTables {
+                        trigger := table + "_append_only"
+                        if err := tx.Exec(fmt.Sprintf(`DROP TRIGGER IF EXISTS %q ON %q`, trigger, table)).Error; err != nil {
+                                return err
+                        }
+                        sql := fmt.Sprintf(`CREATE TRIGGER %q BEFORE UPDATE OR DELETE OR TRUNCATE ON %q
+                                FOR EACH STATEMENT EXECUTE FUNCTION reject_append_only_change()`, trigger, table)
+                        if err := tx.Exec(sql).Error; err != nil {
+                                return err
+                        }
+                }
+                return nil
+        })
+}
+
+// moneyColumns lists the columns that hold amounts in minor units (paise)
+var moneyColumns = []struct {
+        table  string
+        column string
+}{
+        {"parties", "balance"},
+        {"transactions", "amount"},
+        {"transactions", "running_balance"},
+        {"reminders", "amount"},
+}
+
+// migrateMoneyColumns converts legacy floating point amount columns to bigint
+// paise. It runs before AutoMigrate, which would otherwise truncate the
+// fractional part when changing the column type.
+func migrateMoneyColumns(db *gorm.DB) error {
+        return db.Transaction(func(tx *gorm.DB) error {
+                for _, mc := range moneyColumns {
+                        var dataType string
+                        err := tx.Raw(`SELECT data_type FROM information_schema.columns
+                                WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?`,
+                                mc.table, mc.column).Scan(&dataType).Error
+                        if err != nil {
+                                return err
+                        }
+
+                        if dataType != "numeric" && dataType != "double precision" && dataType != "real" {
+                                continue
+                        }
+
+                        log.Printf("Converting %s.%s from %s to bigint paise", mc.table, mc.column, dataType)
+                        sql := fmt.Sprintf(`ALTER TABLE %q ALTER COLUMN %q TYPE bigint USING ROUND(%q::numeric * 100)::bigint`,
+                                mc.table, mc.column, mc.column)
+                        if err := tx.Exec(sql).Error; err != nil {
+                                return fmt.Errorf("failed to convert %s.%s: %w", mc.table, mc.column, err)
+                        }
+                }
+                return nil
+        })
 }
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+        "net/http"
+
+        "github.com/gin-gonic/gin"
+)
+
+// ReconcileBalances recomputes party balances from transactions and reports
+// drift, repairing it when repair=true. An optional user_id limits the run.
+func (h *Handler) ReconcileBalances(c *gin.Context) {
+        repair := c.Query("repair") == "true"
+
+        report, appErr := h.reconcileService.Reconcile(c.Query("user_id"), repair)
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, report)
+}
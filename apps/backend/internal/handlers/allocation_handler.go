@@ -0,0 +1,108 @@
+package handlers
+
+import (
+        "net/http"
+
+        "khatabook-go-backend/internal/middleware"
+        "khatabook-go-backend/internal/models"
+        apperrors "khatabook-go-backend/pkg/errors"
+
+        "github.com/gin-gonic/gin"
+)
+
+// GetPartyBills retrieves a party's bills with their outstanding amounts
+func (h *Handler) GetPartyBills(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        openOnly := c.DefaultQuery("status", "open") == "open"
+        bills, appErr := h.allocationService.GetBills(userID, c.Param("id"), openOnly)
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, bills)
+}
+
+// AutoAllocateParty allocates a party's unallocated payments to its open bills, oldest first
+func (h *Handler) AutoAllocateParty(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        allocations, appErr := h.allocationService.AutoAllocate(userID, c.Param("id"))
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, allocations)
+}
+
+// GetTransactionAllocations retrieves the allocations of a bill or payment
+func (h *Handler) GetTransactionAllocations(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        allocations, appErr := h.allocationService.GetAllocations(userID, c.Param("id"))
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, allocations)
+}
+
+// AllocatePayment allocates a payment to specific bills
+func (h *Handler) AllocatePayment(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        var req models.AllocatePaymentRequest
+        if err := c.ShouldBindJSON(&req); err != nil {
+                appErr := apperrors.BadRequest(err.Error())
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        allocations, appErr := h.allocationService.AllocatePayment(userID, c.Param("id"), &req)
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusCreated, allocations)
+}
+
+// DeleteAllocation removes an allocation
+func (h *Handler) DeleteAllocation(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        if appErr := h.allocationService.DeleteAllocation(userID, c.Param("id")); appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, gin.H{"message": "Allocation deleted successfully"})
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+        "net/http"
+
+        "khatabook-go-backend/internal/middleware"
+        apperrors "khatabook-go-backend/pkg/errors"
+
+        "github.com/gin-gonic/gin"
+)
+
+// GetAuditLog lists the audit log entries of the user, optionally for one
+// entity type (entity) and one entity (id)
+func (h *Handler) GetAuditLog(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        entries, appErr := h.auditService.GetEntries(userID, c.Query("entity"), c.Query("id"))
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+        "net/http"
+
+        "khatabook-go-backend/internal/middleware"
+        apperrors "khatabook-go-backend/pkg/errors"
+
+        "github.com/gin-gonic/gin"
+)
+
+// calendarFeedPath is where the reminder calendar is served
+const calendarFeedPath = "/api/reminders/calendar.ics"
+
+// CalendarFeedUser returns the user a calendar feed token belongs to
+func (h *Handler) CalendarFeedUser(token string) (string, *apperrors.AppError) {
+        return h.calendarService.UserForToken(token)
+}
+
+// GetReminderCalendar serves the user's open reminders as an iCalendar feed
+func (h *Handler) GetReminderCalendar(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        data, appErr := h.calendarService.Calendar(userID)
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.Header("Cache-Control", "no-store")
+        c.Data(http.StatusOK, "text/calendar; charset=utf-8", data)
+}
+
+// GetCalendarToken shows whether the user has a calendar feed token
+func (h *Handler) GetCalendarToken(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        token, appErr := h.calendarService.GetToken(userID)
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, token)
+}
+
+// CreateCalendarToken issues a new calendar feed token, revoking the old one
+func (h *Handler) CreateCalendarToken(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        token, record, appErr := h.calendarService.CreateToken(userID)
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusCreated, gin.H{
+                "token":      token,
+                "url":        calendarFeedPath + "?token=" + token,
+                "created_at": record.CreatedAt,
+        })
+}
+
+// RevokeCalendarToken revokes the user's calendar feed token
+func (h *Handler) RevokeCalendarToken(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        if appErr := h.calendarService.RevokeToken(userID); appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, gin.H{"message": "Calendar feed token revoked"})
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+        "net/http"
+
+        "khatabook-go-backend/internal/middleware"
+        apperrors "khatabook-go-backend/pkg/errors"
+
+        "github.com/gin-gonic/gin"
+)
+
+// VerifyLedgerChain verifies the user's ledger hash chain and reports the
+// first break, if any
+func (h *Handler) VerifyLedgerChain(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        report, appErr := h.chainService.Verify(userID)
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, report)
+}
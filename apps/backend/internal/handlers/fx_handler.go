@@ -0,0 +1,71 @@
+package handlers
+
+import (
+        "net/http"
+
+        "khatabook-go-backend/internal/middleware"
+        "khatabook-go-backend/internal/models"
+        apperrors "khatabook-go-backend/pkg/errors"
+
+        "github.com/gin-gonic/gin"
+)
+
+// GetExchangeRates retrieves the user's exchange rates with optional currency filter
+func (h *Handler) GetExchangeRates(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        rates, appErr := h.fxService.GetRates(userID, c.Query("currency"))
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, rates)
+}
+
+// SetExchangeRate records the rate of a currency for a date
+func (h *Handler) SetExchangeRate(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        var req models.SetExchangeRateRequest
+        if err := c.ShouldBindJSON(&req); err != nil {
+                appErr := apperrors.BadRequest(err.Error())
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        rate, appErr := h.fxService.SetRate(userID, &req)
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusCreated, rate)
+}
+
+// DeleteExchangeRate deletes an exchange rate
+func (h *Handler) DeleteExchangeRate(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        if appErr := h.fxService.DeleteRate(userID, c.Param("id")); appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, gin.H{"message": "Exchange rate deleted successfully"})
+}
@@ -12,17 +12,47 @@ type Handler struct {
 	partyService       *services.PartyService
 	transactionService *services.TransactionService
 	reminderService    *services.ReminderService
+	journalService     *services.JournalService
+	reconcileService   *services.ReconcileService
+	fxService          *services.FXService
+	invoiceService     *services.InvoiceService
+	allocationService  *services.AllocationService
+	agingService       *services.AgingService
+	interestService    *services.InterestService
+	recurringService   *services.RecurringService
+	ruleService        *services.ReminderRuleService
+	templateService    *services.ReminderTemplateService
+	calendarService    *services.CalendarService
+	trashService       *services.TrashService
+	auditService       *services.AuditService
+	chainService       *services.ChainService
+	dispatcher         *services.ReminderDispatcher
 	jwtSecret          string
 	db                 *gorm.DB
 }
 
 // NewHandler creates a new handler with all services
-func NewHandler(db *gorm.DB, jwtSecret string) *Handler {
+func NewHandler(db *gorm.DB, jwtSecret string, dispatcher *services.ReminderDispatcher) *Handler {
 	return &Handler{
 		authService:        services.NewAuthService(db, jwtSecret),
 		partyService:       services.NewPartyService(db),
 		transactionService: services.NewTransactionService(db),
 		reminderService:    services.NewReminderService(db),
+		journalService:     services.NewJournalService(db),
+		reconcileService:   services.NewReconcileService(db),
+		fxService:          services.NewFXService(db),
+		invoiceService:     services.NewInvoiceService(db),
+		allocationService:  services.NewAllocationService(db),
+		agingService:       services.NewAgingService(db),
+		interestService:    services.NewInterestService(db),
+		recurringService:   services.NewRecurringService(db),
+		ruleService:        services.NewReminderRuleService(db),
+		templateService:    services.NewReminderTemplateService(db),
+		calendarService:    services.NewCalendarService(db),
+		trashService:       services.NewTrashService(db),
+		auditService:       services.NewAuditService(db),
+		chainService:       services.NewChainService(db),
+		dispatcher:         dispatcher,
 		jwtSecret:          jwtSecret,
 		db:                 db,
 	}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+        "net/http"
+
+        "khatabook-go-backend/internal/middleware"
+        "khatabook-go-backend/internal/models"
+        apperrors "khatabook-go-backend/pkg/errors"
+
+        "github.com/gin-gonic/gin"
+)
+
+// GetInterestTerms retrieves a party's interest terms
+func (h *Handler) GetInterestTerms(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        terms, appErr := h.interestService.GetTerms(userID, c.Param("id"))
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, terms)
+}
+
+// SetInterestTerms creates or replaces a party's interest terms
+func (h *Handler) SetInterestTerms(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        var req models.SetInterestTermsRequest
+        if err := c.ShouldBindJSON(&req); err != nil {
+                appErr := apperrors.BadRequest(err.Error())
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        terms, appErr := h.interestService.SetTerms(userID, c.Param("id"), &req)
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, terms)
+}
+
+// DeleteInterestTerms removes a party's interest terms
+func (h *Handler) DeleteInterestTerms(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        if appErr := h.interestService.DeleteTerms(userID, c.Param("id")); appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, gin.H{"message": "Interest terms deleted successfully"})
+}
+
+// PreviewInterest computes the interest due from a party without posting it
+func (h *Handler) PreviewInterest(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        preview, appErr := h.interestService.Preview(userID, c.Param("id"), c.Query("from"), c.Query("to"))
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, preview)
+}
+
+// PostInterest posts the interest due from a party as a ledger transaction
+func (h *Handler) PostInterest(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        var req models.PostInterestRequest
+        if c.Request.ContentLength > 0 {
+                if err := c.ShouldBindJSON(&req); err != nil {
+                        appErr := apperrors.BadRequest(err.Error())
+                        c.JSON(appErr.Code, appErr.ToResponse())
+                        return
+                }
+        }
+
+        transaction, appErr := h.interestService.WithContext(c.Request.Context()).Post(userID, c.Param("id"), &req)
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusCreated, transaction)
+}
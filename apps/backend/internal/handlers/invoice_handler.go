@@ -0,0 +1,170 @@
+package handlers
+
+import (
+        "net/http"
+
+        "khatabook-go-backend/internal/middleware"
+        "khatabook-go-backend/internal/models"
+        apperrors "khatabook-go-backend/pkg/errors"
+
+        "github.com/gin-gonic/gin"
+)
+
+// GetInvoices retrieves the user's invoices with optional filters
+func (h *Handler) GetInvoices(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        filters := make(map[string]interface{})
+        if partyID := c.Query("party_id"); partyID != "" {
+                filters["party_id"] = partyID
+        }
+        if invoiceType := c.Query("invoice_type"); invoiceType != "" {
+                filters["invoice_type"] = invoiceType
+        }
+        if status := c.Query("status"); status != "" {
+                filters["status"] = status
+        }
+
+        invoices, appErr := h.invoiceService.GetInvoices(userID, filters)
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, invoices)
+}
+
+// GetInvoice retrieves a single invoice with its line items
+func (h *Handler) GetInvoice(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        invoice, appErr := h.invoiceService.GetInvoiceByID(userID, c.Param("id"))
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, invoice)
+}
+
+// CreateInvoice creates a draft invoice
+func (h *Handler) CreateInvoice(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        var req models.CreateInvoiceRequest
+        if err := c.ShouldBindJSON(&req); err != nil {
+                appErr := apperrors.BadRequest(err.Error())
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        invoice, appErr := h.invoiceService.CreateInvoice(userID, &req)
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusCreated, invoice)
+}
+
+// UpdateInvoice updates a draft invoice
+func (h *Handler) UpdateInvoice(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        var req models.UpdateInvoiceRequest
+        if err := c.ShouldBindJSON(&req); err != nil {
+                appErr := apperrors.BadRequest(err.Error())
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        invoice, appErr := h.invoiceService.UpdateInvoice(userID, c.Param("id"), &req)
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, invoice)
+}
+
+// DeleteInvoice deletes a draft invoice
+func (h *Handler) DeleteInvoice(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        if appErr := h.invoiceService.DeleteInvoice(userID, c.Param("id")); appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, gin.H{"message": "Invoice deleted successfully"})
+}
+
+// PostInvoice posts a draft invoice to the party ledger
+func (h *Handler) PostInvoice(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        var req models.PostInvoiceRequest
+        if c.Request.ContentLength > 0 {
+                if err := c.ShouldBindJSON(&req); err != nil {
+                        appErr := apperrors.BadRequest(err.Error())
+                        c.JSON(appErr.Code, appErr.ToResponse())
+                        return
+                }
+        }
+
+        invoice, appErr := h.invoiceService.WithContext(c.Request.Context()).PostInvoice(userID, c.Param("id"), &req)
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, invoice)
+}
+
+// CancelInvoice cancels an invoice and reverses its ledger effect
+func (h *Handler) CancelInvoice(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        invoice, appErr := h.invoiceService.WithContext(c.Request.Context()).CancelInvoice(userID, c.Param("id"))
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, invoice)
+}
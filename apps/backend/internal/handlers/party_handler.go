@@ -21,14 +21,15 @@ func (h *Handler) GetParties(c *gin.Context) {
 
         // Get optional party_type filter
         partyType := c.Query("party_type")
+        includeArchived := c.Query("archived") == "true"
 
         var parties []models.Party
         var appErr *apperrors.AppError
 
         if partyType != "" {
-                parties, appErr = h.partyService.GetPartiesByType(userID, partyType)
+                parties, appErr = h.partyService.GetPartiesByType(userID, partyType, includeArchived)
         } else {
-                parties, appErr = h.partyService.GetAllParties(userID)
+                parties, appErr = h.partyService.GetAllParties(userID, includeArchived)
         }
 
         if appErr != nil {
@@ -55,7 +56,7 @@ func (h *Handler) CreateParty(c *gin.Context) {
                 return
         }
 
-        party, appErr := h.partyService.CreateParty(userID, &req)
+        party, appErr := h.partyService.WithContext(c.Request.Context()).CreateParty(userID, &req)
         if appErr != nil {
                 c.JSON(appErr.Code, appErr.ToResponse())
                 return
@@ -100,7 +101,7 @@ func (h *Handler) UpdateParty(c *gin.Context) {
                 return
         }
 
-        party, appErr := h.partyService.UpdateParty(userID, partyID, &req)
+        party, appErr := h.partyService.WithContext(c.Request.Context()).UpdateParty(userID, partyID, &req)
         if appErr != nil {
                 c.JSON(appErr.Code, appErr.ToResponse())
                 return
@@ -119,7 +120,7 @@ func (h *Handler) DeleteParty(c *gin.Context) {
         }
 
         partyID := c.Param("id")
-        appErr := h.partyService.DeleteParty(userID, partyID)
+        appErr := h.partyService.WithContext(c.Request.Context()).DeleteParty(userID, partyID, c.Query("cascade") == "true")
         if appErr != nil {
                 c.JSON(appErr.Code, appErr.ToResponse())
                 return
@@ -127,3 +128,69 @@ func (h *Handler) DeleteParty(c *gin.Context) {
 
         c.JSON(http.StatusOK, gin.H{"message": "Party deleted successfully"})
 }
+
+// RestoreParty restores a party from the trash
+func (h *Handler) RestoreParty(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        party, appErr := h.partyService.WithContext(c.Request.Context()).RestoreParty(userID, c.Param("id"))
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, party)
+}
+
+// ArchiveParty archives a party
+func (h *Handler) ArchiveParty(c *gin.Context) {
+        h.setPartyArchived(c, true)
+}
+
+// UnarchiveParty brings an archived party back into party lists
+func (h *Handler) UnarchiveParty(c *gin.Context) {
+        h.setPartyArchived(c, false)
+}
+
+// setPartyArchived archives or unarchives the party in the path
+func (h *Handler) setPartyArchived(c *gin.Context, archive bool) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        party, appErr := h.partyService.WithContext(c.Request.Context()).ArchiveParty(userID, c.Param("id"), archive)
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, party)
+}
+
+// GetPartyStatementPDF renders the party's account statement as a PDF
+func (h *Handler) GetPartyStatementPDF(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        partyID := c.Param("id")
+        document, appErr := h.partyService.StatementPDF(userID, partyID, c.Query("from"), c.Query("to"))
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.Header("Content-Disposition", `inline; filename="statement-`+partyID+`.pdf"`)
+        c.Data(http.StatusOK, "application/pdf", document)
+}
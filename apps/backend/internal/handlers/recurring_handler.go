@@ -0,0 +1,132 @@
+package handlers
+
+import (
+        "net/http"
+
+        "khatabook-go-backend/internal/middleware"
+        "khatabook-go-backend/internal/models"
+        apperrors "khatabook-go-backend/pkg/errors"
+
+        "github.com/gin-gonic/gin"
+)
+
+// GetRecurring retrieves the user's recurring transactions with optional party filter
+func (h *Handler) GetRecurring(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        templates, appErr := h.recurringService.GetRecurring(userID, c.Query("party_id"))
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, templates)
+}
+
+// GetRecurringByID retrieves a single recurring transaction
+func (h *Handler) GetRecurringByID(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        template, appErr := h.recurringService.GetRecurringByID(userID, c.Param("id"))
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, template)
+}
+
+// CreateRecurring creates a recurring transaction
+func (h *Handler) CreateRecurring(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        var req models.CreateRecurringRequest
+        if err := c.ShouldBindJSON(&req); err != nil {
+                appErr := apperrors.BadRequest(err.Error())
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        template, appErr := h.recurringService.CreateRecurring(userID, &req)
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusCreated, template)
+}
+
+// UpdateRecurring updates a recurring transaction
+func (h *Handler) UpdateRecurring(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        var req models.UpdateRecurringRequest
+        if err := c.ShouldBindJSON(&req); err != nil {
+                appErr := apperrors.BadRequest(err.Error())
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        template, appErr := h.recurringService.UpdateRecurring(userID, c.Param("id"), &req)
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, template)
+}
+
+// DeleteRecurring deletes a recurring transaction
+func (h *Handler) DeleteRecurring(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        if appErr := h.recurringService.DeleteRecurring(userID, c.Param("id")); appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, gin.H{"message": "Recurring transaction deleted successfully"})
+}
+
+// GetRecurringOccurrences retrieves the transactions a recurring transaction has created
+func (h *Handler) GetRecurringOccurrences(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        occurrences, appErr := h.recurringService.GetOccurrences(userID, c.Param("id"))
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, occurrences)
+}
@@ -55,7 +55,7 @@ func (h *Handler) CreateReminder(c *gin.Context) {
                 return
         }
 
-        reminder, appErr := h.reminderService.CreateReminder(userID, &req)
+        reminder, appErr := h.reminderService.WithContext(c.Request.Context()).CreateReminder(userID, &req)
         if appErr != nil {
                 c.JSON(appErr.Code, appErr.ToResponse())
                 return
@@ -81,7 +81,7 @@ func (h *Handler) UpdateReminder(c *gin.Context) {
                 return
         }
 
-        reminder, appErr := h.reminderService.UpdateReminder(userID, reminderID, &req)
+        reminder, appErr := h.reminderService.WithContext(c.Request.Context()).UpdateReminder(userID, reminderID, &req)
         if appErr != nil {
                 c.JSON(appErr.Code, appErr.ToResponse())
                 return
@@ -119,7 +119,7 @@ func (h *Handler) DeleteReminder(c *gin.Context) {
         }
 
         reminderID := c.Param("id")
-        appErr := h.reminderService.DeleteReminder(userID, reminderID)
+        appErr := h.reminderService.WithContext(c.Request.Context()).DeleteReminder(userID, reminderID)
         if appErr != nil {
                 c.JSON(appErr.Code, appErr.ToResponse())
                 return
@@ -128,6 +128,24 @@ func (h *Handler) DeleteReminder(c *gin.Context) {
         c.JSON(http.StatusOK, gin.H{"message": "Reminder deleted successfully"})
 }
 
+// RestoreReminder restores a reminder from the trash
+func (h *Handler) RestoreReminder(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        reminder, appErr := h.reminderService.WithContext(c.Request.Context()).RestoreReminder(userID, c.Param("id"))
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, reminder)
+}
+
 // UpdateReminderStatus updates reminder status (mark as completed)
 func (h *Handler) UpdateReminderStatus(c *gin.Context) {
         userID, ok := middleware.GetUserID(c)
@@ -149,7 +167,25 @@ func (h *Handler) UpdateReminderStatus(c *gin.Context) {
         var req models.UpdateReminderRequest
         req.Status = status
 
-        reminder, appErr := h.reminderService.UpdateReminder(userID, reminderID, &req)
+        reminder, appErr := h.reminderService.WithContext(c.Request.Context()).UpdateReminder(userID, reminderID, &req)
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, reminder)
+}
+
+// SendReminder delivers a pending reminder now
+func (h *Handler) SendReminder(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        reminder, appErr := h.dispatcher.Deliver(c.Request.Context(), userID, c.Param("id"))
         if appErr != nil {
                 c.JSON(appErr.Code, appErr.ToResponse())
                 return
@@ -157,3 +193,64 @@ func (h *Handler) UpdateReminderStatus(c *gin.Context) {
 
         c.JSON(http.StatusOK, reminder)
 }
+
+// GetReminderDeliveries retrieves the delivery attempts of a reminder
+func (h *Handler) GetReminderDeliveries(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        deliveries, appErr := h.reminderService.GetDeliveries(userID, c.Param("id"))
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, deliveries)
+}
+
+// SnoozeReminder moves a reminder's due date
+func (h *Handler) SnoozeReminder(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        var req models.SnoozeReminderRequest
+        if err := c.ShouldBindJSON(&req); err != nil {
+                appErr := apperrors.BadRequest(err.Error())
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        reminder, appErr := h.reminderService.WithContext(c.Request.Context()).SnoozeReminder(userID, c.Param("id"), &req)
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, reminder)
+}
+
+// GetReminderHistory retrieves the status changes of a reminder
+func (h *Handler) GetReminderHistory(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        events, appErr := h.reminderService.GetHistory(userID, c.Param("id"))
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, events)
+}
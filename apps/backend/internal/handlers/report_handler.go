@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"encoding/csv"
 	"net/http"
 	"strconv"
 	"time"
 
 	apperrors "khatabook-go-backend/pkg/errors"
 	"khatabook-go-backend/internal/middleware"
+	"khatabook-go-backend/internal/services"
+	"khatabook-go-backend/pkg/money"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,36 +23,53 @@ func (h *Handler) GetReportSummary(c *gin.Context) {
 		return
 	}
 
-	var totalCredit, totalDebit, totalPayable, totalReceivable float64
 	var pendingReminders int64
 
-	// Get totals from transactions
-	h.db.Table("transactions").Where("user_id = ? AND transaction_type = ?", userID, "credit").
-		Select("COALESCE(SUM(amount), 0)").Row().Scan(&totalCredit)
-
-	h.db.Table("transactions").Where("user_id = ? AND transaction_type = ?", userID, "debit").
-		Select("COALESCE(SUM(amount), 0)").Row().Scan(&totalDebit)
-
-	// Get party balances
-	h.db.Table("parties").Where("user_id = ? AND balance > 0", userID).
-		Select("COALESCE(SUM(balance), 0)").Row().Scan(&totalReceivable)
+	rates, appErr := h.fxService.LoadRates(userID)
+	if appErr != nil {
+		c.JSON(appErr.Code, appErr.ToResponse())
+		return
+	}
 
-	h.db.Table("parties").Where("user_id = ? AND balance < 0", userID).
-		Select("COALESCE(SUM(ABS(balance)), 0)").Row().Scan(&totalPayable)
+	// Totals are derived from the journal postings, in the base currency
+	totals, appErr := h.journalService.GetTotals(userID, rates, time.Now().Format("2006-01-02"))
+	if appErr != nil {
+		c.JSON(appErr.Code, appErr.ToResponse())
+		return
+	}
 
 	// Count pending reminders
-	h.db.Table("reminders").Where("user_id = ? AND status = ?", userID, "pending").
+	h.db.Table("reminders").Where("user_id = ? AND status = ? AND deleted_at IS NULL", userID, "pending").
 		Count(&pendingReminders)
 
-	netBalance := totalReceivable - totalPayable
+	// Parties whose balance has run past their credit limit
+	type OverLimitParty struct {
+		PartyID     string       `json:"party_id"`
+		PartyName   string       `json:"party_name"`
+		Currency    string       `json:"currency"`
+		Balance     money.Amount `json:"balance"`
+		CreditLimit money.Amount `json:"credit_limit"`
+		Excess      money.Amount `json:"excess"`
+	}
+	overLimit := []OverLimitParty{}
+	h.db.Table("parties").
+		Select("id as party_id, name as party_name, currency, balance, credit_limit, balance - credit_limit as excess").
+		Where("user_id = ? AND credit_limit > 0 AND balance > credit_limit AND deleted_at IS NULL", userID).
+		Order("excess DESC").
+		Scan(&overLimit)
+
+	netBalance := totals.TotalReceivable - totals.TotalPayable
 
 	response := gin.H{
-		"total_credit":      totalCredit,
-		"total_debit":       totalDebit,
-		"total_receivable":  totalReceivable,
-		"total_payable":     totalPayable,
-		"net_balance":       netBalance,
-		"pending_reminders": pendingReminders,
+		"base_currency":      totals.BaseCurrency,
+		"total_credit":       totals.TotalCredit,
+		"total_debit":        totals.TotalDebit,
+		"total_receivable":   totals.TotalReceivable,
+		"total_payable":      totals.TotalPayable,
+		"net_balance":        netBalance,
+		"unrealized_fx":      totals.UnrealizedFX,
+		"pending_reminders":  pendingReminders,
+		"over_limit_parties": overLimit,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -72,24 +92,63 @@ func (h *Handler) GetDailyReport(c *gin.Context) {
 
 	startDate := time.Now().AddDate(0, 0, -days)
 
+	rates, appErr := h.fxService.LoadRates(userID)
+	if appErr != nil {
+		c.JSON(appErr.Code, appErr.ToResponse())
+		return
+	}
+
 	type DailyData struct {
-		Date   string  `json:"date"`
-		Credit float64 `json:"credit"`
-		Debit  float64 `json:"debit"`
+		Date   string       `json:"date"`
+		Credit money.Amount `json:"credit"`
+		Debit  money.Amount `json:"debit"`
 	}
 
-	var dailyData []DailyData
+	var rows []struct {
+		Date     string
+		Currency string
+		Credit   money.Amount
+		Debit    money.Amount
+	}
 
 	h.db.Table("transactions").
-		Where("user_id = ? AND date >= ?", userID, startDate.Format("2006-01-02")).
-		Select("date, transaction_type, COALESCE(SUM(amount), 0) as amount").
-		Group("date, transaction_type").
-		Scan(&dailyData)
+		Where("user_id = ? AND date >= ? AND deleted_at IS NULL", userID, startDate.Format("2006-01-02")).
+		Select(`date, currency,
+			COALESCE(SUM(CASE WHEN transaction_type='credit' THEN amount ELSE 0 END), 0) as credit,
+			COALESCE(SUM(CASE WHEN transaction_type='debit' THEN amount ELSE 0 END), 0) as debit`).
+		Group("date, currency").
+		Order("date").
+		Scan(&rows)
+
+	// Each day's movements are converted at that day's rate
+	dailyData := []DailyData{}
+	for _, row := range rows {
+		credit, err := rates.ToBase(row.Credit, row.Currency, row.Date)
+		if err != nil {
+			appErr := apperrors.Unprocessable(err.Error())
+			c.JSON(appErr.Code, appErr.ToResponse())
+			return
+		}
+		debit, err := rates.ToBase(row.Debit, row.Currency, row.Date)
+		if err != nil {
+			appErr := apperrors.Unprocessable(err.Error())
+			c.JSON(appErr.Code, appErr.ToResponse())
+			return
+		}
+
+		if n := len(dailyData); n > 0 && dailyData[n-1].Date == row.Date {
+			dailyData[n-1].Credit += credit
+			dailyData[n-1].Debit += debit
+			continue
+		}
+		dailyData = append(dailyData, DailyData{Date: row.Date, Credit: credit, Debit: debit})
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"days":  days,
-		"data":  dailyData,
-		"total": len(dailyData),
+		"days":          days,
+		"base_currency": rates.Base,
+		"data":          dailyData,
+		"total":         len(dailyData),
 	})
 }
 
@@ -102,28 +161,139 @@ func (h *Handler) GetPartyWiseReport(c *gin.Context) {
 		return
 	}
 
+	rates, appErr := h.fxService.LoadRates(userID)
+	if appErr != nil {
+		c.JSON(appErr.Code, appErr.ToResponse())
+		return
+	}
+
 	type PartyReport struct {
-		PartyID       string  `json:"party_id"`
-		PartyName     string  `json:"party_name"`
-		PartyType     string  `json:"party_type"`
-		Credit        float64 `json:"credit"`
-		Debit         float64 `json:"debit"`
-		Balance       float64 `json:"balance"`
-		TxnCount      int64   `json:"txn_count"`
+		PartyID     string       `json:"party_id"`
+		PartyName   string       `json:"party_name"`
+		PartyType   string       `json:"party_type"`
+		Currency    string       `json:"currency"`
+		Credit      money.Amount `json:"credit"`
+		Debit       money.Amount `json:"debit"`
+		Balance     money.Amount `json:"balance"`
+		BalanceBase money.Amount `json:"balance_base"`
+		TxnCount    int64        `json:"txn_count"`
 	}
 
 	var reports []PartyReport
 
 	h.db.Table("parties p").
-		Select(`p.id, p.name, p.party_type, 
+		Select(`p.id as party_id, p.name as party_name, p.party_type, p.currency,
 			COALESCE(SUM(CASE WHEN t.transaction_type='credit' THEN t.amount ELSE 0 END), 0) as credit,
 			COALESCE(SUM(CASE WHEN t.transaction_type='debit' THEN t.amount ELSE 0 END), 0) as debit,
 			p.balance,
 			COUNT(t.id) as txn_count`).
-		Joins("LEFT JOIN transactions t ON p.id = t.party_id").
-		Where("p.user_id = ?", userID).
+		Joins("LEFT JOIN transactions t ON p.id = t.party_id AND t.deleted_at IS NULL").
+		Where("p.user_id = ? AND p.deleted_at IS NULL", userID).
 		Group("p.id").
 		Scan(&reports)
 
+	today := time.Now().Format("2006-01-02")
+	for i := range reports {
+		balance, err := rates.ToBase(reports[i].Balance, reports[i].Currency, today)
+		if err != nil {
+			appErr := apperrors.Unprocessable(err.Error())
+			c.JSON(appErr.Code, appErr.ToResponse())
+			return
+		}
+		reports[i].BalanceBase = balance
+	}
+
 	c.JSON(http.StatusOK, reports)
 }
+
+// GetFXReport returns every party balance in the base currency with its
+// unrealized exchange gain or loss
+func (h *Handler) GetFXReport(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		appErr := apperrors.Unauthorized("User not found in context")
+		c.JSON(appErr.Code, appErr.ToResponse())
+		return
+	}
+
+	asOf := c.DefaultQuery("as_of", time.Now().Format("2006-01-02"))
+
+	rates, appErr := h.fxService.LoadRates(userID)
+	if appErr != nil {
+		c.JSON(appErr.Code, appErr.ToResponse())
+		return
+	}
+
+	positions, appErr := h.journalService.GetPositions(userID, rates, asOf)
+	if appErr != nil {
+		c.JSON(appErr.Code, appErr.ToResponse())
+		return
+	}
+
+	var unrealized money.Amount
+	for _, position := range positions {
+		unrealized += position.UnrealizedFX
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"base_currency": rates.Base,
+		"as_of":         asOf,
+		"positions":     positions,
+		"unrealized_fx": unrealized,
+	})
+}
+
+// GetAgingReport returns outstanding amounts per party bucketed by age, as
+// JSON or, with format=csv, as a CSV download
+func (h *Handler) GetAgingReport(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		appErr := apperrors.Unauthorized("User not found in context")
+		c.JSON(appErr.Code, appErr.ToResponse())
+		return
+	}
+
+	buckets, err := services.ParseAgingBuckets(c.Query("buckets"))
+	if err != nil {
+		appErr := apperrors.BadRequest(err.Error())
+		c.JSON(appErr.Code, appErr.ToResponse())
+		return
+	}
+
+	report, appErr := h.agingService.Aging(userID, services.AgingOptions{
+		AsOf:      c.Query("as_of"),
+		Basis:     c.Query("basis"),
+		Buckets:   buckets,
+		PartyType: c.Query("party_type"),
+	})
+	if appErr != nil {
+		c.JSON(appErr.Code, appErr.ToResponse())
+		return
+	}
+
+	if c.Query("format") != "csv" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="aging-`+report.AsOf+`.csv"`)
+	c.Header("Content-Type", "text/csv")
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	header := append([]string{"Party", "Type", "Currency"}, report.Labels...)
+	w.Write(append(header, "Total", "Unapplied"))
+	for _, row := range report.Rows {
+		record := []string{row.PartyName, row.PartyType, row.Currency}
+		for _, amount := range row.Buckets {
+			record = append(record, amount.String())
+		}
+		w.Write(append(record, row.Total.String(), row.Unapplied.String()))
+	}
+	totals := []string{"Total", "", report.BaseCurrency}
+	for _, amount := range report.Totals {
+		totals = append(totals, amount.String())
+	}
+	w.Write(append(totals, report.Total.String(), ""))
+	w.Flush()
+}
@@ -67,7 +67,7 @@ func (h *Handler) CreateTransaction(c *gin.Context) {
                 return
         }
 
-        transaction, appErr := h.transactionService.CreateTransaction(userID, &req)
+        transaction, appErr := h.transactionService.WithContext(c.Request.Context()).CreateTransaction(userID, &req)
         if appErr != nil {
                 c.JSON(appErr.Code, appErr.ToResponse())
                 return
@@ -112,7 +112,7 @@ func (h *Handler) UpdateTransaction(c *gin.Context) {
                 return
         }
 
-        transaction, appErr := h.transactionService.UpdateTransaction(userID, transactionID, &req)
+        transaction, appErr := h.transactionService.WithContext(c.Request.Context()).UpdateTransaction(userID, transactionID, &req)
         if appErr != nil {
                 c.JSON(appErr.Code, appErr.ToResponse())
                 return
@@ -131,7 +131,7 @@ func (h *Handler) DeleteTransaction(c *gin.Context) {
         }
 
         transactionID := c.Param("id")
-        appErr := h.transactionService.DeleteTransaction(userID, transactionID)
+        appErr := h.transactionService.WithContext(c.Request.Context()).DeleteTransaction(userID, transactionID)
         if appErr != nil {
                 c.JSON(appErr.Code, appErr.ToResponse())
                 return
@@ -139,3 +139,49 @@ func (h *Handler) DeleteTransaction(c *gin.Context) {
 
         c.JSON(http.StatusOK, gin.H{"message": "Transaction deleted successfully"})
 }
+
+// RestoreTransaction restores a transaction from the trash
+func (h *Handler) RestoreTransaction(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        override := c.Query("override_credit_limit") == "true"
+        transaction, appErr := h.transactionService.WithContext(c.Request.Context()).RestoreTransaction(userID, c.Param("id"), override)
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusOK, transaction)
+}
+
+// ReverseTransaction reverses a transaction and optionally posts a correction
+func (h *Handler) ReverseTransaction(c *gin.Context) {
+        userID, ok := middleware.GetUserID(c)
+        if !ok {
+                appErr := apperrors.Unauthorized("User not found in context")
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        var req models.ReverseTransactionRequest
+        if c.Request.ContentLength > 0 {
+                if err := c.ShouldBindJSON(&req); err != nil {
+                        appErr := apperrors.BadRequest(err.Error())
+                        c.JSON(appErr.Code, appErr.ToResponse())
+                        return
+                }
+        }
+
+        reversal, appErr := h.transactionService.WithContext(c.Request.Context()).ReverseTransaction(userID, c.Param("id"), &req)
+        if appErr != nil {
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        c.JSON(http.StatusCreated, reversal)
+}
@@ -5,7 +5,9 @@ import (
 
         "khatabook-go-backend/internal/models"
         apperrors "khatabook-go-backend/pkg/errors"
+        "khatabook-go-backend/pkg/gst"
         "khatabook-go-backend/internal/middleware"
+        "khatabook-go-backend/pkg/money"
 
         "github.com/gin-gonic/gin"
 )
@@ -45,6 +47,35 @@ func (h *Handler) UpdateUserProfile(c *gin.Context) {
                 return
         }
 
+        if req.BaseCurrency != "" && !money.IsSupported(req.BaseCurrency) {
+                appErr := apperrors.BadRequest("Unsupported currency " + req.BaseCurrency)
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        if req.StateCode != "" && !gst.ValidStateCode(req.StateCode) {
+                appErr := apperrors.BadRequest("Invalid GST state code " + req.StateCode)
+                c.JSON(appErr.Code, appErr.ToResponse())
+                return
+        }
+
+        // Strict mode is one-way: once on, the ledger can only be corrected by reversals
+        if req.StrictMode != nil && !*req.StrictMode {
+                var user models.User
+                if err := h.db.Select("id", "strict_mode").Where("id = ?", userID).First(&user).Error; err != nil {
+                        appErr := apperrors.NotFound("User not found")
+                        c.JSON(appErr.Code, appErr.ToResponse())
+                        return
+                }
+                if user.StrictMode {
+                        appErr := apperrors.Forbidden("Strict mode cannot be turned off")
+                        c.JSON(appErr.Code, appErr.ToResponse())
+                        return
+                }
+                // Already off; never write false, so a concurrent switch-on stands
+                req.StrictMode = nil
+        }
+
         if err := h.db.Model(&models.User{}).Where("id = ?", userID).Updates(req).Error; err != nil {
                 appErr := apperrors.Internal("Failed to update user", err)
                 c.JSON(appErr.Code, appErr.ToResponse())
@@ -71,8 +102,11 @@ func (h *Handler) GetUserSettings(c *gin.Context) {
         }
 
         c.JSON(http.StatusOK, gin.H{
-                "language":  user.Language,
-                "theme":     user.Theme,
-                "font_size": user.FontSize,
+                "language":      user.Language,
+                "theme":         user.Theme,
+                "font_size":     user.FontSize,
+                "base_currency": user.BaseCurrency,
+                "credit_policy": user.CreditPolicy,
+                "strict_mode":   user.StrictMode,
         })
 }
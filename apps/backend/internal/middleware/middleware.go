@@ -1,10 +1,12 @@
 package middleware
 
 import (
+        "crypto/subtle"
         "fmt"
         "strings"
         "time"
 
+        "khatabook-go-backend/pkg/audit"
         "khatabook-go-backend/pkg/errors"
         "khatabook-go-backend/pkg/logger"
 
@@ -29,7 +31,7 @@ func CORS(allowedOrigins string) gin.HandlerFunc {
                 }
 
                 c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-                c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+                c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Admin-Token, X-Device-ID")
                 c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
                 if c.Request.Method == "OPTIONS" {
@@ -105,6 +107,53 @@ func AuthRequired(jwtSecret string) gin.HandlerFunc {
                 }
 
                 // Store user ID in context
+                c.Set("user_id", userID)
+
+                // Changes made by this request are audited as this user
+                c.Request = c.Request.WithContext(audit.WithActor(c.Request.Context(), audit.Actor{
+                        UserID: userID,
+                        Device: c.GetHeader("X-Device-ID"),
+                        IP:     c.ClientIP(),
+                }))
+                c.Next()
+        }
+}
+
+// AdminRequired validates the X-Admin-Token header against the configured
+// admin token. Admin routes are disabled when no token is configured.
+func AdminRequired(adminToken string) gin.HandlerFunc {
+        return func(c *gin.Context) {
+                if adminToken == "" {
+                        appErr := errors.Forbidden("Admin access is not configured")
+                        c.JSON(appErr.Code, appErr.ToResponse())
+                        c.Abort()
+                        return
+                }
+
+                token := c.GetHeader("X-Admin-Token")
+                if subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+                        appErr := errors.Unauthorized("Invalid admin token")
+                        c.JSON(appErr.Code, appErr.ToResponse())
+                        c.Abort()
+                        return
+                }
+
+                c.Next()
+        }
+}
+
+// FeedTokenRequired authenticates feeds fetched by apps that cannot send an
+// Authorization header, using the token query parameter. lookup returns the
+// user the token belongs to.
+func FeedTokenRequired(lookup func(token string) (string, *errors.AppError)) gin.HandlerFunc {
+        return func(c *gin.Context) {
+                userID, appErr := lookup(c.Query("token"))
+                if appErr != nil {
+                        c.JSON(appErr.Code, appErr.ToResponse())
+                        c.Abort()
+                        return
+                }
+
                 c.Set("user_id", userID)
                 c.Next()
         }
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"khatabook-go-backend/pkg/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Allocation settles part of a bill with part of a payment. Bills are the
+// transactions that raise what is owed on a party's account: credits for a
+// customer, debits for a supplier. Payments are those of the opposite type.
+type Allocation struct {
+	ID        string       `gorm:"primaryKey" json:"id"`
+	UserID    string       `gorm:"index;not null" json:"user_id"`
+	PartyID   string       `gorm:"index;not null" json:"party_id"`
+	PaymentID string       `gorm:"index;not null" json:"payment_id"`
+	BillID    string       `gorm:"index;not null" json:"bill_id"`
+	Amount    money.Amount `gorm:"not null" json:"amount"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// BeforeCreate hook to set UUID
+func (a *Allocation) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// AllocationRequest allocates part of a payment to one bill
+type AllocationRequest struct {
+	BillID string       `json:"bill_id" binding:"required"`
+	Amount money.Amount `json:"amount" binding:"required,gt=0"`
+}
+
+// AllocatePaymentRequest represents a manual payment allocation request
+type AllocatePaymentRequest struct {
+	Allocations []AllocationRequest `json:"allocations" binding:"required,min=1,dive"`
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Audited entities
+const (
+	AuditEntityParty       = "party"
+	AuditEntityTransaction = "transaction"
+	AuditEntityReminder    = "reminder"
+)
+
+// AuditEntities lists every entity the audit log covers
+var AuditEntities = []string{AuditEntityParty, AuditEntityTransaction, AuditEntityReminder}
+
+// Audited actions
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+	AuditActionPurge   = "purge"
+)
+
+// Audit actors
+const (
+	AuditActorUser   = "user"
+	AuditActorSystem = "system"
+)
+
+// ErrAuditImmutable is returned for attempts to change an audit log entry
+var ErrAuditImmutable = errors.New("audit log entries cannot be changed")
+
+// AuditLog records one change to a party, transaction or reminder with the
+// entity as it was before and after. Entries are only ever appended.
+type AuditLog struct {
+	ID        string    `gorm:"primaryKey" json:"id"`
+	UserID    string    `gorm:"index;not null" json:"user_id"`
+	Actor     string    `gorm:"not null" json:"actor"` // user or system
+	ActorID   *string   `json:"actor_id"`
+	DeviceID  *string   `json:"device_id"`
+	IP        *string   `json:"ip"`
+	Entity    string    `gorm:"not null;index:idx_audit_logs_entity" json:"entity"`
+	EntityID  string    `gorm:"not null;index:idx_audit_logs_entity" json:"entity_id"`
+	Action    string    `gorm:"not null" json:"action"`
+	Before    JSON      `gorm:"type:jsonb" json:"before"`
+	After     JSON      `gorm:"type:jsonb" json:"after"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// BeforeCreate hook to set UUID
+func (a *AuditLog) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// BeforeUpdate refuses changes to an audit log entry
+func (a *AuditLog) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditImmutable
+}
+
+// BeforeDelete refuses to delete an audit log entry
+func (a *AuditLog) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditImmutable
+}
+
+// JSON is a JSON document stored in a jsonb column and written out as is
+type JSON []byte
+
+// Value implements driver.Valuer
+func (j JSON) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return nil, nil
+	}
+	return string(j), nil
+}
+
+// Scan implements sql.Scanner
+func (j *JSON) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append(JSON(nil), v...)
+	case string:
+		*j = JSON(v)
+	default:
+		return fmt.Errorf("cannot scan %T into JSON", src)
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
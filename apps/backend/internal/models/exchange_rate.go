@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"khatabook-go-backend/pkg/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ExchangeRate is a dated rate converting one unit of Currency into the
+// user's base currency
+type ExchangeRate struct {
+	ID        string     `gorm:"primaryKey" json:"id"`
+	UserID    string     `gorm:"not null;uniqueIndex:idx_exchange_rates_user_currency_date" json:"user_id"`
+	Currency  string     `gorm:"size:3;not null;uniqueIndex:idx_exchange_rates_user_currency_date" json:"currency"`
+	Date      string     `gorm:"not null;uniqueIndex:idx_exchange_rates_user_currency_date" json:"date"`
+	Rate      money.Rate `gorm:"not null" json:"rate"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// BeforeCreate hook to set UUID
+func (r *ExchangeRate) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// SetExchangeRateRequest represents an exchange rate create or update request
+type SetExchangeRateRequest struct {
+	Currency string     `json:"currency" binding:"required,len=3"`
+	Date     string     `json:"date" binding:"required"`
+	Rate     money.Rate `json:"rate" binding:"required,gt=0"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"khatabook-go-backend/pkg/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CategoryInterest marks the transactions posted by interest accrual
+const CategoryInterest = "interest"
+
+// InterestTerms are the late-payment interest terms agreed with a party
+type InterestTerms struct {
+	ID        string        `gorm:"primaryKey" json:"id"`
+	UserID    string        `gorm:"index;not null" json:"user_id"`
+	PartyID   string        `gorm:"uniqueIndex;not null" json:"party_id"`
+	Rate      money.Percent `gorm:"not null" json:"rate"` // annual percentage
+	GraceDays int           `gorm:"not null;default:0" json:"grace_days"`
+	Method    string        `gorm:"not null;default:simple" json:"method"` // "simple" or "compound"
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+
+	// DeletedAt is set while the party is in the trash
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// BeforeCreate hook to set UUID
+func (t *InterestTerms) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// SetInterestTermsRequest represents an interest terms request
+type SetInterestTermsRequest struct {
+	Rate      money.Percent `json:"rate" binding:"required,gt=0"`
+	GraceDays int           `json:"grace_days" binding:"gte=0"`
+	Method    string        `json:"method" binding:"required,oneof=simple compound"`
+}
+
+// PostInterestRequest represents a request to post accrued interest
+type PostInterestRequest struct {
+	From           string `json:"from"`
+	To             string `json:"to"`
+	OverrideCredit bool   `json:"override_credit_limit"`
+}
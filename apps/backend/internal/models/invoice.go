@@ -0,0 +1,126 @@
+package models
+
+import (
+	"time"
+
+	"khatabook-go-backend/pkg/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Invoice types and statuses
+const (
+	InvoiceTypeSale     = "sale"     // issued to a customer, posted as a credit
+	InvoiceTypePurchase = "purchase" // bill received from a supplier, posted as a debit
+
+	InvoiceStatusDraft     = "draft"
+	InvoiceStatusPosted    = "posted"
+	InvoiceStatusCancelled = "cancelled"
+)
+
+// Invoice is a GST invoice header. Totals are computed from the line items
+// and stored so that posted invoices keep the figures they were issued with.
+type Invoice struct {
+	ID            string        `gorm:"primaryKey" json:"id"`
+	UserID        string        `gorm:"index;not null" json:"user_id"`
+	PartyID       string        `gorm:"index;not null" json:"party_id"`
+	InvoiceType   string        `gorm:"not null" json:"invoice_type"`
+	InvoiceNumber string        `gorm:"not null" json:"invoice_number"`
+	Date          string        `gorm:"not null" json:"date"`
+	DueDate       *string       `json:"due_date"`
+	Status        string        `gorm:"not null;default:draft" json:"status"`
+	Currency      string        `gorm:"size:3;not null;default:INR" json:"currency"`
+	SupplierState string        `gorm:"size:2" json:"supplier_state"`
+	PlaceOfSupply string        `gorm:"size:2" json:"place_of_supply"`
+	RoundOff      bool          `gorm:"not null;default:true" json:"round_off"`
+	Taxable       money.Amount  `gorm:"not null;default:0" json:"taxable"`
+	CGST          money.Amount  `gorm:"not null;default:0" json:"cgst"`
+	SGST          money.Amount  `gorm:"not null;default:0" json:"sgst"`
+	IGST          money.Amount  `gorm:"not null;default:0" json:"igst"`
+	RoundOffValue money.Amount  `gorm:"not null;default:0" json:"round_off_amount"`
+	Total         money.Amount  `gorm:"not null;default:0" json:"total"`
+	Notes         *string       `json:"notes"`
+	TransactionID *string       `gorm:"index" json:"transaction_id"`
+	Items         []InvoiceItem `gorm:"foreignKey:InvoiceID" json:"items,omitempty"`
+	Warnings      []string      `gorm:"-" json:"warnings,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+// BeforeCreate hook to set UUID
+func (i *Invoice) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == "" {
+		i.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// InvoiceItem is a line of an invoice
+type InvoiceItem struct {
+	ID        string         `gorm:"primaryKey" json:"id"`
+	InvoiceID string         `gorm:"index;not null" json:"invoice_id"`
+	Position  int            `gorm:"not null" json:"position"`
+	ItemName  string         `gorm:"not null" json:"item_name"`
+	HSNSAC    *string        `gorm:"column:hsn_sac;size:8" json:"hsn_sac"`
+	Quantity  money.Quantity `gorm:"not null" json:"quantity"`
+	Unit      *string        `json:"unit"`
+	Rate      money.Amount   `gorm:"not null" json:"rate"`
+	Discount  money.Amount   `gorm:"not null;default:0" json:"discount"`
+	GSTRate   money.Percent  `gorm:"not null;default:0" json:"gst_rate"`
+	Taxable   money.Amount   `gorm:"not null" json:"taxable"`
+	CGST      money.Amount   `gorm:"not null;default:0" json:"cgst"`
+	SGST      money.Amount   `gorm:"not null;default:0" json:"sgst"`
+	IGST      money.Amount   `gorm:"not null;default:0" json:"igst"`
+	Total     money.Amount   `gorm:"not null" json:"total"`
+}
+
+// BeforeCreate hook to set UUID
+func (i *InvoiceItem) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == "" {
+		i.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// InvoiceItemRequest represents a line item in an invoice request
+type InvoiceItemRequest struct {
+	ItemName string         `json:"item_name" binding:"required"`
+	HSNSAC   string         `json:"hsn_sac"`
+	Quantity money.Quantity `json:"quantity" binding:"gt=0"`
+	Unit     string         `json:"unit"`
+	Rate     money.Amount   `json:"rate" binding:"gte=0"`
+	Discount money.Amount   `json:"discount" binding:"gte=0"`
+	GSTRate  money.Percent  `json:"gst_rate" binding:"gte=0"`
+}
+
+// CreateInvoiceRequest represents invoice creation request. An empty invoice
+// number is generated and the place of supply defaults to the party's state.
+type CreateInvoiceRequest struct {
+	PartyID       string               `json:"party_id" binding:"required"`
+	InvoiceType   string               `json:"invoice_type" binding:"required,oneof=sale purchase"`
+	InvoiceNumber string               `json:"invoice_number"`
+	Date          string               `json:"date"`
+	DueDate       string               `json:"due_date"`
+	PlaceOfSupply string               `json:"place_of_supply" binding:"omitempty,len=2,numeric"`
+	RoundOff      *bool                `json:"round_off"`
+	Notes         string               `json:"notes"`
+	Items         []InvoiceItemRequest `json:"items" binding:"required,min=1,dive"`
+}
+
+// UpdateInvoiceRequest represents a draft invoice update request. Items,
+// when present, replace all existing lines.
+type UpdateInvoiceRequest struct {
+	InvoiceNumber string               `json:"invoice_number"`
+	Date          string               `json:"date"`
+	DueDate       string               `json:"due_date"`
+	PlaceOfSupply string               `json:"place_of_supply" binding:"omitempty,len=2,numeric"`
+	RoundOff      *bool                `json:"round_off"`
+	Notes         string               `json:"notes"`
+	Items         []InvoiceItemRequest `json:"items" binding:"omitempty,dive"`
+}
+
+// PostInvoiceRequest represents a request to post a draft invoice
+type PostInvoiceRequest struct {
+	OverrideCredit bool `json:"override_credit_limit"`
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"khatabook-go-backend/pkg/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Account types used by the double-entry journal
+const (
+	AccountTypeParty  = "party"  // receivable/payable account of a single party
+	AccountTypeCash   = "cash"   // the user's cash and bank counter account
+	AccountTypeEquity = "equity" // counter account for opening balances
+)
+
+// Account represents a ledger account in the double-entry journal
+type Account struct {
+	ID          string    `gorm:"primaryKey" json:"id"`
+	UserID      string    `gorm:"not null;uniqueIndex:idx_accounts_user_code" json:"user_id"`
+	Code        string    `gorm:"not null;uniqueIndex:idx_accounts_user_code" json:"code"`
+	Name        string    `gorm:"not null" json:"name"`
+	AccountType string    `gorm:"not null" json:"account_type"`
+	Currency    string    `gorm:"size:3;not null;default:INR" json:"currency"`
+	PartyID     *string   `gorm:"index" json:"party_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// BeforeCreate hook to set UUID
+func (a *Account) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// JournalEntry groups the balanced postings of a single business event
+type JournalEntry struct {
+	ID            string    `gorm:"primaryKey" json:"id"`
+	UserID        string    `gorm:"index;not null" json:"user_id"`
+	TransactionID *string   `gorm:"index" json:"transaction_id"`
+	Date          string    `gorm:"not null" json:"date"`
+	Description   string    `json:"description"`
+	Postings      []Posting `gorm:"foreignKey:EntryID" json:"postings,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// BeforeCreate hook to set UUID
+func (e *JournalEntry) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// Posting is one leg of a journal entry. Amounts are debit-positive, so the
+// sum over a party account equals what the party owes the user.
+type Posting struct {
+	ID        string       `gorm:"primaryKey" json:"id"`
+	EntryID   string       `gorm:"index;not null" json:"entry_id"`
+	AccountID string       `gorm:"index;not null" json:"account_id"`
+	UserID    string       `gorm:"index;not null" json:"user_id"`
+	Amount    money.Amount `gorm:"not null" json:"amount"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// BeforeCreate hook to set UUID
+func (p *Posting) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == "" {
+		p.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// AccountBalance represents an account together with its posted balance
+type AccountBalance struct {
+	Account
+	Balance money.Amount `json:"balance"`
+}
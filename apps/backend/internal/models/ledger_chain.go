@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LedgerLink is one link of a user's tamper-evident ledger chain. Every
+// change to a transaction appends a link holding a hash of the transaction
+// content, chained to the previous link by Hash = SHA-256 over PrevHash,
+// Seq, TransactionID, Action and ContentHash. Links are never changed, so
+// altering a transaction or a link afterwards breaks the chain.
+type LedgerLink struct {
+	ID            string    `gorm:"primaryKey" json:"id"`
+	UserID        string    `gorm:"not null;uniqueIndex:idx_ledger_links_seq" json:"user_id"`
+	Seq           int64     `gorm:"not null;uniqueIndex:idx_ledger_links_seq" json:"seq"`
+	TransactionID string    `gorm:"index;not null" json:"transaction_id"`
+	Action        string    `gorm:"not null" json:"action"` // an audit action
+	ContentHash   string    `gorm:"size:64;not null" json:"content_hash"`
+	PrevHash      string    `gorm:"size:64;not null" json:"prev_hash"`
+	Hash          string    `gorm:"size:64;not null" json:"hash"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// BeforeCreate hook to set UUID
+func (l *LedgerLink) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == "" {
+		l.ID = uuid.New().String()
+	}
+	return nil
+}
@@ -3,23 +3,50 @@ package models
 import (
 	"time"
 
+	"khatabook-go-backend/pkg/money"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// Party types
+const (
+	PartyTypeCustomer = "customer"
+	PartyTypeSupplier = "supplier"
+)
+
+// Credit limit policies applied when a transaction takes a party past its limit
+const (
+	CreditPolicyReject   = "reject"   // refuse the transaction
+	CreditPolicyWarn     = "warn"     // record it and return a warning
+	CreditPolicyOverride = "override" // refuse it unless the request sets override_credit_limit
+)
+
 // Party represents a customer or supplier
 type Party struct {
-	ID        string    `gorm:"primaryKey" json:"id"`
-	UserID    string    `gorm:"index;not null" json:"user_id"`
-	Name      string    `gorm:"not null" json:"name"`
-	Phone     *string   `json:"phone"`
-	Email     *string   `json:"email"`
-	Address   *string   `json:"address"`
-	Notes     *string   `json:"notes"`
-	PartyType string    `gorm:"not null" json:"party_type"` // "customer" or "supplier"
-	Balance   float64   `gorm:"default:0" json:"balance"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          string       `gorm:"primaryKey" json:"id"`
+	UserID      string       `gorm:"index;not null" json:"user_id"`
+	Name        string       `gorm:"not null" json:"name"`
+	Phone       *string      `json:"phone"`
+	Email       *string      `json:"email"`
+	Address     *string      `json:"address"`
+	Notes       *string      `json:"notes"`
+	PartyType   string       `gorm:"not null" json:"party_type"` // "customer" or "supplier"
+	Balance     money.Amount `gorm:"default:0" json:"balance"`
+	Currency    string       `gorm:"size:3;not null;default:INR" json:"currency"`
+	GSTIN       *string      `gorm:"size:15" json:"gstin"`
+	StateCode   *string      `gorm:"size:2" json:"state_code"`               // GST state code, the default place of supply
+	CreditLimit money.Amount `gorm:"not null;default:0" json:"credit_limit"` // highest balance the party may run up; 0 means no limit
+	Language    *string      `gorm:"size:8" json:"language"`                 // language of reminders; nil uses the user's
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+
+	// DeletedAt is set while the party is in the trash
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+
+	// ArchivedAt is set while the party is archived: it keeps its ledger but
+	// is left out of party lists
+	ArchivedAt *time.Time `gorm:"index" json:"archived_at"`
 }
 
 // BeforeCreate hook to set UUID
@@ -30,21 +57,47 @@ func (p *Party) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
-// CreatePartyRequest represents party creation request
+// CreatePartyRequest represents party creation request. The opening balance
+// is either a signed Balance or an OpeningBalance with a BalanceType.
 type CreatePartyRequest struct {
-	Name      string  `json:"name" binding:"required"`
-	Phone     string  `json:"phone"`
-	Email     string  `json:"email"`
-	Address   string  `json:"address"`
-	PartyType string  `json:"party_type" binding:"required"`
-	Balance   float64 `json:"balance"`
+	Name           string       `json:"name" binding:"required"`
+	Phone          string       `json:"phone"`
+	Email          string       `json:"email"`
+	Address        string       `json:"address"`
+	PartyType      string       `json:"party_type" binding:"required"`
+	Balance        money.Amount `json:"balance"`
+	OpeningBalance money.Amount `json:"opening_balance" binding:"gte=0"`
+	BalanceType    string       `json:"balance_type" binding:"omitempty,oneof=none credit debit"`
+	OpeningDate    string       `json:"opening_date"`
+	Currency       string       `json:"currency" binding:"omitempty,len=3"`
+	GSTIN          string       `json:"gstin" binding:"omitempty,len=15"`
+	StateCode      string       `json:"state_code" binding:"omitempty,len=2,numeric"`
+	CreditLimit    money.Amount `json:"credit_limit" binding:"gte=0"`
+	Language       string       `json:"language" binding:"omitempty,min=2,max=8"`
+}
+
+// SignedOpeningBalance returns the opening balance as an effect on the party balance
+func (r *CreatePartyRequest) SignedOpeningBalance() money.Amount {
+	switch r.BalanceType {
+	case TransactionTypeCredit:
+		return r.OpeningBalance
+	case TransactionTypeDebit:
+		return -r.OpeningBalance
+	default:
+		return r.Balance
+	}
 }
 
-// UpdatePartyRequest represents party update request
+// UpdatePartyRequest represents party update request. Balance is only
+// accepted so that direct balance edits can be rejected explicitly.
 type UpdatePartyRequest struct {
-	Name      string  `json:"name"`
-	Phone     string  `json:"phone"`
-	Email     string  `json:"email"`
-	Address   string  `json:"address"`
-	Balance   float64 `json:"balance"`
+	Name        string        `json:"name"`
+	Phone       string        `json:"phone"`
+	Email       string        `json:"email"`
+	Address     string        `json:"address"`
+	GSTIN       string        `json:"gstin" binding:"omitempty,len=15"`
+	StateCode   string        `json:"state_code" binding:"omitempty,len=2,numeric"`
+	CreditLimit *money.Amount `json:"credit_limit" binding:"omitempty,gte=0"`
+	Language    string        `json:"language" binding:"omitempty,min=2,max=8"`
+	Balance     *money.Amount `json:"balance" gorm:"-"`
 }
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"khatabook-go-backend/pkg/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RecurringTransaction is a template that the scheduler turns into a
+// transaction on every occurrence of its rule
+type RecurringTransaction struct {
+	ID              string       `gorm:"primaryKey" json:"id"`
+	UserID          string       `gorm:"index;not null" json:"user_id"`
+	PartyID         string       `gorm:"index;not null" json:"party_id"`
+	Amount          money.Amount `gorm:"not null" json:"amount"`
+	TransactionType string       `gorm:"not null" json:"transaction_type"` // "credit" or "debit"
+	Description     *string      `json:"description"`
+	Category        *string      `json:"category"`
+	Frequency       string       `gorm:"not null" json:"frequency"` // "daily", "weekly" or "monthly"
+	Interval        int          `gorm:"not null;default:1" json:"interval"`
+	DayOfMonth      int          `gorm:"not null;default:0" json:"day_of_month"` // monthly only; 0 keeps the start day
+	StartDate       string       `gorm:"not null" json:"start_date"`
+	EndDate         *string      `json:"end_date"`
+	NextDate        *string      `gorm:"index" json:"next_date"` // nil once the rule has ended
+	Active          bool         `gorm:"not null;default:true" json:"active"`
+	CreditHold      *string      `json:"credit_hold"` // why the next occurrence is held back by the party's credit limit
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+
+	// DeletedAt is set while the party is in the trash
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// BeforeCreate hook to set UUID
+func (r *RecurringTransaction) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// RecurringOccurrence records the transaction created for one occurrence.
+// The unique index makes each occurrence materialise at most once.
+type RecurringOccurrence struct {
+	ID            string    `gorm:"primaryKey" json:"id"`
+	RecurringID   string    `gorm:"not null;uniqueIndex:idx_recurring_occurrences_date" json:"recurring_id"`
+	Date          string    `gorm:"not null;uniqueIndex:idx_recurring_occurrences_date" json:"date"`
+	TransactionID string    `gorm:"index;not null" json:"transaction_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// BeforeCreate hook to set UUID
+func (o *RecurringOccurrence) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == "" {
+		o.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// CreateRecurringRequest represents recurring transaction creation request
+type CreateRecurringRequest struct {
+	PartyID         string       `json:"party_id" binding:"required"`
+	Amount          money.Amount `json:"amount" binding:"required,gt=0"`
+	TransactionType string       `json:"transaction_type" binding:"required,oneof=credit debit"`
+	Description     string       `json:"description"`
+	Category        string       `json:"category"`
+	Frequency       string       `json:"frequency" binding:"required,oneof=daily weekly monthly"`
+	Interval        int          `json:"interval" binding:"gte=0"`
+	DayOfMonth      int          `json:"day_of_month" binding:"gte=0,lte=31"`
+	StartDate       string       `json:"start_date"`
+	EndDate         string       `json:"end_date"`
+}
+
+// UpdateRecurringRequest represents recurring transaction update request.
+// Changing the rule reschedules from the next pending occurrence.
+type UpdateRecurringRequest struct {
+	Amount      *money.Amount `json:"amount" binding:"omitempty,gt=0"`
+	Description string        `json:"description"`
+	Category    string        `json:"category"`
+	Frequency   string        `json:"frequency" binding:"omitempty,oneof=daily weekly monthly"`
+	Interval    int           `json:"interval" binding:"gte=0"`
+	DayOfMonth  *int          `json:"day_of_month" binding:"omitempty,gte=0,lte=31"`
+	EndDate     *string       `json:"end_date"`
+	Active      *bool         `json:"active"`
+}
@@ -3,21 +3,62 @@ package models
 import (
         "time"
 
+        "khatabook-go-backend/pkg/money"
+
         "github.com/google/uuid"
         "gorm.io/gorm"
 )
 
-// Reminder represents a payment reminder
+// Reminder statuses. A reminder starts pending, is sent once it falls due and
+// may be snoozed to a later due date, after which it is sent again. Completed
+// and cancelled reminders are final.
+const (
+        ReminderStatusPending   = "pending"
+        ReminderStatusSent      = "sent"
+        ReminderStatusSnoozed   = "snoozed"
+        ReminderStatusCompleted = "completed"
+        ReminderStatusCancelled = "cancelled"
+)
+
+// Reminder event actors
+const (
+        ReminderActorUser   = "user"
+        ReminderActorSystem = "system"
+)
+
+// Reminder represents a payment reminder. Pending reminders are delivered to
+// the party once they fall due; failed deliveries are retried with backoff
+// until DeliveryFailed is set.
 type Reminder struct {
-        ID        string    `gorm:"primaryKey" json:"id"`
-        UserID    string    `gorm:"index;not null" json:"user_id"`
-        PartyID   string    `gorm:"index;not null" json:"party_id"`
-        Amount    float64   `gorm:"not null" json:"amount"`
-        DueDate   string    `gorm:"not null" json:"due_date"`
-        Message   *string   `json:"message"`
-        Status    string    `gorm:"default:pending" json:"status"` // "pending", "completed"
-        CreatedAt time.Time `json:"created_at"`
-        UpdatedAt time.Time `json:"updated_at"`
+        ID             string       `gorm:"primaryKey" json:"id"`
+        UserID         string       `gorm:"index;not null" json:"user_id"`
+        PartyID        string       `gorm:"index;not null" json:"party_id"`
+        Amount         money.Amount `gorm:"not null" json:"amount"`
+        DueDate        string       `gorm:"not null" json:"due_date"`
+        Message        *string      `json:"message"`
+        Status         string       `gorm:"default:pending" json:"status"` // "pending", "sent", "snoozed", "completed", "cancelled"
+        Channel        *string      `gorm:"size:16" json:"channel"`        // nil uses the default channel
+        Attempts       int          `gorm:"not null;default:0" json:"delivery_attempts"`
+        NextAttemptAt  *time.Time   `gorm:"index" json:"next_attempt_at"`
+        LeasedUntil    *time.Time   `json:"-"` // set while a dispatcher is sending the reminder
+        LastError      *string      `json:"last_error"`
+        DeliveryFailed bool         `gorm:"not null;default:false" json:"delivery_failed"`
+        SentAt         *time.Time   `json:"sent_at"`
+        Settled        money.Amount `gorm:"not null;default:0" json:"settled"` // paid towards Amount
+        RuleID         *string      `gorm:"index" json:"rule_id"`              // set when created by a reminder rule
+        TemplateID     *string      `json:"template_id"`                       // nil uses the default template of the party's language
+        CreatedAt      time.Time    `json:"created_at"`
+        UpdatedAt      time.Time    `json:"updated_at"`
+
+        // DeletedAt is set while the reminder is in the trash
+        DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+
+        Settlements []ReminderSettlement `gorm:"foreignKey:ReminderID" json:"settlements,omitempty"`
+}
+
+// Outstanding returns the part of the reminder amount not yet paid
+func (r *Reminder) Outstanding() money.Amount {
+        return r.Amount - r.Settled
 }
 
 // BeforeCreate hook to set UUID
@@ -28,16 +69,91 @@ func (r *Reminder) BeforeCreate(tx *gorm.DB) error {
         return nil
 }
 
+// ReminderDelivery records one attempt to deliver a reminder
+type ReminderDelivery struct {
+        ID         string    `gorm:"primaryKey" json:"id"`
+        ReminderID string    `gorm:"index;not null" json:"reminder_id"`
+        UserID     string    `gorm:"index;not null" json:"user_id"`
+        Attempt    int       `gorm:"not null" json:"attempt"`
+        Channel    string    `gorm:"not null" json:"channel"`
+        Recipient  string    `json:"recipient"`
+        Status     string    `gorm:"not null" json:"status"` // "sent", "failed"
+        ProviderID *string   `json:"provider_id"`
+        Error      *string   `json:"error"`
+        CreatedAt  time.Time `json:"created_at"`
+}
+
+// BeforeCreate hook to set UUID
+func (d *ReminderDelivery) BeforeCreate(tx *gorm.DB) error {
+        if d.ID == "" {
+                d.ID = uuid.New().String()
+        }
+        return nil
+}
+
+// ReminderEvent records a change of a reminder's status or due date
+type ReminderEvent struct {
+        ID          string    `gorm:"primaryKey" json:"id"`
+        ReminderID  string    `gorm:"index;not null" json:"reminder_id"`
+        UserID      string    `gorm:"index;not null" json:"user_id"`
+        FromStatus  string    `json:"from_status"` // empty when the reminder was created
+        ToStatus    string    `gorm:"not null" json:"to_status"`
+        FromDueDate string    `json:"from_due_date"`
+        ToDueDate   string    `json:"to_due_date"`
+        Actor       string    `gorm:"not null" json:"actor"` // "user", "system"
+        Note        *string   `json:"note"`
+        CreatedAt   time.Time `json:"created_at"`
+}
+
+// BeforeCreate hook to set UUID
+func (e *ReminderEvent) BeforeCreate(tx *gorm.DB) error {
+        if e.ID == "" {
+                e.ID = uuid.New().String()
+        }
+        return nil
+}
+
+// ReminderSettlement records a payment applied to a reminder
+type ReminderSettlement struct {
+        ID            string       `gorm:"primaryKey" json:"id"`
+        ReminderID    string       `gorm:"index;not null" json:"reminder_id"`
+        UserID        string       `gorm:"index;not null" json:"user_id"`
+        TransactionID string       `gorm:"index;not null" json:"transaction_id"`
+        Amount        money.Amount `gorm:"not null" json:"amount"`
+        Completed     bool         `gorm:"not null;default:false" json:"completed"` // this payment completed the reminder
+        CreatedAt     time.Time    `json:"created_at"`
+}
+
+// BeforeCreate hook to set UUID
+func (s *ReminderSettlement) BeforeCreate(tx *gorm.DB) error {
+        if s.ID == "" {
+                s.ID = uuid.New().String()
+        }
+        return nil
+}
+
 // CreateReminderRequest represents reminder creation request
 type CreateReminderRequest struct {
-        PartyID string  `json:"party_id" binding:"required"`
-        Amount  float64 `json:"amount" binding:"required,gt=0"`
-        DueDate string  `json:"due_date" binding:"required"`
-        Message string  `json:"message"`
+        PartyID    string       `json:"party_id" binding:"required"`
+        Amount     money.Amount `json:"amount" binding:"required,gt=0"`
+        DueDate    string       `json:"due_date" binding:"required"`
+        Message    string       `json:"message"` // may use template placeholders; overrides the template
+        Channel    string       `json:"channel" binding:"omitempty,oneof=email sms whatsapp log"`
+        TemplateID string       `json:"template_id"`
 }
 
 // UpdateReminderRequest represents reminder update request
 type UpdateReminderRequest struct {
-        Status  string `json:"status" binding:"required"`
+        Status  string `json:"status" binding:"required,oneof=pending sent snoozed completed cancelled"`
         Message string `json:"message"`
+        Channel string `json:"channel" binding:"omitempty,oneof=email sms whatsapp log"`
+        Note    string `json:"note"`
+}
+
+// SnoozeReminderRequest moves a reminder's due date, either to Until or by
+// Days from the later of today and the current due date
+type SnoozeReminderRequest struct {
+        Until string `json:"until"`
+        Days  int    `json:"days" binding:"omitempty,gt=0,lte=365"`
+        Note  string `json:"note"`
 }
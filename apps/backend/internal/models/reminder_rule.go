@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"khatabook-go-backend/pkg/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReminderRule creates reminders automatically for parties whose balance has
+// been unpaid for OverdueDays. A rule without a party applies to every party
+// of the user that has no rule of its own. MinAmount is in the party's
+// currency for a party rule and in the user's base currency for a user-wide
+// rule.
+type ReminderRule struct {
+	ID          string       `gorm:"primaryKey" json:"id"`
+	UserID      string       `gorm:"index;not null" json:"user_id"`
+	PartyID     *string      `gorm:"index" json:"party_id"`
+	OverdueDays int          `gorm:"not null" json:"overdue_days"`
+	MinAmount   money.Amount `gorm:"not null;default:0" json:"min_amount"`
+	RepeatDays  int          `gorm:"not null;default:7" json:"repeat_days"` // minimum days between reminders the rule creates for a party
+	Channel     *string      `gorm:"size:16" json:"channel"`
+	Message     *string      `json:"message"`
+	TemplateID  *string      `json:"template_id"`
+	Active      bool         `gorm:"not null;default:true" json:"active"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+
+	// DeletedAt is set while the rule's party is in the trash
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// BeforeCreate hook to set UUID
+func (r *ReminderRule) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// CreateReminderRuleRequest represents reminder rule creation request
+type CreateReminderRuleRequest struct {
+	PartyID     string       `json:"party_id"`
+	OverdueDays int          `json:"overdue_days" binding:"gte=0,lte=3650"`
+	MinAmount   money.Amount `json:"min_amount" binding:"gte=0"`
+	RepeatDays  *int         `json:"repeat_days" binding:"omitempty,gte=1,lte=365"`
+	Channel     string       `json:"channel" binding:"omitempty,oneof=email sms whatsapp log"`
+	Message     string       `json:"message"`
+	TemplateID  string       `json:"template_id"`
+}
+
+// UpdateReminderRuleRequest represents reminder rule update request
+type UpdateReminderRuleRequest struct {
+	OverdueDays *int          `json:"overdue_days" binding:"omitempty,gte=0,lte=3650"`
+	MinAmount   *money.Amount `json:"min_amount" binding:"omitempty,gte=0"`
+	RepeatDays  *int          `json:"repeat_days" binding:"omitempty,gte=1,lte=365"`
+	Channel     *string       `json:"channel" binding:"omitempty,oneof=email sms whatsapp log"`
+	Message     *string       `json:"message"`
+	TemplateID  *string       `json:"template_id"`
+	Active      *bool         `json:"active"`
+}
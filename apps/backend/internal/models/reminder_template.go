@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"khatabook-go-backend/pkg/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReminderTemplate is a stored reminder message in one language. Subject and
+// Body may use the placeholders {{party_name}}, {{amount}}, {{due_date}} and
+// {{business_name}}. The default template of a language is used for
+// reminders that do not name one.
+type ReminderTemplate struct {
+	ID        string    `gorm:"primaryKey" json:"id"`
+	UserID    string    `gorm:"index;not null" json:"user_id"`
+	Name      string    `gorm:"not null" json:"name"`
+	Language  string    `gorm:"size:8;not null;default:en" json:"language"`
+	Subject   *string   `json:"subject"` // email subject; nil uses the built-in one
+	Body      string    `gorm:"not null" json:"body"`
+	IsDefault bool      `gorm:"not null;default:false" json:"is_default"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BeforeCreate hook to set UUID
+func (t *ReminderTemplate) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// CreateReminderTemplateRequest represents reminder template creation request
+type CreateReminderTemplateRequest struct {
+	Name      string `json:"name" binding:"required"`
+	Language  string `json:"language" binding:"omitempty,min=2,max=8"`
+	Subject   string `json:"subject"`
+	Body      string `json:"body" binding:"required"`
+	IsDefault bool   `json:"is_default"`
+}
+
+// UpdateReminderTemplateRequest represents reminder template update request
+type UpdateReminderTemplateRequest struct {
+	Name      string  `json:"name"`
+	Language  string  `json:"language" binding:"omitempty,min=2,max=8"`
+	Subject   *string `json:"subject"`
+	Body      string  `json:"body"`
+	IsDefault *bool   `json:"is_default"`
+}
+
+// PreviewReminderTemplateRequest renders a template. The text comes from
+// TemplateID, Body or the reminder's own template, in that order, and the
+// values from ReminderID or PartyID, with sample values for the rest.
+type PreviewReminderTemplateRequest struct {
+	TemplateID string       `json:"template_id"`
+	Subject    string       `json:"subject"`
+	Body       string       `json:"body"`
+	Language   string       `json:"language" binding:"omitempty,min=2,max=8"`
+	ReminderID string       `json:"reminder_id"`
+	PartyID    string       `json:"party_id"`
+	Amount     money.Amount `json:"amount" binding:"gte=0"`
+	DueDate    string       `json:"due_date"`
+}
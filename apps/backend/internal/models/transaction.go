@@ -3,24 +3,48 @@ package models
 import (
         "time"
 
+        "khatabook-go-backend/pkg/money"
+
         "github.com/google/uuid"
         "gorm.io/gorm"
 )
 
+// Transaction types
+const (
+        TransactionTypeCredit  = "credit"  // increases what the party owes
+        TransactionTypeDebit   = "debit"   // decreases what the party owes
+        TransactionTypeOpening = "opening" // opening balance; Amount carries the sign
+)
+
 // Transaction represents a financial transaction
 type Transaction struct {
-        ID              string    `gorm:"primaryKey" json:"id"`
-        UserID          string    `gorm:"index;not null" json:"user_id"`
-        PartyID         string    `gorm:"index;not null" json:"party_id"`
-        Amount          float64   `gorm:"not null" json:"amount"`
-        TransactionType string    `gorm:"not null" json:"transaction_type"` // "credit" or "debit"
-        Description     *string   `json:"description"`
-        Date            string    `gorm:"not null" json:"date"`
-        Category        *string   `json:"category"`
-        AttachmentURL   *string   `json:"attachment_url"`
-        RunningBalance  float64   `json:"running_balance"`
-        CreatedAt       time.Time `json:"created_at"`
-        UpdatedAt       time.Time `json:"updated_at"`
+        ID              string       `gorm:"primaryKey" json:"id"`
+        UserID          string       `gorm:"index;not null" json:"user_id"`
+        PartyID         string       `gorm:"index;not null" json:"party_id"`
+        Amount          money.Amount `gorm:"not null" json:"amount"`
+        Currency        string       `gorm:"size:3;not null;default:INR" json:"currency"`
+        TransactionType string       `gorm:"not null" json:"transaction_type"` // "credit" or "debit"
+        Description     *string      `json:"description"`
+        Date            string       `gorm:"not null" json:"date"`
+        Category        *string      `json:"category"`
+        AttachmentURL   *string      `json:"attachment_url"`
+        RunningBalance  money.Amount `json:"running_balance"`
+        CreatedAt       time.Time    `json:"created_at"`
+        UpdatedAt       time.Time    `json:"updated_at"`
+        Warnings        []string     `gorm:"-" json:"warnings,omitempty"`
+
+        // DeletedAt is set while the transaction is in the trash
+        DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+
+        // ChainSeq and ChainHash identify the latest link of the user's
+        // ledger hash chain that covers the transaction
+        ChainSeq  *int64  `json:"chain_seq"`
+        ChainHash *string `gorm:"size:64" json:"chain_hash"`
+
+        // ReversalOf links a reversing entry to the transaction it cancels and
+        // ReplacementOf links a corrected entry to the transaction it replaces
+        ReversalOf    *string `gorm:"index" json:"reversal_of"`
+        ReplacementOf *string `gorm:"index" json:"replacement_of"`
 }
 
 // BeforeCreate hook to set UUID
@@ -31,21 +55,54 @@ func (t *Transaction) BeforeCreate(tx *gorm.DB) error {
         return nil
 }
 
+// SignedAmount returns the effect of the transaction on the party balance
+func (t *Transaction) SignedAmount() money.Amount {
+        switch t.TransactionType {
+        case TransactionTypeCredit, TransactionTypeOpening:
+                return t.Amount
+        default:
+                return -t.Amount
+        }
+}
+
 // CreateTransactionRequest represents transaction creation request
 type CreateTransactionRequest struct {
-        PartyID         string  `json:"party_id" binding:"required"`
-        Amount          float64 `json:"amount" binding:"required,gt=0"`
-        TransactionType string  `json:"transaction_type" binding:"required"`
-        Description     string  `json:"description"`
-        Date            string  `json:"date"`
-        Category        string  `json:"category"`
+        PartyID         string       `json:"party_id" binding:"required"`
+        Amount          money.Amount `json:"amount" binding:"required,gt=0"`
+        Currency        string       `json:"currency" binding:"omitempty,len=3"`
+        TransactionType string       `json:"transaction_type" binding:"required,oneof=credit debit"`
+        Description     string       `json:"description"`
+        Date            string       `json:"date"`
+        Category        string       `json:"category"`
+        AutoAllocate    *bool        `json:"auto_allocate"` // FIFO-allocate payments to open bills; defaults to true
+        OverrideCredit  bool         `json:"override_credit_limit"`
 }
 
 // UpdateTransactionRequest represents transaction update request
 type UpdateTransactionRequest struct {
-        Amount          float64 `json:"amount"`
-        TransactionType string  `json:"transaction_type"`
-        Description     string  `json:"description"`
-        Date            string  `json:"date"`
-        Category        string  `json:"category"`
+        Amount          *money.Amount `json:"amount"` // nil leaves it unchanged; an opening balance may be set to 0
+        TransactionType string        `json:"transaction_type" binding:"omitempty,oneof=credit debit"`
+        Description     string        `json:"description"`
+        Date            string        `json:"date"`
+        Category        string        `json:"category"`
+        OverrideCredit  bool          `json:"override_credit_limit"`
+}
+
+// ReverseTransactionRequest represents a request to cancel a transaction with
+// a reversing entry and optionally post a corrected one in its place
+type ReverseTransactionRequest struct {
+        Date        string                         `json:"date"` // date of the reversing entry; defaults to today
+        Description string                         `json:"description"`
+        Replacement *ReplacementTransactionRequest `json:"replacement"`
+}
+
+// ReplacementTransactionRequest describes the corrected entry. Fields left
+// empty are copied from the reversed transaction.
+type ReplacementTransactionRequest struct {
+        Amount          *money.Amount `json:"amount"`
+        TransactionType string        `json:"transaction_type" binding:"omitempty,oneof=credit debit"`
+        Description     string        `json:"description"`
+        Date            string        `json:"date"`
+        Category        string        `json:"category"`
+        OverrideCredit  bool          `json:"override_credit_limit"`
 }
@@ -17,6 +17,11 @@ type User struct {
 	Language     string    `gorm:"default:en" json:"language"`
 	Theme        string    `gorm:"default:theme-classic" json:"theme"`
 	FontSize     string    `gorm:"default:medium" json:"font_size"`
+	BaseCurrency string    `gorm:"size:3;not null;default:INR" json:"base_currency"`
+	GSTIN        *string   `gorm:"size:15" json:"gstin"`
+	StateCode    *string   `gorm:"size:2" json:"state_code"` // GST state code of the business
+	CreditPolicy string    `gorm:"not null;default:warn" json:"credit_policy"`
+	StrictMode   bool      `gorm:"not null;default:false" json:"strict_mode"` // posted transactions are reversed, never edited or deleted
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
@@ -31,8 +36,14 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 
 // UpdateUserRequest represents user profile update request
 type UpdateUserRequest struct {
-	Name  string `json:"name"`
-	Phone string `json:"phone"`
+	Name         string `json:"name"`
+	Phone        string `json:"phone"`
+	BaseCurrency string `json:"base_currency" binding:"omitempty,len=3"`
+	GSTIN        string `json:"gstin" binding:"omitempty,len=15"`
+	StateCode    string `json:"state_code" binding:"omitempty,len=2,numeric"`
+	CreditPolicy string `json:"credit_policy" binding:"omitempty,oneof=reject warn override"`
+	Language     string `json:"language" binding:"omitempty,min=2,max=8"`
+	StrictMode   *bool  `json:"strict_mode"` // may only be turned on
 }
 
 // RegisterRequest represents user registration request
@@ -0,0 +1,275 @@
+package services
+
+import (
+        "fmt"
+        "sort"
+        "strconv"
+        "strings"
+        "time"
+
+        "khatabook-go-backend/internal/models"
+        apperrors "khatabook-go-backend/pkg/errors"
+        "khatabook-go-backend/pkg/money"
+
+        "gorm.io/gorm"
+)
+
+// Aging bases
+const (
+        AgingBasisTransaction = "transaction" // age from the transaction date
+        AgingBasisDue         = "due"         // age from the invoice due date, where there is one
+)
+
+// DefaultAgingBuckets are the upper bounds in days of the standard buckets
+// 0-30, 31-60, 61-90 and 91+
+var DefaultAgingBuckets = []int{30, 60, 90}
+
+// AgingService buckets outstanding bills by age
+type AgingService struct {
+        db *gorm.DB
+        fx *FXService
+}
+
+// NewAgingService creates a new aging service
+func NewAgingService(db *gorm.DB) *AgingService {
+        return &AgingService{db: db, fx: NewFXService(db)}
+}
+
+// AgingOptions selects what an aging report covers
+type AgingOptions struct {
+        AsOf      string // date ages are measured to
+        Basis     string // AgingBasisTransaction or AgingBasisDue
+        Buckets   []int  // increasing upper bounds in days
+        PartyType string // customer, supplier or empty for both
+}
+
+// AgingRow is one party of an aging report, in the party's currency
+type AgingRow struct {
+        PartyID   string         `json:"party_id"`
+        PartyName string         `json:"party_name"`
+        PartyType string         `json:"party_type"`
+        Currency  string         `json:"currency"`
+        Buckets   []money.Amount `json:"buckets"`
+        Total     money.Amount   `json:"total"`
+        Unapplied money.Amount   `json:"unapplied"`
+}
+
+// AgingReport holds outstanding amounts per party and age bucket. Totals
+// are converted into the base currency at the rate on AsOf.
+type AgingReport struct {
+        AsOf         string         `json:"as_of"`
+        Basis        string         `json:"basis"`
+        BaseCurrency string         `json:"base_currency"`
+        Labels       []string       `json:"labels"`
+        Rows         []AgingRow     `json:"rows"`
+        Totals       []money.Amount `json:"totals"`
+        Total        money.Amount   `json:"total"`
+}
+
+// ParseAgingBuckets parses a comma-separated list of increasing day counts
+// such as "30,60,90"
+func ParseAgingBuckets(s string) ([]int, error) {
+        if strings.TrimSpace(s) == "" {
+                return DefaultAgingBuckets, nil
+        }
+
+        var buckets []int
+        for _, part := range strings.Split(s, ",") {
+                days, err := strconv.Atoi(strings.TrimSpace(part))
+                if err != nil || days <= 0 {
+                        return nil, fmt.Errorf("invalid bucket %q", part)
+                }
+                if len(buckets) > 0 && days <= buckets[len(buckets)-1] {
+                        return nil, fmt.Errorf("buckets must be increasing")
+                }
+                buckets = append(buckets, days)
+        }
+        return buckets, nil
+}
+
+// agingLabels names the buckets, with a leading "Not due" column when
+// ageing from due dates
+func agingLabels(basis string, buckets []int) []string {
+        var labels []string
+        if basis == AgingBasisDue {
+                labels = append(labels, "Not due")
+        }
+        // Amounts not yet due have their own column, so overdue ones start at a day
+        lower := 0
+        if basis == AgingBasisDue {
+                lower = 1
+        }
+        for _, upper := range buckets {
+                labels = append(labels, fmt.Sprintf("%d-%d", lower, upper))
+                lower = upper + 1
+        }
+        return append(labels, fmt.Sprintf("%d+", lower))
+}
+
+// bucketIndex returns the column of an amount that is days old. Bucket
+// bounds are inclusive, so with 30, 60 and 90 day 90 is in 61-90 and day 91
+// in 91+.
+func bucketIndex(basis string, buckets []int, days int) int {
+        offset := 0
+        if basis == AgingBasisDue {
+                if days <= 0 {
+                        return 0
+                }
+                offset = 1
+        }
+        i := sort.SearchInts(buckets, days)
+        return offset + i
+}
+
+// Aging builds the aging report of a user. Payments are applied to the
+// bills they are allocated to, and any unallocated payment is applied to the
+// oldest remaining bills so each party's aged total equals its balance.
+func (s *AgingService) Aging(userID string, opts AgingOptions) (*AgingReport, *apperrors.AppError) {
+        if opts.AsOf == "" {
+                opts.AsOf = time.Now().Format("2006-01-02")
+        }
+        asOf, err := time.Parse("2006-01-02", opts.AsOf)
+        if err != nil {
+                return nil, apperrors.BadRequest("as_of must be a date in YYYY-MM-DD format")
+        }
+        if opts.Basis == "" {
+                opts.Basis = AgingBasisTransaction
+        }
+        if opts.Basis != AgingBasisTransaction && opts.Basis != AgingBasisDue {
+                return nil, apperrors.BadRequest("basis must be transaction or due")
+        }
+        if len(opts.Buckets) == 0 {
+                opts.Buckets = DefaultAgingBuckets
+        }
+
+        rates, appErr := s.fx.LoadRates(userID)
+        if appErr != nil {
+                return nil, appErr
+        }
+
+        var parties []models.Party
+        query := s.db.Where("user_id = ?", userID)
+        if opts.PartyType != "" {
+                query = query.Where("party_type = ?", opts.PartyType)
+        }
+        if err := query.Order("name").Find(&parties).Error; err != nil {
+                return nil, apperrors.Internal("Failed to fetch parties", err)
+        }
+
+        var transactions []models.Transaction
+        if err := s.db.Where("user_id = ? AND date <= ?", userID, opts.AsOf).
+                Order("party_id, " + ledgerOrder).
+                Find(&transactions).Error; err != nil {
+                return nil, apperrors.Internal("Failed to fetch transactions", err)
+        }
+        byParty := map[string][]models.Transaction{}
+        loaded := map[string]bool{}
+        for _, t := range transactions {
+                byParty[t.PartyID] = append(byParty[t.PartyID], t)
+                loaded[t.ID] = true
+        }
+
+        var allocations []models.Allocation
+        if err := s.db.Where("user_id = ?", userID).Find(&allocations).Error; err != nil {
+                return nil, apperrors.Internal("Failed to fetch allocations", err)
+        }
+        allocationsByParty := map[string][]models.Allocation{}
+        for _, a := range allocations {
+                // Settlements by payments after the as-of date did not exist yet
+                if !loaded[a.PaymentID] || !loaded[a.BillID] {
+                        continue
+                }
+                allocationsByParty[a.PartyID] = append(allocationsByParty[a.PartyID], a)
+        }
+
+        dueDates := map[string]string{}
+        if opts.Basis == AgingBasisDue {
+                var invoices []models.Invoice
+                if err := s.db.Select("transaction_id", "due_date").
+                        Where("user_id = ? AND transaction_id IS NOT NULL AND due_date IS NOT NULL", userID).
+                        Find(&invoices).Error; err != nil {
+                        return nil, apperrors.Internal("Failed to fetch invoices", err)
+                }
+                for _, invoice := range invoices {
+                        dueDates[*invoice.TransactionID] = *invoice.DueDate
+                }
+        }
+
+        labels := agingLabels(opts.Basis, opts.Buckets)
+        report := &AgingReport{
+                AsOf:         opts.AsOf,
+                Basis:        opts.Basis,
+                BaseCurrency: rates.Base,
+                Labels:       labels,
+                Rows:         []AgingRow{},
+                Totals:       make([]money.Amount, len(labels)),
+        }
+
+        for i := range parties {
+                party := &parties[i]
+                bills, payments := splitOpenItems(party, byParty[party.ID], allocationsByParty[party.ID])
+
+                // Apply unallocated payments to the oldest open bills
+                var unapplied money.Amount
+                for _, payment := range payments {
+                        unapplied += payment.Outstanding
+                }
+                for b := range bills {
+                        applied := bills[b].Outstanding
+                        if unapplied < applied {
+                                applied = unapplied
+                        }
+                        bills[b].Outstanding -= applied
+                        unapplied -= applied
+                }
+
+                row := AgingRow{
+                        PartyID:   party.ID,
+                        PartyName: party.Name,
+                        PartyType: party.PartyType,
+                        Currency:  party.Currency,
+                        Buckets:   make([]money.Amount, len(labels)),
+                        Unapplied: unapplied,
+                }
+                for _, bill := range bills {
+                        if bill.Outstanding <= 0 {
+                                continue
+                        }
+                        from := bill.Date
+                        if due, ok := dueDates[bill.ID]; ok {
+                                from = due
+                        }
+                        date, err := time.Parse("2006-01-02", from)
+                        if err != nil {
+                                return nil, apperrors.Internal("Invalid date on transaction "+bill.ID, err)
+                        }
+                        days := daysBetween(date, asOf)
+
+                        row.Buckets[bucketIndex(opts.Basis, opts.Buckets, days)] += bill.Outstanding
+                        row.Total += bill.Outstanding
+                }
+                if row.Total == 0 && row.Unapplied == 0 {
+                        continue
+                }
+
+                for b, amount := range row.Buckets {
+                        converted, err := rates.ToBase(amount, party.Currency, opts.AsOf)
+                        if err != nil {
+                                return nil, apperrors.Unprocessable(err.Error())
+                        }
+                        report.Totals[b] += converted
+                        report.Total += converted
+                }
+                report.Rows = append(report.Rows, row)
+        }
+
+        return report, nil
+}
+
+// daysBetween counts the calendar days from one date to another, ignoring
+// the time of day and any daylight saving change in between
+func daysBetween(from, to time.Time) int {
+        start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+        end := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+        return int(end.Sub(start).Hours() / 24)
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+        "reflect"
+        "testing"
+        "time"
+)
+
+func TestParseAgingBuckets(t *testing.T) {
+        tests := []struct {
+                in      string
+                want    []int
+                wantErr bool
+        }{
+                {"", DefaultAgingBuckets, false},
+                {"30,60,90", []int{30, 60, 90}, false},
+                {" 15, 45 ", []int{15, 45}, false},
+                {"30,30", nil, true},
+                {"60,30", nil, true},
+                {"30,x", nil, true},
+        }
+        for _, tt := range tests {
+                got, err := ParseAgingBuckets(tt.in)
+                if (err != nil) != tt.wantErr {
+                        t.Errorf("ParseAgingBuckets(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+                        continue
+                }
+                if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+                        t.Errorf("ParseAgingBuckets(%q) = %v, want %v", tt.in, got, tt.want)
+                }
+        }
+}
+
+func TestAgingLabels(t *testing.T) {
+        tests := []struct {
+                basis   string
+                buckets []int
+                want    []string
+        }{
+                {AgingBasisTransaction, []int{30, 60, 90}, []string{"0-30", "31-60", "61-90", "91+"}},
+                {AgingBasisDue, []int{30, 60, 90}, []string{"Not due", "1-30", "31-60", "61-90", "91+"}},
+                {AgingBasisTransaction, []int{7}, []string{"0-7", "8+"}},
+        }
+        for _, tt := range tests {
+                if got := agingLabels(tt.basis, tt.buckets); !reflect.DeepEqual(got, tt.want) {
+                        t.Errorf("agingLabels(%s, %v) = %v, want %v", tt.basis, tt.buckets, got, tt.want)
+                }
+        }
+}
+
+func TestBucketIndex(t *testing.T) {
+        buckets := []int{30, 60, 90}
+        tests := []struct {
+                basis string
+                days  int
+                want  int
+        }{
+                {AgingBasisTransaction, 0, 0},
+                {AgingBasisTransaction, 30, 0},
+                {AgingBasisTransaction, 31, 1},
+                {AgingBasisTransaction, 60, 1},
+                {AgingBasisTransaction, 61, 2},
+                {AgingBasisTransaction, 90, 2},
+                {AgingBasisTransaction, 91, 3},
+                {AgingBasisTransaction, 400, 3},
+                {AgingBasisDue, -5, 0},
+                {AgingBasisDue, 0, 0},
+                {AgingBasisDue, 1, 1},
+                {AgingBasisDue, 30, 1},
+                {AgingBasisDue, 90, 3},
+                {AgingBasisDue, 91, 4},
+        }
+        for _, tt := range tests {
+                got := bucketIndex(tt.basis, buckets, tt.days)
+                if got != tt.want {
+                        t.Errorf("bucketIndex(%s, %d) = %d, want %d", tt.basis, tt.days, got, tt.want)
+                }
+                // The column must be the one its label names
+                labels := agingLabels(tt.basis, buckets)
+                if got >= len(labels) {
+                        t.Errorf("bucketIndex(%s, %d) = %d is past the %d labels", tt.basis, tt.days, got, len(labels))
+                }
+        }
+}
+
+func TestDaysBetween(t *testing.T) {
+        kolkata := time.FixedZone("IST", 19800)
+        newYork, err := time.LoadLocation("America/New_York")
+        if err != nil {
+                newYork = time.FixedZone("EST", -5*3600)
+        }
+        tests := []struct {
+                name     string
+                from, to time.Time
+                want     int
+        }{
+                {"same day", testDate(2024, 3, 1, time.UTC), testDate(2024, 3, 1, time.UTC), 0},
+                {"leap February", testDate(2024, 2, 1, time.UTC), testDate(2024, 3, 1, time.UTC), 29},
+                {"future date", testDate(2024, 3, 10, time.UTC), testDate(2024, 3, 1, time.UTC), -9},
+                {"late in the day", time.Date(2024, 3, 1, 23, 59, 0, 0, kolkata), testDate(2024, 3, 2, kolkata), 1},
+                {"across spring forward", testDate(2024, 3, 9, newYork), testDate(2024, 3, 11, newYork), 2},
+                {"across fall back", testDate(2024, 11, 2, newYork), testDate(2024, 11, 4, newYork), 2},
+        }
+        for _, tt := range tests {
+                if got := daysBetween(tt.from, tt.to); got != tt.want {
+                        t.Errorf("%s: daysBetween = %d, want %d", tt.name, got, tt.want)
+                }
+        }
+}
+
+func testDate(year int, month time.Month, day int, loc *time.Location) time.Time {
+        return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,344 @@
+package services
+
+import (
+        "errors"
+
+        "khatabook-go-backend/internal/models"
+        apperrors "khatabook-go-backend/pkg/errors"
+        "khatabook-go-backend/pkg/money"
+
+        "gorm.io/gorm"
+)
+
+// AllocationService links payments to the bills they settle
+type AllocationService struct {
+        db *gorm.DB
+}
+
+// NewAllocationService creates a new allocation service
+func NewAllocationService(db *gorm.DB) *AllocationService {
+        return &AllocationService{db: db}
+}
+
+// OpenItem is a bill or payment with the part of it already allocated
+type OpenItem struct {
+        models.Transaction
+        Allocated   money.Amount `json:"allocated"`
+        Outstanding money.Amount `json:"outstanding"`
+}
+
+// TransactionAllocations lists the allocations of a bill or a payment
+type TransactionAllocations struct {
+        OpenItem
+        IsBill      bool                `json:"is_bill"`
+        Allocations []models.Allocation `json:"allocations"`
+}
+
+// GetBills retrieves a party's bills with their outstanding amounts, in
+// ledger order. With openOnly set, settled bills are left out.
+func (s *AllocationService) GetBills(userID, partyID string, openOnly bool) ([]OpenItem, *apperrors.AppError) {
+        var party models.Party
+        if err := s.db.Where("id = ? AND user_id = ?", partyID, userID).First(&party).Error; err != nil {
+                if errors.Is(err, gorm.ErrRecordNotFound) {
+                        return nil, apperrors.NotFound("Party not found")
+                }
+                return nil, apperrors.Internal("Database error", err)
+        }
+
+        bills, _, err := openItems(s.db, &party)
+        if err != nil {
+                return nil, apperrors.Internal("Failed to fetch bills", err)
+        }
+
+        result := []OpenItem{}
+        for _, bill := range bills {
+                if openOnly && bill.Outstanding == 0 {
+                        continue
+                }
+                result = append(result, bill)
+        }
+        return result, nil
+}
+
+// GetAllocations retrieves the allocations of a bill or payment
+func (s *AllocationService) GetAllocations(userID, transactionID string) (*TransactionAllocations, *apperrors.AppError) {
+        var transaction models.Transaction
+        if err := s.db.Where("id = ? AND user_id = ?", transactionID, userID).First(&transaction).Error; err != nil {
+                if errors.Is(err, gorm.ErrRecordNotFound) {
+                        return nil, apperrors.NotFound("Transaction not found")
+                }
+                return nil, apperrors.Internal("Database error", err)
+        }
+
+        var party models.Party
+        if err := s.db.Where("id = ?", transaction.PartyID).First(&party).Error; err != nil {
+                return nil, apperrors.Internal("Database error", err)
+        }
+
+        result := &TransactionAllocations{IsBill: isBill(&party, &transaction), Allocations: []models.Allocation{}}
+        if err := s.db.Where("payment_id = ? OR bill_id = ?", transactionID, transactionID).
+                Order("created_at").
+                Find(&result.Allocations).Error; err != nil {
+                return nil, apperrors.Internal("Failed to fetch allocations", err)
+        }
+
+        result.Transaction = transaction
+        for _, allocation := range result.Allocations {
+                result.Allocated += allocation.Amount
+        }
+        result.Outstanding = transaction.Amount.Abs() - result.Allocated
+        return result, nil
+}
+
+// AllocatePayment allocates a payment to the given bills of the same party
+func (s *AllocationService) AllocatePayment(userID, paymentID string, req *models.AllocatePaymentRequest) (*TransactionAllocations, *apperrors.AppError) {
+        var payment models.Transaction
+        if err := s.db.Where("id = ? AND user_id = ?", paymentID, userID).First(&payment).Error; err != nil {
+                if errors.Is(err, gorm.ErrRecordNotFound) {
+                        return nil, apperrors.NotFound("Transaction not found")
+                }
+                return nil, apperrors.Internal("Database error", err)
+        }
+
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                party, err := lockParty(tx, userID, payment.PartyID)
+                if err != nil {
+                        return err
+                }
+                if isBill(party, &payment) {
+                        return apperrors.BadRequest("Transaction is a bill, not a payment")
+                }
+
+                bills, payments, err := openItems(tx, party)
+                if err != nil {
+                        return err
+                }
+                outstanding := map[string]money.Amount{}
+                for _, bill := range bills {
+                        outstanding[bill.ID] = bill.Outstanding
+                }
+                var unallocated money.Amount
+                for _, p := range payments {
+                        if p.ID == payment.ID {
+                                unallocated = p.Outstanding
+                        }
+                }
+
+                for _, allocation := range req.Allocations {
+                        open, ok := outstanding[allocation.BillID]
+                        if !ok {
+                                return apperrors.BadRequest("Bill " + allocation.BillID + " is not a bill of this party")
+                        }
+                        if allocation.Amount > open {
+                                return apperrors.BadRequest("Allocation exceeds the outstanding amount " + open.String() + " of bill " + allocation.BillID)
+                        }
+                        if allocation.Amount > unallocated {
+                                return apperrors.BadRequest("Allocations exceed the unallocated payment amount")
+                        }
+                        outstanding[allocation.BillID] -= allocation.Amount
+                        unallocated -= allocation.Amount
+
+                        if err := tx.Create(&models.Allocation{
+                                UserID:    userID,
+                                PartyID:   party.ID,
+                                PaymentID: payment.ID,
+                                BillID:    allocation.BillID,
+                                Amount:    allocation.Amount,
+                        }).Error; err != nil {
+                                return err
+                        }
+                }
+                return nil
+        })
+        if err != nil {
+                return nil, apperrors.FromError(err, "Failed to allocate payment")
+        }
+
+        return s.GetAllocations(userID, paymentID)
+}
+
+// AutoAllocate allocates all unallocated payments of a party to its open
+// bills, oldest first
+func (s *AllocationService) AutoAllocate(userID, partyID string) ([]models.Allocation, *apperrors.AppError) {
+        var allocations []models.Allocation
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                party, err := lockParty(tx, userID, partyID)
+                if err != nil {
+                        return err
+                }
+                allocations, err = autoAllocate(tx, party)
+                return err
+        })
+        if err != nil {
+                return nil, apperrors.FromError(err, "Failed to allocate payments")
+        }
+        return allocations, nil
+}
+
+// DeleteAllocation removes an allocation, reopening the bill by its amount
+func (s *AllocationService) DeleteAllocation(userID, allocationID string) *apperrors.AppError {
+        var allocation models.Allocation
+        if err := s.db.Where("id = ? AND user_id = ?", allocationID, userID).First(&allocation).Error; err != nil {
+                if errors.Is(err, gorm.ErrRecordNotFound) {
+                        return apperrors.NotFound("Allocation not found")
+                }
+                return apperrors.Internal("Database error", err)
+        }
+
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                if _, err := lockParty(tx, userID, allocation.PartyID); err != nil {
+                        return err
+                }
+                return tx.Delete(&allocation).Error
+        })
+        if err != nil {
+                return apperrors.FromError(err, "Failed to delete allocation")
+        }
+        return nil
+}
+
+// isBill reports whether a transaction raises what is owed on the party's
+// account in the usual direction for the party: a credit to a customer or a
+// debit from a supplier. Opening balances count by their sign.
+func isBill(party *models.Party, transaction *models.Transaction) bool {
+        signed := transaction.SignedAmount()
+        if party.PartyType == models.PartyTypeSupplier {
+                return signed < 0
+        }
+        return signed > 0
+}
+
+// openItems splits a party's transactions into bills and payments in ledger
+// order, each with its allocated and outstanding amounts
+func openItems(tx *gorm.DB, party *models.Party) ([]OpenItem, []OpenItem, error) {
+        var transactions []models.Transaction
+        if err := tx.Where("party_id = ?", party.ID).Order(ledgerOrder).Find(&transactions).Error; err != nil {
+                return nil, nil, err
+        }
+
+        var allocations []models.Allocation
+        if err := tx.Where("party_id = ?", party.ID).Find(&allocations).Error; err != nil {
+                return nil, nil, err
+        }
+
+        bills, payments := splitOpenItems(party, transactions, allocations)
+        return bills, payments, nil
+}
+
+// splitOpenItems does the work of openItems on transactions already loaded
+// in ledger order
+func splitOpenItems(party *models.Party, transactions []models.Transaction, allocations []models.Allocation) ([]OpenItem, []OpenItem) {
+        allocated := map[string]money.Amount{}
+        for _, allocation := range allocations {
+                allocated[allocation.BillID] += allocation.Amount
+                allocated[allocation.PaymentID] += allocation.Amount
+        }
+
+        var bills, payments []OpenItem
+        for _, transaction := range transactions {
+                if transaction.Amount == 0 {
+                        continue
+                }
+                item := OpenItem{
+                        Transaction: transaction,
+                        Allocated:   allocated[transaction.ID],
+                        Outstanding: transaction.Amount.Abs() - allocated[transaction.ID],
+                }
+                if isBill(party, &transaction) {
+                        bills = append(bills, item)
+                } else {
+                        payments = append(payments, item)
+                }
+        }
+        return bills, payments
+}
+
+// autoAllocate matches unallocated payments to open bills in FIFO order.
+// The caller must hold the party lock.
+func autoAllocate(tx *gorm.DB, party *models.Party) ([]models.Allocation, error) {
+        bills, payments, err := openItems(tx, party)
+        if err != nil {
+                return nil, err
+        }
+
+        allocations := []models.Allocation{}
+        b, p := 0, 0
+        for b < len(bills) && p < len(payments) {
+                if bills[b].Outstanding <= 0 {
+                        b++
+                        continue
+                }
+                if payments[p].Outstanding <= 0 {
+                        p++
+                        continue
+                }
+
+                amount := bills[b].Outstanding
+                if payments[p].Outstanding < amount {
+                        amount = payments[p].Outstanding
+                }
+                allocation := models.Allocation{
+                        UserID:    party.UserID,
+                        PartyID:   party.ID,
+                        PaymentID: payments[p].ID,
+                        BillID:    bills[b].ID,
+                        Amount:    amount,
+                }
+                if err := tx.Create(&allocation).Error; err != nil {
+                        return nil, err
+                }
+                allocations = append(allocations, allocation)
+
+                bills[b].Outstanding -= amount
+                payments[p].Outstanding -= amount
+        }
+        return allocations, nil
+}
+
+// trimAllocations keeps a changed transaction's allocations consistent. If
+// it switched between bill and payment they are removed; if its amount fell
+// below what is allocated, the newest allocations are reduced first. The
+// caller must hold the party lock.
+func trimAllocations(tx *gorm.DB, party *models.Party, transaction *models.Transaction) error {
+        var allocations []models.Allocation
+        if err := tx.Where("payment_id = ? OR bill_id = ?", transaction.ID, transaction.ID).
+                Order("created_at DESC, id DESC").
+                Find(&allocations).Error; err != nil {
+                return err
+        }
+
+        bill := isBill(party, transaction)
+        var total money.Amount
+        for _, allocation := range allocations {
+                total += allocation.Amount
+        }
+
+        excess := total - transaction.Amount.Abs()
+        for i := range allocations {
+                allocation := &allocations[i]
+                wrongSide := (allocation.BillID == transaction.ID) != bill
+                if !wrongSide && excess <= 0 {
+                        break
+                }
+
+                if wrongSide || allocation.Amount <= excess {
+                        if err := tx.Delete(allocation).Error; err != nil {
+                                return err
+                        }
+                        if !wrongSide {
+                                excess -= allocation.Amount
+                        }
+                        continue
+                }
+                if err := tx.Model(allocation).Update("amount", allocation.Amount-excess).Error; err != nil {
+                        return err
+                }
+                excess = 0
+        }
+        return nil
+}
+
+// deleteAllocations removes every allocation of a transaction
+func deleteAllocations(tx *gorm.DB, transactionID string) error {
+        return tx.Where("payment_id = ? OR bill_id = ?", transactionID, transactionID).Delete(&models.Allocation{}).Error
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+        "encoding/json"
+
+        "khatabook-go-backend/internal/models"
+        "khatabook-go-backend/pkg/audit"
+        apperrors "khatabook-go-backend/pkg/errors"
+
+        "gorm.io/gorm"
+)
+
+// AuditService reads the audit log
+type AuditService struct {
+        db *gorm.DB
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(db *gorm.DB) *AuditService {
+        return &AuditService{db: db}
+}
+
+// maxAuditEntries caps how many entries one query returns
+const maxAuditEntries = 500
+
+// GetEntries retrieves the audit log of a user, newest first, optionally
+// limited to one entity type and one entity
+func (s *AuditService) GetEntries(userID, entity, entityID string) ([]models.AuditLog, *apperrors.AppError) {
+        if entity != "" && !isAuditEntity(entity) {
+                return nil, apperrors.BadRequest("Unknown audit entity " + entity)
+        }
+
+        query := s.db.Where("user_id = ?", userID)
+        if entity != "" {
+                query = query.Where("entity = ?", entity)
+        }
+        if entityID != "" {
+                query = query.Where("entity_id = ?", entityID)
+        }
+
+        entries := []models.AuditLog{}
+        if err := query.Order("created_at DESC, id").Limit(maxAuditEntries).Find(&entries).Error; err != nil {
+                return nil, apperrors.Internal("Failed to fetch audit log", err)
+        }
+        return entries, nil
+}
+
+// isAuditEntity reports whether the audit log covers an entity type
+func isAuditEntity(entity string) bool {
+        for _, e := range models.AuditEntities {
+                if e == entity {
+                        return true
+                }
+        }
+        return false
+}
+
+// recordAudit appends an entry for a change to an entity of the user inside
+// the DB transaction making the change. before and after are the entity as
+// it was and as it is now, nil for a create or delete. The actor comes from
+// the context of tx; changes without one are put down to the user.
+func recordAudit(tx *gorm.DB, userID, entity, entityID, action string, before, after interface{}) error {
+        entry := &models.AuditLog{
+                UserID:   userID,
+                Actor:    models.AuditActorUser,
+                ActorID:  &userID,
+                Entity:   entity,
+                EntityID: entityID,
+                Action:   action,
+        }
+
+        if actor, ok := audit.FromContext(tx.Statement.Context); ok {
+                if actor.System {
+                        entry.Actor = models.AuditActorSystem
+                        entry.ActorID = nil
+                } else {
+                        entry.ActorID = nullableString(actor.UserID)
+                        entry.DeviceID = nullableString(actor.Device)
+                        entry.IP = nullableString(actor.IP)
+                }
+        }
+
+        var err error
+        if entry.Before, err = auditSnapshot(before); err != nil {
+                return err
+        }
+        if entry.After, err = auditSnapshot(after); err != nil {
+                return err
+        }
+        return tx.Create(entry).Error
+}
+
+// auditSnapshot encodes an entity for the audit log
+func auditSnapshot(entity interface{}) (models.JSON, error) {
+        if entity == nil {
+                return nil, nil
+        }
+        return json.Marshal(entity)
+}
+
+// nullableString returns nil for an empty string
+func nullableString(s string) *string {
+        if s == "" {
+                return nil
+        }
+        return &s
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+        "crypto/rand"
+        "crypto/sha256"
+        "encoding/base64"
+        "encoding/hex"
+        "errors"
+        "fmt"
+        "strings"
+        "time"
+
+        "khatabook-go-backend/internal/models"
+        apperrors "khatabook-go-backend/pkg/errors"
+        "khatabook-go-backend/pkg/i18n"
+        "khatabook-go-backend/pkg/ical"
+
+        "gorm.io/gorm"
+)
+
+// CalendarService publishes open reminders as an iCalendar feed
+type CalendarService struct {
+        db *gorm.DB
+}
+
+// NewCalendarService creates a new calendar service
+func NewCalendarService(db *gorm.DB) *CalendarService {
+        return &CalendarService{db: db}
+}
+
+// GetToken retrieves the user's feed token record
+func (s *CalendarService) GetToken(userID string) (*models.CalendarToken, *apperrors.AppError) {
+        var token models.CalendarToken
+        if err := s.db.Where("user_id = ?", userID).First(&token).Error; err != nil {
+                if errors.Is(err, gorm.ErrRecordNotFound) {
+                        return nil, apperrors.NotFound("No calendar feed token")
+                }
+                return nil, apperrors.Internal("Database error", err)
+        }
+        return &token, nil
+}
+
+// CreateToken issues a new feed token for the user, revoking the previous
+// one. The token is only returned here; it cannot be read back later.
+func (s *CalendarService) CreateToken(userID string) (string, *models.CalendarToken, *apperrors.AppError) {
+        secret := make([]byte, 32)
+        if _, err := rand.Read(secret); err != nil {
+                return "", nil, apperrors.Internal("Failed to generate token", err)
+        }
+        token := base64.RawURLEncoding.EncodeToString(secret)
+
+        record := &models.CalendarToken{UserID: userID, TokenHash: hashToken(token)}
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                if err := tx.Where("user_id = ?", userID).Delete(&models.CalendarToken{}).Error; err != nil {
+                        return err
+                }
+                return tx.Create(record).Error
+        })
+        if err != nil {
+                return "", nil, apperrors.Internal("Failed to create calendar feed token", err)
+        }
+        return token, record, nil
+}
+
+// RevokeToken revokes the user's feed token
+func (s *CalendarService) RevokeToken(userID string) *apperrors.AppError {
+        result := s.db.Where("user_id = ?", userID).Delete(&models.CalendarToken{})
+        if result.Error != nil {
+                return apperrors.Internal("Failed to revoke calendar feed token", result.Error)
+        }
+        if result.RowsAffected == 0 {
+                return apperrors.NotFound("No calendar feed token")
+        }
+        return nil
+}
+
+// UserForToken returns the user a feed token belongs to
+func (s *CalendarService) UserForToken(token string) (string, *apperrors.AppError) {
+        if token == "" {
+                return "", apperrors.Unauthorized("Calendar feed token missing")
+        }
+
+        var record models.CalendarToken
+        if err := s.db.Where("token_hash = ?", hashToken(token)).First(&record).Error; err != nil {
+                if errors.Is(err, gorm.ErrRecordNotFound) {
+                        return "", apperrors.Unauthorized("Invalid calendar feed token")
+                }
+                return "", apperrors.Internal("Database error", err)
+        }
+
+        // Only used to show when the feed was last fetched
+        _ = s.db.Model(&record).Update("last_used_at", time.Now()).Error
+        return record.UserID, nil
+}
+
+// Calendar renders the user's open reminders as an iCalendar feed. Event
+// UIDs are derived from the reminder IDs so that calendar apps update
+// events in place when a reminder is snoozed or part-paid.
+func (s *CalendarService) Calendar(userID string) ([]byte, *apperrors.AppError) {
+        var reminders []models.Reminder
+        err := s.db.Where("user_id = ? AND status IN ?", userID, openReminderStatuses).
+                Order("due_date, created_at").
+                Find(&reminders).Error
+        if err != nil {
+                return nil, apperrors.Internal("Failed to fetch reminders", err)
+        }
+
+        partyIDs := make([]string, len(reminders))
+        for i, reminder := range reminders {
+                partyIDs[i] = reminder.PartyID
+        }
+        var parties []models.Party
+        if len(partyIDs) > 0 {
+                if err := s.db.Select("id", "name", "currency").Where("id IN ?", partyIDs).Find(&parties).Error; err != nil {
+                        return nil, apperrors.Internal("Failed to fetch parties", err)
+                }
+        }
+        partyByID := map[string]*models.Party{}
+        for i := range parties {
+                partyByID[parties[i].ID] = &parties[i]
+        }
+
+        calendar := &ical.Calendar{
+                ProductID: "-//Khatabook Pro//Reminders//EN",
+                Name:      "Khatabook reminders",
+        }
+        for _, reminder := range reminders {
+                date, err := time.Parse("2006-01-02", reminder.DueDate)
+                if err != nil {
+                        continue
+                }
+                party := partyByID[reminder.PartyID]
+                if party == nil {
+                        continue
+                }
+
+                outstanding := i18n.FormatAmount(reminder.Outstanding(), party.Currency)
+                description := []string{
+                        "Party: " + party.Name,
+                        "Amount due: " + outstanding,
+                }
+                if reminder.Settled > 0 {
+                        description = append(description, fmt.Sprintf("Paid so far: %s of %s",
+                                i18n.FormatAmount(reminder.Settled, party.Currency), i18n.FormatAmount(reminder.Amount, party.Currency)))
+                }
+                description = append(description, "Status: "+reminder.Status)
+                if reminder.Message != nil && *reminder.Message != "" {
+                        description = append(description, "", *reminder.Message)
+                }
+
+                calendar.Events = append(calendar.Events, ical.Event{
+                        UID:          "reminder-" + reminder.ID + "@khatabook-pro",
+                        Date:         date,
+                        Summary:      fmt.Sprintf("%s: %s due", party.Name, outstanding),
+                        Description:  strings.Join(description, "\n"),
+                        LastModified: reminder.UpdatedAt,
+                })
+        }
+        return calendar.Bytes(), nil
+}
+
+// hashToken returns the hex SHA-256 of a feed token
+func hashToken(token string) string {
+        sum := sha256.Sum256([]byte(token))
+        return hex.EncodeToString(sum[:])
+}
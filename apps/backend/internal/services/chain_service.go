@@ -0,0 +1,318 @@
+package services
+
+import (
+        "crypto/sha256"
+        "encoding/hex"
+        "encoding/json"
+        "errors"
+        "fmt"
+        "sort"
+        "strings"
+
+        "khatabook-go-backend/internal/models"
+        apperrors "khatabook-go-backend/pkg/errors"
+
+        "gorm.io/gorm"
+        "gorm.io/gorm/clause"
+)
+
+// ChainService verifies the per-user hash chain over ledger transactions
+type ChainService struct {
+        db *gorm.DB
+}
+
+// NewChainService creates a new chain service
+func NewChainService(db *gorm.DB) *ChainService {
+        return &ChainService{db: db}
+}
+
+// genesisHash is the previous hash of the first link of every chain
+var genesisHash = strings.Repeat("0", 64)
+
+// chainBatch is how many links or transactions are read at a time
+const chainBatch = 1000
+
+// ChainBreak is a place where the chain stops verifying. Seq is the link at
+// fault, or 0 for a transaction that no link covers.
+type ChainBreak struct {
+        Seq           int64  `json:"seq"`
+        TransactionID string `json:"transaction_id,omitempty"`
+        Reason        string `json:"reason"`
+}
+
+// ChainReport is the result of verifying a user's chain
+type ChainReport struct {
+        UserID       string      `json:"user_id"`
+        Links        int64       `json:"links"`
+        Transactions int64       `json:"transactions"`
+        HeadHash     string      `json:"head_hash"`
+        Valid        bool        `json:"valid"`
+        Breaks       int         `json:"breaks"`
+        FirstBreak   *ChainBreak `json:"first_break,omitempty"`
+}
+
+// Verify walks a user's chain from the first link, checking that every link
+// follows the one before it, and then checks that every transaction still
+// has the content of the latest link covering it
+func (s *ChainService) Verify(userID string) (*ChainReport, *apperrors.AppError) {
+        report, err := s.verify(userID)
+        if err != nil {
+                return nil, apperrors.Internal("Failed to verify ledger chain", err)
+        }
+        return report, nil
+}
+
+// ChainUsers returns the users that have a chain or transactions
+func (s *ChainService) ChainUsers() ([]string, error) {
+        var linked, owners []string
+        if err := s.db.Model(&models.LedgerLink{}).Distinct("user_id").Pluck("user_id", &linked).Error; err != nil {
+                return nil, err
+        }
+        if err := s.db.Unscoped().Model(&models.Transaction{}).Distinct("user_id").Pluck("user_id", &owners).Error; err != nil {
+                return nil, err
+        }
+
+        seen := map[string]bool{}
+        users := []string{}
+        for _, id := range append(linked, owners...) {
+                if !seen[id] {
+                        seen[id] = true
+                        users = append(users, id)
+                }
+        }
+        sort.Strings(users)
+        return users, nil
+}
+
+// verify builds the chain report of a user
+func (s *ChainService) verify(userID string) (*ChainReport, error) {
+        report := &ChainReport{UserID: userID, HeadHash: genesisHash}
+        var breaks []ChainBreak
+
+        // Walk the links in order; nothing after a broken link can be trusted
+        latest := map[string]models.LedgerLink{}
+        var lastSeq int64
+walk:
+        for {
+                var links []models.LedgerLink
+                if err := s.db.Where("user_id = ? AND seq > ?", userID, lastSeq).Order("seq").Limit(chainBatch).Find(&links).Error; err != nil {
+                        return nil, err
+                }
+                if len(links) == 0 {
+                        break
+                }
+
+                for _, link := range links {
+                        report.Links++
+                        if brk := checkLink(link, lastSeq, report.HeadHash); brk != nil {
+                                breaks = append(breaks, *brk)
+                                break walk
+                        }
+
+                        lastSeq = link.Seq
+                        report.HeadHash = link.Hash
+                        latest[link.TransactionID] = link
+                }
+        }
+
+        // Compare every transaction, trashed ones included, with its latest link
+        seen := map[string]bool{}
+        lastID := ""
+        for {
+                var transactions []models.Transaction
+                if err := s.db.Unscoped().Where("user_id = ? AND id > ?", userID, lastID).Order("id").Limit(chainBatch).Find(&transactions).Error; err != nil {
+                        return nil, err
+                }
+                if len(transactions) == 0 {
+                        break
+                }
+                lastID = transactions[len(transactions)-1].ID
+
+                for i := range transactions {
+                        transaction := &transactions[i]
+                        report.Transactions++
+                        seen[transaction.ID] = true
+
+                        link, ok := latest[transaction.ID]
+                        switch {
+                        case !ok && len(breaks) > 0:
+                                // Links after the break were not read
+                        case !ok:
+                                breaks = append(breaks, ChainBreak{TransactionID: transaction.ID, Reason: "transaction is not in the chain"})
+                        default:
+                                if reason := checkChained(transaction, link); reason != "" {
+                                        breaks = append(breaks, ChainBreak{Seq: link.Seq, TransactionID: transaction.ID, Reason: reason})
+                                }
+                        }
+                }
+        }
+
+        // Only purged transactions may be gone
+        for id, link := range latest {
+                if !seen[id] && link.Action != models.AuditActionPurge {
+                        breaks = append(breaks, ChainBreak{Seq: link.Seq, TransactionID: id, Reason: "transaction is missing"})
+                }
+        }
+
+        sort.Slice(breaks, func(i, j int) bool {
+                if breaks[i].Seq != breaks[j].Seq {
+                        return breaks[i].Seq < breaks[j].Seq
+                }
+                return breaks[i].TransactionID < breaks[j].TransactionID
+        })
+        report.Breaks = len(breaks)
+        report.Valid = len(breaks) == 0
+        if len(breaks) > 0 {
+                report.FirstBreak = &breaks[0]
+        }
+        return report, nil
+}
+
+// checkLink checks that a link follows the link with seq lastSeq and hash
+// headHash, and that its own hash is right
+func checkLink(link models.LedgerLink, lastSeq int64, headHash string) *ChainBreak {
+        switch {
+        case link.Seq != lastSeq+1:
+                return &ChainBreak{Seq: lastSeq + 1, Reason: fmt.Sprintf("link %d is missing", lastSeq+1)}
+        case link.PrevHash != headHash:
+                return &ChainBreak{Seq: link.Seq, TransactionID: link.TransactionID, Reason: "link does not follow the previous link"}
+        case link.Hash != linkHash(link.PrevHash, link.Seq, link.TransactionID, link.Action, link.ContentHash):
+                return &ChainBreak{Seq: link.Seq, TransactionID: link.TransactionID, Reason: "link hash does not match its content"}
+        }
+        return nil
+}
+
+// checkChained compares a stored transaction with the latest link covering
+// it and returns why they disagree, or "" when they match. The trash state
+// is part of the check: only a delete link leaves a transaction trashed.
+func checkChained(transaction *models.Transaction, link models.LedgerLink) string {
+        switch {
+        case link.Action == models.AuditActionPurge:
+                return "purged transaction still exists"
+        case transaction.DeletedAt.Valid && link.Action != models.AuditActionDelete:
+                return "transaction is in the trash but its latest link is " + link.Action
+        case !transaction.DeletedAt.Valid && link.Action == models.AuditActionDelete:
+                return "transaction is not in the trash but its latest link is delete"
+        case transactionDigest(transaction) != link.ContentHash:
+                return "transaction differs from its chained content"
+        case transaction.ChainSeq == nil || *transaction.ChainSeq != link.Seq ||
+                transaction.ChainHash == nil || *transaction.ChainHash != link.Hash:
+                return "transaction does not point at its latest link"
+        }
+        return ""
+}
+
+// Backfill chains the transactions recorded before the chain existed, in the
+// order they were created. Trashed ones get a delete link after their create
+// link.
+func (s *ChainService) Backfill() error {
+        var users []string
+        if err := s.db.Unscoped().Model(&models.Transaction{}).Where("chain_seq IS NULL").
+                Distinct("user_id").Pluck("user_id", &users).Error; err != nil {
+                return fmt.Errorf("failed to load unchained transactions: %w", err)
+        }
+
+        for _, userID := range users {
+                err := s.db.Transaction(func(tx *gorm.DB) error {
+                        var transactions []models.Transaction
+                        if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+                                Where("user_id = ? AND chain_seq IS NULL", userID).
+                                Order("created_at, id").Find(&transactions).Error; err != nil {
+                                return err
+                        }
+                        for i := range transactions {
+                                if err := chainTransaction(tx, &transactions[i], models.AuditActionCreate); err != nil {
+                                        return err
+                                }
+                                if transactions[i].DeletedAt.Valid {
+                                        if err := chainTransaction(tx, &transactions[i], models.AuditActionDelete); err != nil {
+                                                return err
+                                        }
+                                }
+                        }
+                        return nil
+                })
+                if err != nil {
+                        return fmt.Errorf("failed to chain transactions of user %s: %w", userID, err)
+                }
+        }
+        return nil
+}
+
+// chainTransaction appends a link for a change to a transaction to its
+// user's chain and points the transaction at it. The transaction must be as
+// stored. Appends are serialised on the user row.
+func chainTransaction(tx *gorm.DB, transaction *models.Transaction, action string) error {
+        var user models.User
+        err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+                Where("id = ?", transaction.UserID).First(&user).Error
+        if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+                return err
+        }
+
+        link := &models.LedgerLink{
+                UserID:        transaction.UserID,
+                Seq:           1,
+                TransactionID: transaction.ID,
+                Action:        action,
+                ContentHash:   transactionDigest(transaction),
+                PrevHash:      genesisHash,
+        }
+        var last models.LedgerLink
+        err = tx.Where("user_id = ?", transaction.UserID).Order("seq DESC").First(&last).Error
+        if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+                return err
+        }
+        if err == nil {
+                link.Seq = last.Seq + 1
+                link.PrevHash = last.Hash
+        }
+        link.Hash = linkHash(link.PrevHash, link.Seq, link.TransactionID, link.Action, link.ContentHash)
+        if err := tx.Create(link).Error; err != nil {
+                return err
+        }
+
+        transaction.ChainSeq = &link.Seq
+        transaction.ChainHash = &link.Hash
+        return tx.Unscoped().Model(&models.Transaction{}).Where("id = ?", transaction.ID).
+                UpdateColumns(map[string]interface{}{"chain_seq": link.Seq, "chain_hash": link.Hash}).Error
+}
+
+// transactionDigest hashes the fields of a transaction that make up the
+// ledger. Derived fields such as the running balance are left out, and the
+// reversal links only count when set so older links still verify.
+func transactionDigest(transaction *models.Transaction) string {
+        content, _ := json.Marshal(struct {
+                ID            string `json:"id"`
+                UserID        string `json:"user_id"`
+                PartyID       string `json:"party_id"`
+                Amount        int64  `json:"amount"`
+                Currency      string `json:"currency"`
+                Type          string `json:"transaction_type"`
+                Date          string `json:"date"`
+                Description   string `json:"description"`
+                Category      string `json:"category"`
+                ReversalOf    string `json:"reversal_of,omitempty"`
+                ReplacementOf string `json:"replacement_of,omitempty"`
+        }{
+                ID:            transaction.ID,
+                UserID:        transaction.UserID,
+                PartyID:       transaction.PartyID,
+                Amount:        int64(transaction.Amount),
+                Currency:      transaction.Currency,
+                Type:          transaction.TransactionType,
+                Date:          transaction.Date,
+                Description:   stringValue(transaction.Description),
+                Category:      stringValue(transaction.Category),
+                ReversalOf:    stringValue(transaction.ReversalOf),
+                ReplacementOf: stringValue(transaction.ReplacementOf),
+        })
+        sum := sha256.Sum256(content)
+        return hex.EncodeToString(sum[:])
+}
+
+// linkHash chains a link to the one before it
+func linkHash(prevHash string, seq int64, transactionID, action, contentHash string) string {
+        sum := sha256.Sum256([]byte(fmt.Sprintf("%s\n%d\n%s\n%s\n%s", prevHash, seq, transactionID, action, contentHash)))
+        return hex.EncodeToString(sum[:])
+}
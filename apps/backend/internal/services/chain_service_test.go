@@ -0,0 +1,154 @@
+package services
+
+import (
+        "strings"
+        "testing"
+        "time"
+
+        "khatabook-go-backend/internal/models"
+
+        "gorm.io/gorm"
+)
+
+// testTransaction returns a chained-looking transaction for the chain tests
+func testTransaction() *models.Transaction {
+        description, category := "Rice", "stock"
+        return &models.Transaction{
+                ID:              "t-1",
+                UserID:          "u-1",
+                PartyID:         "p-1",
+                Amount:          125050,
+                Currency:        "INR",
+                TransactionType: models.TransactionTypeCredit,
+                Date:            "2024-03-01",
+                Description:     &description,
+                Category:        &category,
+        }
+}
+
+// testChain links transactions one after another as chainTransaction does
+func testChain(actions []string, transaction *models.Transaction) []models.LedgerLink {
+        links := make([]models.LedgerLink, len(actions))
+        prev := genesisHash
+        for i, action := range actions {
+                link := models.LedgerLink{
+                        Seq:           int64(i + 1),
+                        TransactionID: transaction.ID,
+                        Action:        action,
+                        ContentHash:   transactionDigest(transaction),
+                        PrevHash:      prev,
+                }
+                link.Hash = linkHash(link.PrevHash, link.Seq, link.TransactionID, link.Action, link.ContentHash)
+                links[i] = link
+                prev = link.Hash
+        }
+        return links
+}
+
+func TestTransactionDigest(t *testing.T) {
+        base := transactionDigest(testTransaction())
+        if len(base) != 64 {
+                t.Fatalf("digest %q is not a hex SHA-256", base)
+        }
+        if again := transactionDigest(testTransaction()); again != base {
+                t.Errorf("digest is not stable: %s then %s", base, again)
+        }
+
+        reversed := "t-0"
+        tests := []struct {
+                name    string
+                change  func(*models.Transaction)
+                changes bool
+        }{
+                {"amount", func(tr *models.Transaction) { tr.Amount++ }, true},
+                {"type", func(tr *models.Transaction) { tr.TransactionType = models.TransactionTypeDebit }, true},
+                {"date", func(tr *models.Transaction) { tr.Date = "2024-03-02" }, true},
+                {"party", func(tr *models.Transaction) { tr.PartyID = "p-2" }, true},
+                {"description", func(tr *models.Transaction) { tr.Description = nil }, true},
+                {"reversal link", func(tr *models.Transaction) { tr.ReversalOf = &reversed }, true},
+                {"running balance", func(tr *models.Transaction) { tr.RunningBalance = 999 }, false},
+                {"chain pointer", func(tr *models.Transaction) { seq := int64(7); tr.ChainSeq = &seq }, false},
+                {"timestamps", func(tr *models.Transaction) { tr.UpdatedAt = time.Now() }, false},
+        }
+        for _, tt := range tests {
+                transaction := testTransaction()
+                tt.change(transaction)
+                if got := transactionDigest(transaction) != base; got != tt.changes {
+                        t.Errorf("changing the %s changes the digest = %v, want %v", tt.name, got, tt.changes)
+                }
+        }
+}
+
+func TestCheckLink(t *testing.T) {
+        links := testChain([]string{models.AuditActionCreate, models.AuditActionUpdate, models.AuditActionDelete}, testTransaction())
+
+        head, last := genesisHash, int64(0)
+        for _, link := range links {
+                if brk := checkLink(link, last, head); brk != nil {
+                        t.Fatalf("intact link %d: %s", link.Seq, brk.Reason)
+                }
+                head, last = link.Hash, link.Seq
+        }
+
+        tampered := links[1]
+        tampered.Action = models.AuditActionRestore
+        forged := links[1]
+        forged.PrevHash = strings.Repeat("f", 64)
+        forged.Hash = linkHash(forged.PrevHash, forged.Seq, forged.TransactionID, forged.Action, forged.ContentHash)
+
+        tests := []struct {
+                name     string
+                link     models.LedgerLink
+                wantSeq  int64
+                wantText string
+        }{
+                {"gap", links[2], 2, "link 2 is missing"},
+                {"changed content", tampered, 2, "hash does not match"},
+                {"rehashed on another chain", forged, 2, "does not follow"},
+        }
+        for _, tt := range tests {
+                brk := checkLink(tt.link, links[0].Seq, links[0].Hash)
+                if brk == nil {
+                        t.Errorf("%s: link verified", tt.name)
+                        continue
+                }
+                if brk.Seq != tt.wantSeq || !strings.Contains(brk.Reason, tt.wantText) {
+                        t.Errorf("%s: break at %d %q, want %d %q", tt.name, brk.Seq, brk.Reason, tt.wantSeq, tt.wantText)
+                }
+        }
+}
+
+func TestCheckChained(t *testing.T) {
+        trashed := gorm.DeletedAt{Time: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), Valid: true}
+        tests := []struct {
+                name     string
+                action   string
+                change   func(*models.Transaction)
+                wantText string
+        }{
+                {"created", models.AuditActionCreate, nil, ""},
+                {"restored", models.AuditActionRestore, nil, ""},
+                {"deleted", models.AuditActionDelete, func(tr *models.Transaction) { tr.DeletedAt = trashed }, ""},
+                {"trashed without a delete link", models.AuditActionUpdate, func(tr *models.Transaction) { tr.DeletedAt = trashed }, "is in the trash"},
+                {"untrashed without a restore link", models.AuditActionDelete, nil, "is not in the trash"},
+                {"purged", models.AuditActionPurge, nil, "purged transaction still exists"},
+                {"edited", models.AuditActionCreate, func(tr *models.Transaction) { tr.Amount = 1 }, "differs from its chained content"},
+                {"stale pointer", models.AuditActionCreate, func(tr *models.Transaction) { tr.ChainHash = &genesisHash }, "does not point at its latest link"},
+        }
+        for _, tt := range tests {
+                transaction := testTransaction()
+                link := testChain([]string{tt.action}, transaction)[0]
+                transaction.ChainSeq, transaction.ChainHash = &link.Seq, &link.Hash
+                if tt.change != nil {
+                        tt.change(transaction)
+                }
+
+                got := checkChained(transaction, link)
+                if tt.wantText == "" && got != "" {
+                        t.Errorf("%s: %q, want no break", tt.name, got)
+                }
+                if tt.wantText != "" && !strings.Contains(got, tt.wantText) {
+                        t.Errorf("%s: %q, want %q", tt.name, got, tt.wantText)
+                }
+        }
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+        "errors"
+        "fmt"
+        "sort"
+
+        "khatabook-go-backend/internal/models"
+        apperrors "khatabook-go-backend/pkg/errors"
+        "khatabook-go-backend/pkg/money"
+
+        "gorm.io/gorm"
+        "gorm.io/gorm/clause"
+)
+
+// FXService manages the user's exchange-rate table
+type FXService struct {
+        db *gorm.DB
+}
+
+// NewFXService creates a new FX service
+func NewFXService(db *gorm.DB) *FXService {
+        return &FXService{db: db}
+}
+
+// RateTable holds a user's dated exchange rates for converting amounts into
+// the base currency
+type RateTable struct {
+        Base  string
+        rates map[string][]models.ExchangeRate
+}
+
+// RateOn returns the latest rate for a currency dated on or before date
+func (t *RateTable) RateOn(currency, date string) (money.Rate, error) {
+        if currency == t.Base {
+                return money.One, nil
+        }
+
+        rates := t.rates[currency]
+        i := sort.Search(len(rates), func(i int) bool { return rates[i].Date > date })
+        if i == 0 {
+                return 0, fmt.Errorf("no %s exchange rate on or before %s", currency, date)
+        }
+        return rates[i-1].Rate, nil
+}
+
+// ToBase converts an amount in currency into the base currency at the rate
+// in force on date
+func (t *RateTable) ToBase(amount money.Amount, currency, date string) (money.Amount, error) {
+        rate, err := t.RateOn(currency, date)
+        if err != nil {
+                return 0, err
+        }
+        return rate.Convert(amount), nil
+}
+
+// GetRates retrieves the user's exchange rates, optionally for one currency
+func (s *FXService) GetRates(userID, currency string) ([]models.ExchangeRate, *apperrors.AppError) {
+        var rates []models.ExchangeRate
+        query := s.db.Where("user_id = ?", userID)
+        if currency != "" {
+                query = query.Where("currency = ?", currency)
+        }
+        if err := query.Order("currency, date DESC").Find(&rates).Error; err != nil {
+                return nil, apperrors.Internal("Failed to fetch exchange rates", err)
+        }
+        return rates, nil
+}
+
+// SetRate records the rate of a currency for a date, replacing any rate
+// already recorded for that date
+func (s *FXService) SetRate(userID string, req *models.SetExchangeRateRequest) (*models.ExchangeRate, *apperrors.AppError) {
+        if !money.IsSupported(req.Currency) {
+                return nil, apperrors.BadRequest("Unsupported currency " + req.Currency)
+        }
+
+        rate := &models.ExchangeRate{
+                UserID:   userID,
+                Currency: req.Currency,
+                Date:     req.Date,
+                Rate:     req.Rate,
+        }
+
+        err := s.db.Clauses(clause.OnConflict{
+                Columns:   []clause.Column{{Name: "user_id"}, {Name: "currency"}, {Name: "date"}},
+                DoUpdates: clause.AssignmentColumns([]string{"rate", "updated_at"}),
+        }).Create(rate).Error
+        if err != nil {
+                return nil, apperrors.Internal("Failed to save exchange rate", err)
+        }
+
+        var saved models.ExchangeRate
+        if err := s.db.Where("user_id = ? AND currency = ? AND date = ?", userID, req.Currency, req.Date).First(&saved).Error; err != nil {
+                return nil, apperrors.Internal("Failed to save exchange rate", err)
+        }
+        return &saved, nil
+}
+
+// DeleteRate deletes an exchange rate
+func (s *FXService) DeleteRate(userID, rateID string) *apperrors.AppError {
+        result := s.db.Where("id = ? AND user_id = ?", rateID, userID).Delete(&models.ExchangeRate{})
+        if result.Error != nil {
+                return apperrors.Internal("Failed to delete exchange rate", result.Error)
+        }
+        if result.RowsAffected == 0 {
+                return apperrors.NotFound("Exchange rate not found")
+        }
+        return nil
+}
+
+// LoadRates loads the user's base currency and exchange rates
+func (s *FXService) LoadRates(userID string) (*RateTable, *apperrors.AppError) {
+        var user models.User
+        if err := s.db.Select("id", "base_currency").Where("id = ?", userID).First(&user).Error; err != nil {
+                if errors.Is(err, gorm.ErrRecordNotFound) {
+                        return nil, apperrors.NotFound("User not found")
+                }
+                return nil, apperrors.Internal("Database error", err)
+        }
+
+        var rates []models.ExchangeRate
+        if err := s.db.Where("user_id = ?", userID).Order("currency, date").Find(&rates).Error; err != nil {
+                return nil, apperrors.Internal("Failed to fetch exchange rates", err)
+        }
+
+        table := &RateTable{Base: user.BaseCurrency, rates: map[string][]models.ExchangeRate{}}
+        for _, rate := range rates {
+                table.rates[rate.Currency] = append(table.rates[rate.Currency], rate)
+        }
+        return table, nil
+}
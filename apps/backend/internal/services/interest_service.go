@@ -0,0 +1,250 @@
+package services
+
+import (
+        "context"
+        "errors"
+        "fmt"
+        "time"
+
+        "khatabook-go-backend/internal/models"
+        apperrors "khatabook-go-backend/pkg/errors"
+        "khatabook-go-backend/pkg/interest"
+
+        "gorm.io/gorm"
+        "gorm.io/gorm/clause"
+)
+
+// InterestService manages interest terms and accrues interest on overdue
+// party balances
+type InterestService struct {
+        db           *gorm.DB
+        transactions *TransactionService
+}
+
+// NewInterestService creates a new interest service
+func NewInterestService(db *gorm.DB) *InterestService {
+        return &InterestService{db: db, transactions: NewTransactionService(db)}
+}
+
+// WithContext returns a copy of the service that runs its queries with ctx,
+// which carries the actor its changes are audited as
+func (s *InterestService) WithContext(ctx context.Context) *InterestService {
+        service := *s
+        service.db = s.db.WithContext(ctx)
+        return &service
+}
+
+// InterestPreview is the interest a party would be charged for a period
+type InterestPreview struct {
+        PartyID  string               `json:"party_id"`
+        Currency string               `json:"currency"`
+        Terms    models.InterestTerms `json:"terms"`
+        interest.Result
+}
+
+// GetTerms retrieves a party's interest terms
+func (s *InterestService) GetTerms(userID, partyID string) (*models.InterestTerms, *apperrors.AppError) {
+        var terms models.InterestTerms
+        if err := s.db.Where("party_id = ? AND user_id = ?", partyID, userID).First(&terms).Error; err != nil {
+                if errors.Is(err, gorm.ErrRecordNotFound) {
+                        return nil, apperrors.NotFound("Interest terms not found")
+                }
+                return nil, apperrors.Internal("Database error", err)
+        }
+        return &terms, nil
+}
+
+// SetTerms creates or replaces a party's interest terms
+func (s *InterestService) SetTerms(userID, partyID string, req *models.SetInterestTermsRequest) (*models.InterestTerms, *apperrors.AppError) {
+        var count int64
+        if err := s.db.Model(&models.Party{}).Where("id = ? AND user_id = ?", partyID, userID).Count(&count).Error; err != nil {
+                return nil, apperrors.Internal("Database error", err)
+        }
+        if count == 0 {
+                return nil, apperrors.NotFound("Party not found")
+        }
+
+        terms := &models.InterestTerms{
+                UserID:    userID,
+                PartyID:   partyID,
+                Rate:      req.Rate,
+                GraceDays: req.GraceDays,
+                Method:    req.Method,
+        }
+        err := s.db.Clauses(clause.OnConflict{
+                Columns:   []clause.Column{{Name: "party_id"}},
+                DoUpdates: clause.AssignmentColumns([]string{"rate", "grace_days", "method", "updated_at"}),
+        }).Create(terms).Error
+        if err != nil {
+                return nil, apperrors.Internal("Failed to save interest terms", err)
+        }
+
+        return s.GetTerms(userID, partyID)
+}
+
+// DeleteTerms removes a party's interest terms. Interest already posted
+// stays on the ledger.
+func (s *InterestService) DeleteTerms(userID, partyID string) *apperrors.AppError {
+        result := s.db.Unscoped().Where("party_id = ? AND user_id = ?", partyID, userID).Delete(&models.InterestTerms{})
+        if result.Error != nil {
+                return apperrors.Internal("Failed to delete interest terms", result.Error)
+        }
+        if result.RowsAffected == 0 {
+                return apperrors.NotFound("Interest terms not found")
+        }
+        return nil
+}
+
+// Preview computes the interest due from a party for a period without
+// posting it. An empty from continues from the last posted interest, or
+// the first transaction; an empty to ends today.
+func (s *InterestService) Preview(userID, partyID, from, to string) (*InterestPreview, *apperrors.AppError) {
+        party, err := loadParty(s.db, userID, partyID)
+        if err != nil {
+                return nil, apperrors.FromError(err, "Failed to compute interest")
+        }
+        preview, err := s.compute(s.db, party, from, to)
+        if err != nil {
+                return nil, apperrors.FromError(err, "Failed to compute interest")
+        }
+        return preview, nil
+}
+
+// Post computes the interest due for a period and records it as a credit
+// transaction dated the last day of the period
+func (s *InterestService) Post(userID, partyID string, req *models.PostInterestRequest) (*models.Transaction, *apperrors.AppError) {
+        var transaction *models.Transaction
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                party, err := lockParty(tx, userID, partyID)
+                if err != nil {
+                        return err
+                }
+
+                preview, err := s.compute(tx, party, req.From, req.To)
+                if err != nil {
+                        return err
+                }
+                if preview.Interest <= 0 {
+                        return apperrors.BadRequest("No interest is due for " + preview.From + " to " + preview.To)
+                }
+
+                description := fmt.Sprintf("Interest %s to %s at %s%% p.a. (%s)", preview.From, preview.To, preview.Terms.Rate, preview.Terms.Method)
+                category := models.CategoryInterest
+                transaction = &models.Transaction{
+                        UserID:          userID,
+                        PartyID:         partyID,
+                        Amount:          preview.Interest,
+                        Currency:        party.Currency,
+                        TransactionType: models.TransactionTypeCredit,
+                        Description:     &description,
+                        Date:            preview.To,
+                        Category:        &category,
+                }
+                warning, err := s.transactions.checkCreditLimit(tx, party, transaction.SignedAmount(), req.OverrideCredit)
+                if err != nil {
+                        return err
+                }
+                if err := s.transactions.insertTransaction(tx, transaction); err != nil {
+                        return err
+                }
+                if warning != "" {
+                        transaction.Warnings = append(transaction.Warnings, warning)
+                }
+
+                _, err = autoAllocate(tx, party)
+                return err
+        })
+        if err != nil {
+                return nil, apperrors.FromError(err, "Failed to post interest")
+        }
+        return transaction, nil
+}
+
+// compute runs the interest engine over a party's transactions
+func (s *InterestService) compute(tx *gorm.DB, party *models.Party, from, to string) (*InterestPreview, error) {
+        var terms models.InterestTerms
+        if err := tx.Where("party_id = ?", party.ID).First(&terms).Error; err != nil {
+                if errors.Is(err, gorm.ErrRecordNotFound) {
+                        return nil, apperrors.BadRequest("Party has no interest terms")
+                }
+                return nil, err
+        }
+
+        // Interest already posted is never charged twice. A reversed posting
+        // and its reversal do not count, so the period can be posted again.
+        var postedThrough *string
+        if err := tx.Model(&models.Transaction{}).
+                Select("MAX(date)").
+                Where("party_id = ? AND category = ? AND reversal_of IS NULL", party.ID, models.CategoryInterest).
+                Where("NOT EXISTS (SELECT 1 FROM transactions r WHERE r.reversal_of = transactions.id AND r.deleted_at IS NULL)").
+                Scan(&postedThrough).Error; err != nil {
+                return nil, err
+        }
+
+        if from == "" {
+                if postedThrough != nil {
+                        from = nextDay(*postedThrough)
+                } else if err := tx.Model(&models.Transaction{}).
+                        Select("COALESCE(MIN(date), '')").
+                        Where("party_id = ?", party.ID).
+                        Scan(&from).Error; err != nil {
+                        return nil, err
+                }
+        }
+        if to == "" {
+                to = time.Now().Format("2006-01-02")
+        }
+        if from == "" {
+                from = to
+        }
+        if postedThrough != nil && from <= *postedThrough {
+                return nil, apperrors.BadRequest("Interest is already posted through " + *postedThrough)
+        }
+
+        // Simple interest is not charged on earlier interest
+        var transactions []models.Transaction
+        query := tx.Where("party_id = ? AND date <= ?", party.ID, to)
+        if terms.Method == interest.Simple {
+                query = query.Where("category IS NULL OR category <> ?", models.CategoryInterest)
+        }
+        if err := query.Order(ledgerOrder).Find(&transactions).Error; err != nil {
+                return nil, err
+        }
+
+        movements := make([]interest.Movement, len(transactions))
+        for i, t := range transactions {
+                movements[i] = interest.Movement{Date: t.Date, Amount: t.SignedAmount()}
+        }
+
+        result, err := interest.Compute(movements, interest.Terms{
+                Rate:      terms.Rate,
+                GraceDays: terms.GraceDays,
+                Method:    terms.Method,
+        }, from, to)
+        if err != nil {
+                return nil, apperrors.BadRequest(err.Error())
+        }
+
+        return &InterestPreview{PartyID: party.ID, Currency: party.Currency, Terms: terms, Result: *result}, nil
+}
+
+// loadParty loads a party owned by the user without locking it
+func loadParty(tx *gorm.DB, userID, partyID string) (*models.Party, error) {
+        var party models.Party
+        if err := tx.Where("id = ? AND user_id = ?", partyID, userID).First(&party).Error; err != nil {
+                if errors.Is(err, gorm.ErrRecordNotFound) {
+                        return nil, apperrors.NotFound("Party not found")
+                }
+                return nil, err
+        }
+        return &party, nil
+}
+
+// nextDay returns the day after a YYYY-MM-DD date
+func nextDay(date string) string {
+        day, err := time.Parse("2006-01-02", date)
+        if err != nil {
+                return date
+        }
+        return day.AddDate(0, 0, 1).Format("2006-01-02")
+}
@@ -0,0 +1,567 @@
+package services
+
+import (
+        "context"
+        "errors"
+        "fmt"
+        "time"
+
+        "khatabook-go-backend/internal/models"
+        apperrors "khatabook-go-backend/pkg/errors"
+        "khatabook-go-backend/pkg/gst"
+
+        "gorm.io/gorm"
+        "gorm.io/gorm/clause"
+)
+
+// InvoiceService handles invoices and their posting to the ledger
+type InvoiceService struct {
+        db           *gorm.DB
+        transactions *TransactionService
+}
+
+// NewInvoiceService creates a new invoice service
+func NewInvoiceService(db *gorm.DB) *InvoiceService {
+        return &InvoiceService{db: db, transactions: NewTransactionService(db)}
+}
+
+// WithContext returns a copy of the service that runs its queries with ctx,
+// which carries the actor its changes are audited as
+func (s *InvoiceService) WithContext(ctx context.Context) *InvoiceService {
+        service := *s
+        service.db = s.db.WithContext(ctx)
+        return &service
+}
+
+// GetInvoices retrieves invoices with optional filters
+func (s *InvoiceService) GetInvoices(userID string, filters map[string]interface{}) ([]models.Invoice, *apperrors.AppError) {
+        var invoices []models.Invoice
+        query := s.db.Where("user_id = ?", userID)
+
+        if partyID, exists := filters["party_id"]; exists && partyID != "" {
+                query = query.Where("party_id = ?", partyID)
+        }
+        if invoiceType, exists := filters["invoice_type"]; exists && invoiceType != "" {
+                query = query.Where("invoice_type = ?", invoiceType)
+        }
+        if status, exists := filters["status"]; exists && status != "" {
+                query = query.Where("status = ?", status)
+        }
+
+        if err := query.Order("date DESC, created_at DESC").Find(&invoices).Error; err != nil {
+                return nil, apperrors.Internal("Failed to fetch invoices", err)
+        }
+        return invoices, nil
+}
+
+// GetInvoiceByID retrieves a single invoice with its line items
+func (s *InvoiceService) GetInvoiceByID(userID, invoiceID string) (*models.Invoice, *apperrors.AppError) {
+        var invoice models.Invoice
+        err := s.db.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+                Where("id = ? AND user_id = ?", invoiceID, userID).
+                First(&invoice).Error
+        if err != nil {
+                if errors.Is(err, gorm.ErrRecordNotFound) {
+                        return nil, apperrors.NotFound("Invoice not found")
+                }
+                return nil, apperrors.Internal("Database error", err)
+        }
+        return &invoice, nil
+}
+
+// CreateInvoice creates a draft invoice and computes its tax
+func (s *InvoiceService) CreateInvoice(userID string, req *models.CreateInvoiceRequest) (*models.Invoice, *apperrors.AppError) {
+        var party models.Party
+        if err := s.db.Where("id = ? AND user_id = ?", req.PartyID, userID).First(&party).Error; err != nil {
+                if errors.Is(err, gorm.ErrRecordNotFound) {
+                        return nil, apperrors.NotFound("Party not found")
+                }
+                return nil, apperrors.Internal("Database error", err)
+        }
+
+        var user models.User
+        if err := s.db.Select("id", "state_code").Where("id = ?", userID).First(&user).Error; err != nil {
+                return nil, apperrors.Internal("Database error", err)
+        }
+
+        invoice := &models.Invoice{
+                UserID:        userID,
+                PartyID:       party.ID,
+                InvoiceType:   req.InvoiceType,
+                InvoiceNumber: req.InvoiceNumber,
+                Date:          req.Date,
+                Status:        models.InvoiceStatusDraft,
+                Currency:      party.Currency,
+                PlaceOfSupply: req.PlaceOfSupply,
+                RoundOff:      true,
+                Notes:         &req.Notes,
+        }
+        if invoice.Date == "" {
+                invoice.Date = time.Now().Format("2006-01-02")
+        }
+        if req.DueDate != "" {
+                invoice.DueDate = &req.DueDate
+        }
+        if req.RoundOff != nil {
+                invoice.RoundOff = *req.RoundOff
+        }
+
+        // A sale is supplied from the user's state to the party's, a purchase
+        // the other way round
+        userState, partyState := stringValue(user.StateCode), stringValue(party.StateCode)
+        invoice.SupplierState = userState
+        if invoice.PlaceOfSupply == "" {
+                invoice.PlaceOfSupply = partyState
+        }
+        if req.InvoiceType == models.InvoiceTypePurchase {
+                invoice.SupplierState = partyState
+                if req.PlaceOfSupply == "" {
+                        invoice.PlaceOfSupply = userState
+                }
+        }
+
+        items, appErr := computeInvoice(invoice, req.Items)
+        if appErr != nil {
+                return nil, appErr
+        }
+
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                if err := lockInvoiceNumbers(tx, userID); err != nil {
+                        return err
+                }
+                if invoice.InvoiceNumber == "" {
+                        number, err := nextInvoiceNumber(tx, userID, req.InvoiceType)
+                        if err != nil {
+                                return err
+                        }
+                        invoice.InvoiceNumber = number
+                }
+                if err := checkInvoiceNumber(tx, invoice); err != nil {
+                        return err
+                }
+
+                invoice.Items = items
+                return tx.Create(invoice).Error
+        })
+        if err != nil {
+                return nil, apperrors.FromError(err, "Failed to create invoice")
+        }
+
+        return s.GetInvoiceByID(userID, invoice.ID)
+}
+
+// UpdateInvoice updates a draft invoice and recomputes its tax
+func (s *InvoiceService) UpdateInvoice(userID, invoiceID string, req *models.UpdateInvoiceRequest) (*models.Invoice, *apperrors.AppError) {
+        invoice, appErr := s.GetInvoiceByID(userID, invoiceID)
+        if appErr != nil {
+                return nil, appErr
+        }
+
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                // The invoice is locked before the numbering, in the order PostInvoice uses
+                if err := lockInvoice(tx, invoice); err != nil {
+                        return err
+                }
+                if invoice.Status != models.InvoiceStatusDraft {
+                        return apperrors.BadRequest("Only draft invoices can be edited")
+                }
+                if err := tx.Where("invoice_id = ?", invoice.ID).Order("position").Find(&invoice.Items).Error; err != nil {
+                        return err
+                }
+
+                if req.InvoiceNumber != "" {
+                        invoice.InvoiceNumber = req.InvoiceNumber
+                }
+                if req.Date != "" {
+                        invoice.Date = req.Date
+                }
+                if req.DueDate != "" {
+                        invoice.DueDate = &req.DueDate
+                }
+                if req.PlaceOfSupply != "" {
+                        invoice.PlaceOfSupply = req.PlaceOfSupply
+                }
+                if req.RoundOff != nil {
+                        invoice.RoundOff = *req.RoundOff
+                }
+                if req.Notes != "" {
+                        invoice.Notes = &req.Notes
+                }
+
+                itemRequests := req.Items
+                if len(itemRequests) == 0 {
+                        itemRequests = itemRequestsOf(invoice.Items)
+                }
+                items, appErr := computeInvoice(invoice, itemRequests)
+                if appErr != nil {
+                        return appErr
+                }
+
+                if err := lockInvoiceNumbers(tx, userID); err != nil {
+                        return err
+                }
+                if err := checkInvoiceNumber(tx, invoice); err != nil {
+                        return err
+                }
+                if err := tx.Where("invoice_id = ?", invoice.ID).Delete(&models.InvoiceItem{}).Error; err != nil {
+                        return err
+                }
+
+                invoice.Items = nil
+                if err := tx.Model(invoice).
+                        Select("invoice_number", "date", "due_date", "place_of_supply", "round_off", "notes",
+                                "taxable", "cgst", "sgst", "igst", "round_off_value", "total").
+                        Updates(invoice).Error; err != nil {
+                        return err
+                }
+                for i := range items {
+                        items[i].InvoiceID = invoice.ID
+                }
+                return tx.Create(&items).Error
+        })
+        if err != nil {
+                return nil, apperrors.FromError(err, "Failed to update invoice")
+        }
+
+        return s.GetInvoiceByID(userID, invoiceID)
+}
+
+// DeleteInvoice deletes a draft invoice
+func (s *InvoiceService) DeleteInvoice(userID, invoiceID string) *apperrors.AppError {
+        invoice, appErr := s.GetInvoiceByID(userID, invoiceID)
+        if appErr != nil {
+                return appErr
+        }
+
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                if err := lockInvoice(tx, invoice); err != nil {
+                        return err
+                }
+                if invoice.Status != models.InvoiceStatusDraft {
+                        return apperrors.BadRequest("Only draft invoices can be deleted; cancel a posted invoice instead")
+                }
+                if err := tx.Where("invoice_id = ?", invoice.ID).Delete(&models.InvoiceItem{}).Error; err != nil {
+                        return err
+                }
+                return tx.Delete(&models.Invoice{}, "id = ?", invoice.ID).Error
+        })
+        if err != nil {
+                return apperrors.FromError(err, "Failed to delete invoice")
+        }
+        return nil
+}
+
+// PostInvoice posts a draft invoice to the party's ledger. A sale is
+// recorded as a credit and a purchase as a debit for the invoice total.
+func (s *InvoiceService) PostInvoice(userID, invoiceID string, req *models.PostInvoiceRequest) (*models.Invoice, *apperrors.AppError) {
+        invoice, appErr := s.GetInvoiceByID(userID, invoiceID)
+        if appErr != nil {
+                return nil, appErr
+        }
+
+        var warning string
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                party, err := lockParty(tx, userID, invoice.PartyID)
+                if err != nil {
+                        return err
+                }
+                if err := lockInvoice(tx, invoice); err != nil {
+                        return err
+                }
+                if invoice.Status != models.InvoiceStatusDraft {
+                        return apperrors.BadRequest("Only draft invoices can be posted")
+                }
+                if invoice.Total <= 0 {
+                        return apperrors.BadRequest("Invoice total must be positive")
+                }
+
+                transactionType := models.TransactionTypeCredit
+                description := "Sale invoice " + invoice.InvoiceNumber
+                if invoice.InvoiceType == models.InvoiceTypePurchase {
+                        transactionType = models.TransactionTypeDebit
+                        description = "Purchase bill " + invoice.InvoiceNumber
+                }
+                category := "invoice"
+
+                transaction := &models.Transaction{
+                        UserID:          userID,
+                        PartyID:         invoice.PartyID,
+                        Amount:          invoice.Total,
+                        Currency:        invoice.Currency,
+                        TransactionType: transactionType,
+                        Description:     &description,
+                        Date:            invoice.Date,
+                        Category:        &category,
+                }
+                warning, err = s.transactions.checkCreditLimit(tx, party, transaction.SignedAmount(), req.OverrideCredit)
+                if err != nil {
+                        return err
+                }
+                if err := s.transactions.insertTransaction(tx, transaction); err != nil {
+                        return err
+                }
+
+                if err := tx.Model(&models.Invoice{}).Where("id = ?", invoice.ID).Updates(map[string]interface{}{
+                        "status":         models.InvoiceStatusPosted,
+                        "transaction_id": transaction.ID,
+                }).Error; err != nil {
+                        return err
+                }
+
+                // Apply any advance the party has already paid
+                _, err = autoAllocate(tx, party)
+                return err
+        })
+        if err != nil {
+                return nil, apperrors.FromError(err, "Failed to post invoice")
+        }
+
+        invoice, appErr = s.GetInvoiceByID(userID, invoiceID)
+        if appErr != nil {
+                return nil, appErr
+        }
+        if warning != "" {
+                invoice.Warnings = append(invoice.Warnings, warning)
+        }
+        return invoice, nil
+}
+
+// CancelInvoice cancels an invoice, removing its ledger transaction if it
+// was posted. In strict mode the transaction stays and is reversed instead.
+func (s *InvoiceService) CancelInvoice(userID, invoiceID string) (*models.Invoice, *apperrors.AppError) {
+        invoice, appErr := s.GetInvoiceByID(userID, invoiceID)
+        if appErr != nil {
+                return nil, appErr
+        }
+
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                party, err := lockParty(tx, userID, invoice.PartyID)
+                if err != nil {
+                        return err
+                }
+                if err := lockInvoice(tx, invoice); err != nil {
+                        return err
+                }
+                if invoice.Status == models.InvoiceStatusCancelled {
+                        return apperrors.BadRequest("Invoice is already cancelled")
+                }
+
+                return s.cancelInvoice(tx, party, invoice)
+        })
+        if err != nil {
+                return nil, apperrors.FromError(err, "Failed to cancel invoice")
+        }
+
+        return s.GetInvoiceByID(userID, invoiceID)
+}
+
+// cancelInvoice cancels a locked invoice that is not cancelled yet, removing
+// its ledger transaction or, in strict mode, reversing it. The caller must
+// hold the party lock.
+func (s *InvoiceService) cancelInvoice(tx *gorm.DB, party *models.Party, invoice *models.Invoice) error {
+        if invoice.TransactionID != nil {
+                var transaction models.Transaction
+                if err := tx.First(&transaction, "id = ?", *invoice.TransactionID).Error; err != nil {
+                        return err
+                }
+
+                strict, err := strictMode(tx, invoice.UserID)
+                if err != nil {
+                        return err
+                }
+                if strict {
+                        // The invoice keeps its transaction, cancelled by a reversing entry
+                        if _, err := s.transactions.reverse(tx, party, &transaction, &models.ReverseTransactionRequest{}); err != nil {
+                                return err
+                        }
+                        if _, err := autoAllocate(tx, party); err != nil {
+                                return err
+                        }
+                        return tx.Model(&models.Invoice{}).Where("id = ?", invoice.ID).
+                                Update("status", models.InvoiceStatusCancelled).Error
+                }
+
+                if err := s.transactions.deleteTransaction(tx, &transaction); err != nil {
+                        return err
+                }
+                // A cancelled invoice is reissued rather than restored,
+                // so its transaction does not go to the trash
+                if err := recordAudit(tx, transaction.UserID, models.AuditEntityTransaction, transaction.ID, models.AuditActionPurge, &transaction, nil); err != nil {
+                        return err
+                }
+                if err := chainTransaction(tx, &transaction, models.AuditActionPurge); err != nil {
+                        return err
+                }
+                if err := tx.Unscoped().Delete(&transaction).Error; err != nil {
+                        return err
+                }
+                // Payments freed from the invoice settle the party's other bills
+                if _, err := autoAllocate(tx, party); err != nil {
+                        return err
+                }
+        }
+
+        return tx.Model(&models.Invoice{}).Where("id = ?", invoice.ID).Updates(map[string]interface{}{
+                "status":         models.InvoiceStatusCancelled,
+                "transaction_id": nil,
+        }).Error
+}
+
+// computeInvoice validates the item requests and computes the line taxes
+// and invoice totals
+func computeInvoice(invoice *models.Invoice, requests []models.InvoiceItemRequest) ([]models.InvoiceItem, *apperrors.AppError) {
+        if len(requests) == 0 {
+                return nil, apperrors.BadRequest("An invoice needs at least one item")
+        }
+        for _, state := range []string{invoice.SupplierState, invoice.PlaceOfSupply} {
+                if state != "" && !gst.ValidStateCode(state) {
+                        return nil, apperrors.BadRequest("Invalid GST state code " + state)
+                }
+        }
+
+        interstate := gst.Interstate(invoice.SupplierState, invoice.PlaceOfSupply)
+        items := make([]models.InvoiceItem, len(requests))
+        taxes := make([]gst.LineTax, len(requests))
+        for i, req := range requests {
+                if req.HSNSAC != "" && !gst.ValidHSN(req.HSNSAC) {
+                        return nil, apperrors.BadRequest(fmt.Sprintf("Item %d: invalid HSN/SAC code %s", i+1, req.HSNSAC))
+                }
+
+                tax, err := gst.ComputeLine(gst.Line{
+                        Quantity: req.Quantity,
+                        Rate:     req.Rate,
+                        Discount: req.Discount,
+                        GSTRate:  req.GSTRate,
+                }, interstate)
+                if err != nil {
+                        return nil, apperrors.BadRequest(fmt.Sprintf("Item %d: %s", i+1, err))
+                }
+                taxes[i] = tax
+
+                items[i] = models.InvoiceItem{
+                        InvoiceID: invoice.ID,
+                        Position:  i + 1,
+                        ItemName:  req.ItemName,
+                        Quantity:  req.Quantity,
+                        Rate:      req.Rate,
+                        Discount:  req.Discount,
+                        GSTRate:   req.GSTRate,
+                        Taxable:   tax.Taxable,
+                        CGST:      tax.CGST,
+                        SGST:      tax.SGST,
+                        IGST:      tax.IGST,
+                        Total:     tax.Total,
+                }
+                if req.HSNSAC != "" {
+                        hsn := req.HSNSAC
+                        items[i].HSNSAC = &hsn
+                }
+                if req.Unit != "" {
+                        unit := req.Unit
+                        items[i].Unit = &unit
+                }
+        }
+
+        totals := gst.Sum(taxes, invoice.RoundOff)
+        invoice.Taxable = totals.Taxable
+        invoice.CGST = totals.CGST
+        invoice.SGST = totals.SGST
+        invoice.IGST = totals.IGST
+        invoice.RoundOffValue = totals.RoundOff
+        invoice.Total = totals.Total
+
+        return items, nil
+}
+
+// itemRequestsOf turns stored items back into requests so a draft can be
+// recomputed after a header change
+func itemRequestsOf(items []models.InvoiceItem) []models.InvoiceItemRequest {
+        requests := make([]models.InvoiceItemRequest, len(items))
+        for i, item := range items {
+                requests[i] = models.InvoiceItemRequest{
+                        ItemName: item.ItemName,
+                        HSNSAC:   stringValue(item.HSNSAC),
+                        Quantity: item.Quantity,
+                        Unit:     stringValue(item.Unit),
+                        Rate:     item.Rate,
+                        Discount: item.Discount,
+                        GSTRate:  item.GSTRate,
+                }
+        }
+        return requests
+}
+
+// lockInvoiceNumbers serialises the user's invoice numbering on the user
+// row, so concurrent creates cannot pick or claim the same number. The
+// unique indexes on invoice_number back this up.
+func lockInvoiceNumbers(tx *gorm.DB, userID string) error {
+        var user models.User
+        return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+                Where("id = ?", userID).First(&user).Error
+}
+
+// nextInvoiceNumber generates the next sequential number for the user's
+// sale invoices or purchase bills. The caller holds lockInvoiceNumbers.
+func nextInvoiceNumber(tx *gorm.DB, userID, invoiceType string) (string, error) {
+        prefix := "INV-"
+        if invoiceType == models.InvoiceTypePurchase {
+                prefix = "BILL-"
+        }
+
+        var count int64
+        if err := tx.Model(&models.Invoice{}).
+                Where("user_id = ? AND invoice_type = ?", userID, invoiceType).
+                Count(&count).Error; err != nil {
+                return "", err
+        }
+
+        for n := count + 1; ; n++ {
+                number := fmt.Sprintf("%s%05d", prefix, n)
+                var taken int64
+                if err := tx.Model(&models.Invoice{}).
+                        Where("user_id = ? AND invoice_type = ? AND invoice_number = ?", userID, invoiceType, number).
+                        Count(&taken).Error; err != nil {
+                        return "", err
+                }
+                if taken == 0 {
+                        return number, nil
+                }
+        }
+}
+
+// checkInvoiceNumber rejects a number already used by another of the user's
+// sale invoices, or by another bill of the same supplier
+func checkInvoiceNumber(tx *gorm.DB, invoice *models.Invoice) error {
+        query := tx.Model(&models.Invoice{}).
+                Where("user_id = ? AND invoice_type = ? AND invoice_number = ?", invoice.UserID, invoice.InvoiceType, invoice.InvoiceNumber)
+        if invoice.InvoiceType == models.InvoiceTypePurchase {
+                query = query.Where("party_id = ?", invoice.PartyID)
+        }
+        if invoice.ID != "" {
+                query = query.Where("id <> ?", invoice.ID)
+        }
+
+        var count int64
+        if err := query.Count(&count).Error; err != nil {
+                return err
+        }
+        if count > 0 {
+                return apperrors.Conflict("Invoice number " + invoice.InvoiceNumber + " is already in use")
+        }
+        return nil
+}
+
+// lockInvoice reloads the invoice header under a row lock so concurrent
+// post and cancel requests see each other's status
+func lockInvoice(tx *gorm.DB, invoice *models.Invoice) error {
+        return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+                First(invoice, "id = ?", invoice.ID).Error
+}
+
+// stringValue returns the string a pointer refers to, or "" for nil
+func stringValue(s *string) string {
+        if s == nil {
+                return ""
+        }
+        return *s
+}
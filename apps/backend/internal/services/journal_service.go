@@ -0,0 +1,472 @@
+package services
+
+import (
+        "database/sql"
+        "errors"
+        "fmt"
+        "log"
+
+        "khatabook-go-backend/internal/models"
+        apperrors "khatabook-go-backend/pkg/errors"
+        "khatabook-go-backend/pkg/money"
+
+        "gorm.io/gorm"
+        "gorm.io/gorm/clause"
+)
+
+// JournalService maintains the double-entry journal behind transactions
+type JournalService struct {
+        db *gorm.DB
+}
+
+// NewJournalService creates a new journal service
+func NewJournalService(db *gorm.DB) *JournalService {
+        return &JournalService{db: db}
+}
+
+// LedgerTotals holds the dashboard totals derived from postings, in the
+// user's base currency
+type LedgerTotals struct {
+        BaseCurrency    string
+        TotalCredit     money.Amount
+        TotalDebit      money.Amount
+        TotalReceivable money.Amount
+        TotalPayable    money.Amount
+        UnrealizedFX    money.Amount
+}
+
+// PartyPosition is a party balance in its own currency and in the base
+// currency. BookedBase converts each posting at the rate of its date and
+// CurrentBase converts the balance at today's rate; the difference is the
+// unrealized exchange gain (positive) or loss (negative).
+type PartyPosition struct {
+        PartyID      string       `json:"party_id"`
+        PartyName    string       `json:"party_name"`
+        Currency     string       `json:"currency"`
+        Balance      money.Amount `json:"balance"`
+        BookedBase   money.Amount `json:"booked_base"`
+        CurrentBase  money.Amount `json:"current_base"`
+        UnrealizedFX money.Amount `json:"unrealized_fx"`
+}
+
+// GetAccounts retrieves all journal accounts for a user with their balances
+func (s *JournalService) GetAccounts(userID string) ([]models.AccountBalance, *apperrors.AppError) {
+        var accounts []models.AccountBalance
+        err := s.db.Table("accounts a").
+                Select("a.*, COALESCE(SUM(p.amount), 0) as balance").
+                Joins("LEFT JOIN postings p ON p.account_id = a.id").
+                Where("a.user_id = ?", userID).
+                Group("a.id").
+                Order("a.code").
+                Scan(&accounts).Error
+        if err != nil {
+                return nil, apperrors.Internal("Failed to fetch accounts", err)
+        }
+        return accounts, nil
+}
+
+// GetEntries retrieves journal entries with their postings, optionally
+// limited to a single transaction or account
+func (s *JournalService) GetEntries(userID, transactionID, accountID string) ([]models.JournalEntry, *apperrors.AppError) {
+        var entries []models.JournalEntry
+        query := s.db.Where("user_id = ?", userID)
+
+        if transactionID != "" {
+                query = query.Where("transaction_id = ?", transactionID)
+        }
+        if accountID != "" {
+                query = query.Where("id IN (?)", s.db.Model(&models.Posting{}).Select("entry_id").Where("account_id = ?", accountID))
+        }
+
+        if err := query.Preload("Postings").Order("date, created_at").Find(&entries).Error; err != nil {
+                return nil, apperrors.Internal("Failed to fetch journal entries", err)
+        }
+        return entries, nil
+}
+
+// GetTotals derives the credit, debit, receivable and payable totals for a
+// user from the postings on party accounts up to asOf. Credits and debits are
+// converted at the rate of their date, balances at the rate on asOf.
+func (s *JournalService) GetTotals(userID string, rates *RateTable, asOf string) (*LedgerTotals, *apperrors.AppError) {
+        totals := &LedgerTotals{BaseCurrency: rates.Base}
+
+        var movements []struct {
+                Currency string
+                Date     string
+                Credit   money.Amount
+                Debit    money.Amount
+        }
+        err := s.db.Table("postings p").
+                Select(`a.currency, e.date,
+                        COALESCE(SUM(CASE WHEN p.amount > 0 THEN p.amount ELSE 0 END), 0) as credit,
+                        COALESCE(SUM(CASE WHEN p.amount < 0 THEN -p.amount ELSE 0 END), 0) as debit`).
+                Joins("JOIN accounts a ON a.id = p.account_id").
+                Joins("JOIN journal_entries e ON e.id = p.entry_id").
+                Joins("JOIN transactions t ON t.id = e.transaction_id").
+                Where("a.user_id = ? AND a.account_type = ? AND t.transaction_type <> ? AND e.date <= ?", userID, models.AccountTypeParty, models.TransactionTypeOpening, asOf).
+                Group("a.currency, e.date").
+                Scan(&movements).Error
+        if err != nil {
+                return nil, apperrors.Internal("Failed to compute totals", err)
+        }
+
+        for _, m := range movements {
+                credit, err := rates.ToBase(m.Credit, m.Currency, m.Date)
+                if err != nil {
+                        return nil, apperrors.Unprocessable(err.Error())
+                }
+                debit, err := rates.ToBase(m.Debit, m.Currency, m.Date)
+                if err != nil {
+                        return nil, apperrors.Unprocessable(err.Error())
+                }
+                totals.TotalCredit += credit
+                totals.TotalDebit += debit
+        }
+
+        positions, appErr := s.GetPositions(userID, rates, asOf)
+        if appErr != nil {
+                return nil, appErr
+        }
+        for _, position := range positions {
+                if position.CurrentBase > 0 {
+                        totals.TotalReceivable += position.CurrentBase
+                } else {
+                        totals.TotalPayable -= position.CurrentBase
+                }
+                totals.UnrealizedFX += position.UnrealizedFX
+        }
+
+        return totals, nil
+}
+
+// GetPositions returns every party balance converted into the base currency
+// together with its unrealized exchange difference as of a date
+func (s *JournalService) GetPositions(userID string, rates *RateTable, asOf string) ([]PartyPosition, *apperrors.AppError) {
+        var rows []struct {
+                PartyID   string
+                PartyName string
+                Currency  string
+                Date      string
+                Amount    money.Amount
+        }
+        err := s.db.Table("postings p").
+                Select("a.party_id, par.name as party_name, a.currency, e.date, SUM(p.amount) as amount").
+                Joins("JOIN accounts a ON a.id = p.account_id").
+                Joins("JOIN journal_entries e ON e.id = p.entry_id").
+                Joins("JOIN parties par ON par.id = a.party_id").
+                Where("a.user_id = ? AND a.account_type = ? AND e.date <= ?", userID, models.AccountTypeParty, asOf).
+                Group("a.party_id, par.name, a.currency, e.date").
+                Order("par.name, a.party_id, e.date").
+                Scan(&rows).Error
+        if err != nil {
+                return nil, apperrors.Internal("Failed to compute positions", err)
+        }
+
+        positions := []PartyPosition{}
+        for _, row := range rows {
+                n := len(positions)
+                if n == 0 || positions[n-1].PartyID != row.PartyID {
+                        positions = append(positions, PartyPosition{PartyID: row.PartyID, PartyName: row.PartyName, Currency: row.Currency})
+                        n++
+                }
+
+                booked, err := rates.ToBase(row.Amount, row.Currency, row.Date)
+                if err != nil {
+                        return nil, apperrors.Unprocessable(err.Error())
+                }
+                positions[n-1].Balance += row.Amount
+                positions[n-1].BookedBase += booked
+        }
+
+        for i := range positions {
+                current, err := rates.ToBase(positions[i].Balance, positions[i].Currency, asOf)
+                if err != nil {
+                        return nil, apperrors.Unprocessable(err.Error())
+                }
+                positions[i].CurrentBase = current
+                positions[i].UnrealizedFX = current - positions[i].BookedBase
+        }
+
+        return positions, nil
+}
+
+// OpeningDifference is a party whose stored balance is not accounted for by
+// its transactions when it is first brought into the journal
+type OpeningDifference struct {
+        PartyID          string       `json:"party_id"`
+        PartyName        string       `json:"party_name"`
+        StoredBalance    money.Amount `json:"stored_balance"`
+        FromTransactions money.Amount `json:"from_transactions"`
+        Difference       money.Amount `json:"difference"`
+        Booked           bool         `json:"booked"`
+}
+
+// BackfillReport is the outcome of a journal backfill
+type BackfillReport struct {
+        Differences []OpeningDifference `json:"differences"`
+        Posted      int                 `json:"posted"`
+}
+
+// Backfill brings data recorded before the journal existed into it: every
+// unposted transaction is posted. Parties that have never been journaled are
+// checked for a stored balance their transactions do not account for; the
+// difference is reported, and only booked as an opening transaction when
+// bookOpenings is set. The other parties are then rebalanced from their
+// postings. Unbooked differences stay visible to reconciliation as drift.
+func (s *JournalService) Backfill(bookOpenings bool) (*BackfillReport, error) {
+        report := &BackfillReport{Differences: []OpeningDifference{}}
+
+        var parties []models.Party
+        journaled := s.db.Model(&models.Account{}).Select("party_id").Where("party_id IS NOT NULL")
+        if err := s.db.Where("id NOT IN (?)", journaled).Find(&parties).Error; err != nil {
+                return nil, fmt.Errorf("failed to load parties: %w", err)
+        }
+
+        var rebalance []string
+        for i := range parties {
+                party := &parties[i]
+                var difference *OpeningDifference
+                err := s.db.Transaction(func(tx *gorm.DB) error {
+                        var fromTransactions money.Amount
+                        var firstDate sql.NullString
+                        err := tx.Model(&models.Transaction{}).
+                                Select("COALESCE(SUM(CASE WHEN transaction_type = ? THEN -amount ELSE amount END), 0), MIN(date)", models.TransactionTypeDebit).
+                                Where("party_id = ?", party.ID).
+                                Row().Scan(&fromTransactions, &firstDate)
+                        if err != nil {
+                                return err
+                        }
+                        if _, err := s.partyAccount(tx, party.UserID, party.ID); err != nil {
+                                return err
+                        }
+
+                        opening := party.Balance - fromTransactions
+                        if opening == 0 {
+                                return nil
+                        }
+                        difference = &OpeningDifference{
+                                PartyID:          party.ID,
+                                PartyName:        party.Name,
+                                StoredBalance:    party.Balance,
+                                FromTransactions: fromTransactions,
+                                Difference:       opening,
+                        }
+                        if !bookOpenings {
+                                return nil
+                        }
+
+                        date := party.CreatedAt.Format("2006-01-02")
+                        if firstDate.Valid && firstDate.String < date {
+                                date = firstDate.String
+                        }
+                        description := "Opening balance"
+                        transaction := &models.Transaction{
+                                UserID:          party.UserID,
+                                PartyID:         party.ID,
+                                Amount:          opening,
+                                Currency:        party.Currency,
+                                TransactionType: models.TransactionTypeOpening,
+                                Description:     &description,
+                                Date:            date,
+                        }
+                        if err := tx.Create(transaction).Error; err != nil {
+                                return err
+                        }
+                        if err := recordAudit(tx, party.UserID, models.AuditEntityTransaction, transaction.ID, models.AuditActionCreate, nil, transaction); err != nil {
+                                return err
+                        }
+                        difference.Booked = true
+                        return nil
+                })
+                if err != nil {
+                        return nil, fmt.Errorf("failed to backfill opening balance for party %s: %w", party.ID, err)
+                }
+                if difference != nil {
+                        report.Differences = append(report.Differences, *difference)
+                }
+                if difference == nil || difference.Booked {
+                        rebalance = append(rebalance, party.ID)
+                }
+        }
+
+        unposted := s.db.Model(&models.JournalEntry{}).Select("transaction_id").Where("transaction_id IS NOT NULL")
+        for {
+                var transactions []models.Transaction
+                if err := s.db.Where("id NOT IN (?)", unposted).Order("date, created_at").Limit(500).Find(&transactions).Error; err != nil {
+                        return nil, fmt.Errorf("failed to load unposted transactions: %w", err)
+                }
+                if len(transactions) == 0 {
+                        break
+                }
+
+                err := s.db.Transaction(func(tx *gorm.DB) error {
+                        for i := range transactions {
+                                if err := s.postTransaction(tx, &transactions[i]); err != nil {
+                                        return err
+                                }
+                        }
+                        return nil
+                })
+                if err != nil {
+                        return nil, fmt.Errorf("failed to backfill transactions: %w", err)
+                }
+                report.Posted += len(transactions)
+                log.Printf("Journal backfill: posted %d transactions", len(transactions))
+        }
+
+        // Running balances predate the journal and any booked opening
+        transactionService := NewTransactionService(s.db)
+        for _, partyID := range rebalance {
+                if err := s.db.Transaction(func(tx *gorm.DB) error {
+                        return transactionService.rebalanceParty(tx, partyID)
+                }); err != nil {
+                        return nil, fmt.Errorf("failed to rebalance party %s: %w", partyID, err)
+                }
+        }
+
+        return report, nil
+}
+
+// postTransaction writes the journal entry for a transaction: the party
+// account moves by the signed amount and the cash account takes the other leg
+func (s *JournalService) postTransaction(tx *gorm.DB, transaction *models.Transaction) error {
+        partyAccount, err := s.partyAccount(tx, transaction.UserID, transaction.PartyID)
+        if err != nil {
+                return err
+        }
+        // Opening balances come from equity; everything else moves through cash
+        counterAccount, err := s.systemAccount(tx, transaction.UserID, models.AccountTypeCash, transaction.Currency, "Cash and bank")
+        if transaction.TransactionType == models.TransactionTypeOpening {
+                counterAccount, err = s.systemAccount(tx, transaction.UserID, models.AccountTypeEquity, transaction.Currency, "Opening balances")
+        }
+        if err != nil {
+                return err
+        }
+
+        description := transaction.TransactionType
+        if transaction.Description != nil && *transaction.Description != "" {
+                description = *transaction.Description
+        }
+
+        amount := transaction.SignedAmount()
+        entry := &models.JournalEntry{
+                UserID:        transaction.UserID,
+                TransactionID: &transaction.ID,
+                Date:          transaction.Date,
+                Description:   description,
+                Postings: []models.Posting{
+                        {AccountID: partyAccount.ID, UserID: transaction.UserID, Amount: amount},
+                        {AccountID: counterAccount.ID, UserID: transaction.UserID, Amount: -amount},
+                },
+        }
+        return s.createEntry(tx, entry)
+}
+
+// createEntry inserts an entry and its postings after checking they balance
+func (s *JournalService) createEntry(tx *gorm.DB, entry *models.JournalEntry) error {
+        if len(entry.Postings) < 2 {
+                return fmt.Errorf("journal entry needs at least two postings")
+        }
+
+        var sum money.Amount
+        for _, posting := range entry.Postings {
+                sum += posting.Amount
+        }
+        if sum != 0 {
+                return fmt.Errorf("journal entry is unbalanced by %s", sum)
+        }
+
+        return tx.Create(entry).Error
+}
+
+// partyBalance returns the balance of a party account from its postings
+func (s *JournalService) partyBalance(tx *gorm.DB, partyID string) (money.Amount, error) {
+        var balance money.Amount
+        err := tx.Table("postings p").
+                Select("COALESCE(SUM(p.amount), 0)").
+                Joins("JOIN accounts a ON a.id = p.account_id").
+                Where("a.party_id = ? AND a.account_type = ?", partyID, models.AccountTypeParty).
+                Row().Scan(&balance)
+        return balance, err
+}
+
+// removeTransaction deletes the journal entry and postings of a transaction
+func (s *JournalService) removeTransaction(tx *gorm.DB, transactionID string) error {
+        entries := tx.Model(&models.JournalEntry{}).Select("id").Where("transaction_id = ?", transactionID)
+        if err := tx.Where("entry_id IN (?)", entries).Delete(&models.Posting{}).Error; err != nil {
+                return err
+        }
+        return tx.Where("transaction_id = ?", transactionID).Delete(&models.JournalEntry{}).Error
+}
+
+// syncPartyBalance stores the posted balance on the party row and returns it
+func (s *JournalService) syncPartyBalance(tx *gorm.DB, partyID string) (money.Amount, error) {
+        balance, err := s.partyBalance(tx, partyID)
+        if err != nil {
+                return 0, err
+        }
+        if err := tx.Model(&models.Party{}).Where("id = ?", partyID).Update("balance", balance).Error; err != nil {
+                return 0, err
+        }
+        return balance, nil
+}
+
+// partyAccount returns the account of a party, creating it on first use.
+// The account is kept in the party's currency.
+func (s *JournalService) partyAccount(tx *gorm.DB, userID, partyID string) (*models.Account, error) {
+        code := models.AccountTypeParty + ":" + partyID
+
+        var account models.Account
+        err := tx.Where("user_id = ? AND code = ?", userID, code).First(&account).Error
+        if err == nil {
+                return &account, nil
+        }
+        if !errors.Is(err, gorm.ErrRecordNotFound) {
+                return nil, err
+        }
+
+        var party models.Party
+        if err := tx.Select("id", "name", "currency").Where("id = ?", partyID).First(&party).Error; err != nil {
+                return nil, err
+        }
+
+        return s.ensureAccount(tx, &models.Account{
+                UserID:      userID,
+                Code:        code,
+                Name:        party.Name,
+                AccountType: models.AccountTypeParty,
+                Currency:    party.Currency,
+                PartyID:     &partyID,
+        })
+}
+
+// systemAccount returns one of the user's shared accounts in a currency,
+// creating it on first use. Accounts in the default currency keep the bare
+// code they had before accounts carried a currency.
+func (s *JournalService) systemAccount(tx *gorm.DB, userID, accountType, currency, name string) (*models.Account, error) {
+        code := accountType
+        if currency != money.DefaultCurrency {
+                code = accountType + ":" + currency
+                name = name + " (" + currency + ")"
+        }
+
+        return s.ensureAccount(tx, &models.Account{
+                UserID:      userID,
+                Code:        code,
+                Name:        name,
+                AccountType: accountType,
+                Currency:    currency,
+        })
+}
+
+func (s *JournalService) ensureAccount(tx *gorm.DB, account *models.Account) (*models.Account, error) {
+        if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(account).Error; err != nil {
+                return nil, err
+        }
+
+        var existing models.Account
+        if err := tx.Where("user_id = ? AND code = ?", account.UserID, account.Code).First(&existing).Error; err != nil {
+                return nil, err
+        }
+        return &existing, nil
+}
@@ -1,42 +1,74 @@
 package services
 
 import (
+        "context"
         "errors"
+        "fmt"
+        "strings"
+        "time"
 
         "khatabook-go-backend/internal/models"
         apperrors "khatabook-go-backend/pkg/errors"
+        "khatabook-go-backend/pkg/gst"
+        "khatabook-go-backend/pkg/money"
 
         "gorm.io/gorm"
+        "gorm.io/gorm/clause"
 )
 
 // PartyService handles party (customer/supplier) operations
 type PartyService struct {
-        db *gorm.DB
+        db           *gorm.DB
+        transactions *TransactionService
+        invoices     *InvoiceService
 }
 
 // NewPartyService creates a new party service
 func NewPartyService(db *gorm.DB) *PartyService {
-        return &PartyService{db: db}
+        transactions := NewTransactionService(db)
+        return &PartyService{
+                db:           db,
+                transactions: transactions,
+                invoices:     &InvoiceService{db: db, transactions: transactions},
+        }
+}
+
+// WithContext returns a copy of the service that runs its queries with ctx,
+// which carries the actor its changes are audited as
+func (s *PartyService) WithContext(ctx context.Context) *PartyService {
+        service := *s
+        service.db = s.db.WithContext(ctx)
+        return &service
 }
 
-// GetAllParties retrieves all parties for a user
-func (s *PartyService) GetAllParties(userID string) ([]models.Party, *apperrors.AppError) {
+// GetAllParties retrieves all parties for a user, with the archived ones
+// only if asked for
+func (s *PartyService) GetAllParties(userID string, includeArchived bool) ([]models.Party, *apperrors.AppError) {
         var parties []models.Party
-        if err := s.db.Where("user_id = ?", userID).Find(&parties).Error; err != nil {
+        if err := s.partyList(userID, includeArchived).Find(&parties).Error; err != nil {
                 return nil, apperrors.Internal("Failed to fetch parties", err)
         }
         return parties, nil
 }
 
 // GetPartiesByType retrieves parties of a specific type for a user
-func (s *PartyService) GetPartiesByType(userID, partyType string) ([]models.Party, *apperrors.AppError) {
+func (s *PartyService) GetPartiesByType(userID, partyType string, includeArchived bool) ([]models.Party, *apperrors.AppError) {
         var parties []models.Party
-        if err := s.db.Where("user_id = ? AND party_type = ?", userID, partyType).Find(&parties).Error; err != nil {
+        if err := s.partyList(userID, includeArchived).Where("party_type = ?", partyType).Find(&parties).Error; err != nil {
                 return nil, apperrors.Internal("Failed to fetch parties", err)
         }
         return parties, nil
 }
 
+// partyList starts a query for the parties of a user
+func (s *PartyService) partyList(userID string, includeArchived bool) *gorm.DB {
+        query := s.db.Where("user_id = ?", userID)
+        if !includeArchived {
+                query = query.Where("archived_at IS NULL")
+        }
+        return query
+}
+
 // GetPartyByID retrieves a single party
 func (s *PartyService) GetPartyByID(userID, partyID string) (*models.Party, *apperrors.AppError) {
         var party models.Party
@@ -49,48 +81,361 @@ func (s *PartyService) GetPartyByID(userID, partyID string) (*models.Party, *app
         return &party, nil
 }
 
-// CreateParty creates a new party
+// CreateParty creates a new party and records its opening balance as a
+// dated opening transaction
 func (s *PartyService) CreateParty(userID string, req *models.CreatePartyRequest) (*models.Party, *apperrors.AppError) {
         party := &models.Party{
-                UserID:    userID,
-                Name:      req.Name,
-                Phone:     &req.Phone,
-                Email:     &req.Email,
-                Address:   &req.Address,
-                PartyType: req.PartyType,
-                Balance:   req.Balance,
+                UserID:      userID,
+                Name:        req.Name,
+                Phone:       &req.Phone,
+                Email:       &req.Email,
+                Address:     &req.Address,
+                PartyType:   req.PartyType,
+                GSTIN:       &req.GSTIN,
+                StateCode:   &req.StateCode,
+                CreditLimit: req.CreditLimit,
+        }
+        if req.Language != "" {
+                party.Language = &req.Language
+        }
+
+        if req.StateCode != "" && !gst.ValidStateCode(req.StateCode) {
+                return nil, apperrors.BadRequest("Invalid GST state code " + req.StateCode)
         }
 
-        if err := s.db.Create(party).Error; err != nil {
+        // Parties default to the user's base currency
+        party.Currency = req.Currency
+        if party.Currency == "" {
+                var user models.User
+                if err := s.db.Select("id", "base_currency").Where("id = ?", userID).First(&user).Error; err != nil {
+                        return nil, apperrors.Internal("Database error", err)
+                }
+                party.Currency = user.BaseCurrency
+        }
+        if !money.IsSupported(party.Currency) {
+                return nil, apperrors.BadRequest("Unsupported currency " + party.Currency)
+        }
+
+        openingDate := req.OpeningDate
+        if openingDate == "" {
+                openingDate = time.Now().Format("2006-01-02")
+        }
+
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                if err := tx.Create(party).Error; err != nil {
+                        return err
+                }
+
+                opening := req.SignedOpeningBalance()
+                if opening != 0 {
+                        description := "Opening balance"
+                        if err := s.transactions.insertTransaction(tx, &models.Transaction{
+                                UserID:          userID,
+                                PartyID:         party.ID,
+                                Amount:          opening,
+                                Currency:        party.Currency,
+                                TransactionType: models.TransactionTypeOpening,
+                                Description:     &description,
+                                Date:            openingDate,
+                        }); err != nil {
+                                return err
+                        }
+                        if err := tx.First(party, "id = ?", party.ID).Error; err != nil {
+                                return err
+                        }
+                }
+
+                return recordAudit(tx, userID, models.AuditEntityParty, party.ID, models.AuditActionCreate, nil, party)
+        })
+
+        if err != nil {
                 return nil, apperrors.Internal("Failed to create party", err)
         }
         return party, nil
 }
 
-// UpdateParty updates an existing party
+// UpdateParty updates an existing party. The balance is derived from the
+// ledger and cannot be edited directly.
 func (s *PartyService) UpdateParty(userID, partyID string, req *models.UpdatePartyRequest) (*models.Party, *apperrors.AppError) {
-        // Verify ownership
-        if _, err := s.GetPartyByID(userID, partyID); err != nil {
-                return nil, err
+        if req.Balance != nil {
+                return nil, apperrors.BadRequest("Balance cannot be edited directly; record a transaction or edit the opening balance entry")
+        }
+        if req.StateCode != "" && !gst.ValidStateCode(req.StateCode) {
+                return nil, apperrors.BadRequest("Invalid GST state code " + req.StateCode)
         }
 
-        party := &models.Party{}
-        if result := s.db.Model(party).Where("id = ? AND user_id = ?", partyID, userID).Updates(req); result.Error != nil {
-                return nil, apperrors.Internal("Failed to update party", result.Error)
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                // Verify ownership
+                before, err := lockParty(tx, userID, partyID)
+                if err != nil {
+                        return err
+                }
+
+                if err := tx.Model(&models.Party{}).Where("id = ? AND user_id = ?", partyID, userID).Updates(req).Error; err != nil {
+                        return err
+                }
+                return auditPartyChange(tx, before, models.AuditActionUpdate)
+        })
+
+        if err != nil {
+                return nil, apperrors.FromError(err, "Failed to update party")
         }
+        return s.GetPartyByID(userID, partyID)
+}
+
+// DeleteParty moves a party to the trash. A party with a balance or linked
+// records is only deleted with cascade, which deals with every linked record
+// in the same DB transaction: open invoices are cancelled, which removes
+// their transactions, and the remaining transactions, reminders, recurring
+// transactions, reminder rules and interest terms are trashed with the
+// party. The journal entries are removed so the party drops out of the
+// books; allocations and reminder settlements are kept for a restore.
+func (s *PartyService) DeleteParty(userID, partyID string, cascade bool) *apperrors.AppError {
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                party, err := lockParty(tx, userID, partyID)
+                if err != nil {
+                        return err
+                }
 
+                if !cascade {
+                        if err := checkPartyUnlinked(tx, party); err != nil {
+                                return err
+                        }
+                }
+
+                // Trashing transactions is a delete that strict mode does not allow
+                var count int64
+                if err := tx.Model(&models.Transaction{}).Where("party_id = ?", partyID).Count(&count).Error; err != nil {
+                        return err
+                }
+                if count > 0 {
+                        if err := checkNotStrict(tx, userID); err != nil {
+                                return err
+                        }
+                }
+
+                if err := s.cancelPartyInvoices(tx, party); err != nil {
+                        return err
+                }
+                // Cancelling the invoices removed their transactions
+                var transactions []models.Transaction
+                if err := tx.Where("party_id = ?", partyID).Find(&transactions).Error; err != nil {
+                        return err
+                }
+                var reminders []models.Reminder
+                if err := tx.Where("party_id = ?", partyID).Find(&reminders).Error; err != nil {
+                        return err
+                }
+
+                for i := range transactions {
+                        if err := s.transactions.journal.removeTransaction(tx, transactions[i].ID); err != nil {
+                                return err
+                        }
+                        if err := recordAudit(tx, userID, models.AuditEntityTransaction, transactions[i].ID, models.AuditActionDelete, &transactions[i], nil); err != nil {
+                                return err
+                        }
+                        if err := chainTransaction(tx, &transactions[i], models.AuditActionDelete); err != nil {
+                                return err
+                        }
+                }
+                for i := range reminders {
+                        if err := recordAudit(tx, userID, models.AuditEntityReminder, reminders[i].ID, models.AuditActionDelete, &reminders[i], nil); err != nil {
+                                return err
+                        }
+                }
+                if err := recordAudit(tx, userID, models.AuditEntityParty, party.ID, models.AuditActionDelete, party, nil); err != nil {
+                        return err
+                }
+
+                // Everything trashed with the party shares its deletion time, which
+                // is how a restore tells it apart from items trashed earlier
+                deletedAt := time.Now().UTC().Truncate(time.Microsecond)
+                for _, model := range partyDependents {
+                        if err := tx.Model(model).Where("party_id = ?", partyID).Update("deleted_at", deletedAt).Error; err != nil {
+                                return err
+                        }
+                }
+                return tx.Model(&models.Party{}).Where("id = ?", partyID).Update("deleted_at", deletedAt).Error
+        })
+
+        if err != nil {
+                return apperrors.FromError(err, "Failed to delete party")
+        }
+        return nil
+}
+
+// RestoreParty brings a party back from the trash with the transactions,
+// reminders, recurring transactions, reminder rules and interest terms that
+// were trashed with it, and posts the transactions to the journal again
+func (s *PartyService) RestoreParty(userID, partyID string) (*models.Party, *apperrors.AppError) {
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                var party models.Party
+                err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+                        Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", partyID, userID).
+                        First(&party).Error
+                if err != nil {
+                        if errors.Is(err, gorm.ErrRecordNotFound) {
+                                return apperrors.NotFound("Party not found in trash")
+                        }
+                        return err
+                }
+                deletedAt := party.DeletedAt.Time
+
+                var transactions []models.Transaction
+                if err := tx.Unscoped().Where("party_id = ? AND deleted_at = ?", partyID, deletedAt).Order(ledgerOrder).Find(&transactions).Error; err != nil {
+                        return err
+                }
+                if err := tx.Unscoped().Model(&models.Transaction{}).Where("party_id = ? AND deleted_at = ?", partyID, deletedAt).Update("deleted_at", nil).Error; err != nil {
+                        return err
+                }
+                var reminders []models.Reminder
+                if err := tx.Unscoped().Where("party_id = ? AND deleted_at = ?", partyID, deletedAt).Find(&reminders).Error; err != nil {
+                        return err
+                }
+                if err := tx.Unscoped().Model(&models.Reminder{}).Where("party_id = ? AND deleted_at = ?", partyID, deletedAt).Update("deleted_at", nil).Error; err != nil {
+                        return err
+                }
+                for _, model := range partyDependents[2:] {
+                        if err := tx.Unscoped().Model(model).Where("party_id = ? AND deleted_at = ?", partyID, deletedAt).Update("deleted_at", nil).Error; err != nil {
+                                return err
+                        }
+                }
+                if err := tx.Unscoped().Model(&models.Party{}).Where("id = ?", partyID).Update("deleted_at", nil).Error; err != nil {
+                        return err
+                }
+
+                for i := range transactions {
+                        if err := s.transactions.journal.postTransaction(tx, &transactions[i]); err != nil {
+                                return err
+                        }
+                }
+                if err := s.transactions.rebalanceParty(tx, partyID); err != nil {
+                        return err
+                }
+
+                for i := range transactions {
+                        restored := transactions[i]
+                        if err := chainTransaction(tx, &restored, models.AuditActionRestore); err != nil {
+                                return err
+                        }
+                        if err := auditTransactionChange(tx, &transactions[i], models.AuditActionRestore); err != nil {
+                                return err
+                        }
+                }
+                for i := range reminders {
+                        if err := auditReminderChange(tx, &reminders[i], models.AuditActionRestore); err != nil {
+                                return err
+                        }
+                }
+                return auditPartyChange(tx, &party, models.AuditActionRestore)
+        })
+
+        if err != nil {
+                return nil, apperrors.FromError(err, "Failed to restore party")
+        }
         return s.GetPartyByID(userID, partyID)
 }
 
-// DeleteParty deletes a party
-func (s *PartyService) DeleteParty(userID, partyID string) *apperrors.AppError {
-        // Verify ownership
-        if _, err := s.GetPartyByID(userID, partyID); err != nil {
-                return err
+// ArchiveParty archives a party, or brings it back when archive is false.
+// Archiving keeps the ledger as it is and only hides the party from lists,
+// so it works whatever the balance.
+func (s *PartyService) ArchiveParty(userID, partyID string, archive bool) (*models.Party, *apperrors.AppError) {
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                before, err := lockParty(tx, userID, partyID)
+                if err != nil {
+                        return err
+                }
+                if (before.ArchivedAt != nil) == archive {
+                        return nil
+                }
+
+                var archivedAt *time.Time
+                if archive {
+                        now := time.Now()
+                        archivedAt = &now
+                }
+                if err := tx.Model(&models.Party{}).Where("id = ?", partyID).Update("archived_at", archivedAt).Error; err != nil {
+                        return err
+                }
+                return auditPartyChange(tx, before, models.AuditActionUpdate)
+        })
+
+        if err != nil {
+                return nil, apperrors.FromError(err, "Failed to archive party")
         }
+        return s.GetPartyByID(userID, partyID)
+}
+
+// partyLinks lists the kinds of record that refer to a party
+var partyLinks = []struct {
+        name  string
+        model interface{}
+}{
+        {"transactions", &models.Transaction{}},
+        {"reminders", &models.Reminder{}},
+        {"invoices", &models.Invoice{}},
+        {"recurring transactions", &models.RecurringTransaction{}},
+        {"reminder rules", &models.ReminderRule{}},
+        {"interest terms", &models.InterestTerms{}},
+}
 
-        if result := s.db.Where("id = ? AND user_id = ?", partyID, userID).Delete(&models.Party{}); result.Error != nil {
-                return apperrors.Internal("Failed to delete party", result.Error)
+// partyDependents lists the records that go to the trash with their party
+// and come back with it. Transactions and reminders come first; a restore
+// handles them itself.
+var partyDependents = []interface{}{
+        &models.Transaction{},
+        &models.Reminder{},
+        &models.RecurringTransaction{},
+        &models.ReminderRule{},
+        &models.InterestTerms{},
+}
+
+// cancelPartyInvoices cancels the open invoices of a party being deleted.
+// The caller must hold the party lock.
+func (s *PartyService) cancelPartyInvoices(tx *gorm.DB, party *models.Party) error {
+        var invoices []models.Invoice
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+                Where("party_id = ? AND status <> ?", party.ID, models.InvoiceStatusCancelled).
+                Order("created_at").Find(&invoices).Error; err != nil {
+                return err
+        }
+        for i := range invoices {
+                if err := s.invoices.cancelInvoice(tx, party, &invoices[i]); err != nil {
+                        return err
+                }
         }
         return nil
 }
+
+// checkPartyUnlinked rejects deleting a party that has a balance or records
+// referring to it. The caller must hold the party lock.
+func checkPartyUnlinked(tx *gorm.DB, party *models.Party) error {
+        var found []string
+        if party.Balance != 0 {
+                found = append(found, fmt.Sprintf("a balance of %s", party.Balance))
+        }
+        for _, link := range partyLinks {
+                var count int64
+                if err := tx.Model(link.model).Where("party_id = ?", party.ID).Count(&count).Error; err != nil {
+                        return err
+                }
+                if count > 0 {
+                        found = append(found, fmt.Sprintf("%d %s", count, link.name))
+                }
+        }
+        if len(found) == 0 {
+                return nil
+        }
+        return apperrors.Conflict(fmt.Sprintf("%s has %s; archive the party or delete it with cascade=true",
+                party.Name, strings.Join(found, ", ")))
+}
+
+// auditPartyChange records a change to a party in the audit log, reloading
+// the party as it is now
+func auditPartyChange(tx *gorm.DB, before *models.Party, action string) error {
+        var after models.Party
+        if err := tx.Unscoped().First(&after, "id = ?", before.ID).Error; err != nil {
+                return err
+        }
+        return recordAudit(tx, after.UserID, models.AuditEntityParty, after.ID, action, before, &after)
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+        "testing"
+
+        "khatabook-go-backend/internal/models"
+        "khatabook-go-backend/pkg/money"
+)
+
+func TestDeletePartyCascade(t *testing.T) {
+        db := openTestDB(t)
+        user := createTestUser(t, db)
+
+        parties := NewPartyService(db)
+        party, appErr := parties.CreateParty(user.ID, &models.CreatePartyRequest{
+                Name:      "Cascade customer",
+                PartyType: models.PartyTypeCustomer,
+        })
+        if appErr != nil {
+                t.Fatalf("create party: %v", appErr)
+        }
+
+        if _, appErr := NewTransactionService(db).CreateTransaction(user.ID, &models.CreateTransactionRequest{
+                PartyID:         party.ID,
+                Amount:          50000,
+                TransactionType: models.TransactionTypeCredit,
+        }); appErr != nil {
+                t.Fatalf("create transaction: %v", appErr)
+        }
+        invoices := NewInvoiceService(db)
+        posted, appErr := invoices.CreateInvoice(user.ID, &models.CreateInvoiceRequest{
+                PartyID:     party.ID,
+                InvoiceType: models.InvoiceTypeSale,
+                Items:       []models.InvoiceItemRequest{{ItemName: "Rice", Quantity: 1000, Rate: 100000, GSTRate: 500}},
+        })
+        if appErr != nil {
+                t.Fatalf("create invoice: %v", appErr)
+        }
+        if posted, appErr = invoices.PostInvoice(user.ID, posted.ID, &models.PostInvoiceRequest{}); appErr != nil {
+                t.Fatalf("post invoice: %v", appErr)
+        }
+        draft, appErr := invoices.CreateInvoice(user.ID, &models.CreateInvoiceRequest{
+                PartyID:     party.ID,
+                InvoiceType: models.InvoiceTypeSale,
+                Items:       []models.InvoiceItemRequest{{ItemName: "Dal", Quantity: 2000, Rate: 12000}},
+        })
+        if appErr != nil {
+                t.Fatalf("create draft invoice: %v", appErr)
+        }
+        recurring, appErr := NewRecurringService(db).CreateRecurring(user.ID, &models.CreateRecurringRequest{
+                PartyID:         party.ID,
+                Amount:          money.Amount(20000),
+                TransactionType: models.TransactionTypeCredit,
+                Frequency:       "monthly",
+        })
+        if appErr != nil {
+                t.Fatalf("create recurring transaction: %v", appErr)
+        }
+        rule, appErr := NewReminderRuleService(db).CreateRule(user.ID, &models.CreateReminderRuleRequest{
+                PartyID:     party.ID,
+                OverdueDays: 30,
+        })
+        if appErr != nil {
+                t.Fatalf("create reminder rule: %v", appErr)
+        }
+        terms, appErr := NewInterestService(db).SetTerms(user.ID, party.ID, &models.SetInterestTermsRequest{
+                Rate:   1800,
+                Method: "simple",
+        })
+        if appErr != nil {
+                t.Fatalf("set interest terms: %v", appErr)
+        }
+
+        if appErr := parties.DeleteParty(user.ID, party.ID, false); appErr == nil {
+                t.Fatal("party with linked records was deleted without cascade")
+        }
+        if appErr := parties.DeleteParty(user.ID, party.ID, true); appErr != nil {
+                t.Fatalf("delete party with cascade: %v", appErr)
+        }
+
+        for _, id := range []string{posted.ID, draft.ID} {
+                var invoice models.Invoice
+                if err := db.First(&invoice, "id = ?", id).Error; err != nil {
+                        t.Fatalf("load invoice: %v", err)
+                }
+                if invoice.Status != models.InvoiceStatusCancelled || invoice.TransactionID != nil {
+                        t.Errorf("invoice %s is %s with transaction %v, want cancelled without one", invoice.InvoiceNumber, invoice.Status, invoice.TransactionID)
+                }
+        }
+        var invoiceTransactions int64
+        if err := db.Unscoped().Model(&models.Transaction{}).Where("id = ?", *posted.TransactionID).Count(&invoiceTransactions).Error; err != nil {
+                t.Fatalf("count invoice transaction: %v", err)
+        }
+        if invoiceTransactions != 0 {
+                t.Error("transaction of the cancelled invoice still exists")
+        }
+
+        for _, dependent := range []struct {
+                name  string
+                model interface{}
+                id    string
+        }{
+                {"recurring transaction", &models.RecurringTransaction{}, recurring.ID},
+                {"reminder rule", &models.ReminderRule{}, rule.ID},
+                {"interest terms", &models.InterestTerms{}, terms.ID},
+        } {
+                var live, trashed int64
+                if err := db.Model(dependent.model).Where("id = ?", dependent.id).Count(&live).Error; err != nil {
+                        t.Fatalf("count %s: %v", dependent.name, err)
+                }
+                if err := db.Unscoped().Model(dependent.model).Where("id = ? AND deleted_at IS NOT NULL", dependent.id).Count(&trashed).Error; err != nil {
+                        t.Fatalf("count trashed %s: %v", dependent.name, err)
+                }
+                if live != 0 || trashed != 1 {
+                        t.Errorf("%s was not trashed with the party", dependent.name)
+                }
+        }
+
+        var live, trashed int64
+        if err := db.Model(&models.Transaction{}).Where("party_id = ?", party.ID).Count(&live).Error; err != nil {
+                t.Fatalf("count transactions: %v", err)
+        }
+        if err := db.Unscoped().Model(&models.Transaction{}).Where("party_id = ? AND deleted_at IS NOT NULL", party.ID).Count(&trashed).Error; err != nil {
+                t.Fatalf("count trashed transactions: %v", err)
+        }
+        if live != 0 || trashed != 1 {
+                t.Errorf("transactions: %d live and %d trashed, want 0 and 1", live, trashed)
+        }
+
+        if _, appErr := parties.RestoreParty(user.ID, party.ID); appErr != nil {
+                t.Fatalf("restore party: %v", appErr)
+        }
+        var template models.RecurringTransaction
+        if err := db.First(&template, "id = ?", recurring.ID).Error; err != nil {
+                t.Fatalf("recurring transaction was not restored: %v", err)
+        }
+        if !template.Active || template.NextDate == nil {
+                t.Error("restored recurring transaction is not scheduled")
+        }
+        if err := db.First(&models.ReminderRule{}, "id = ?", rule.ID).Error; err != nil {
+                t.Errorf("reminder rule was not restored: %v", err)
+        }
+        if err := db.First(&models.InterestTerms{}, "id = ?", terms.ID).Error; err != nil {
+                t.Errorf("interest terms were not restored: %v", err)
+        }
+}
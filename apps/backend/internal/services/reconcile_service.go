@@ -0,0 +1,147 @@
+package services
+
+import (
+        "khatabook-go-backend/internal/models"
+        apperrors "khatabook-go-backend/pkg/errors"
+        "khatabook-go-backend/pkg/money"
+
+        "gorm.io/gorm"
+)
+
+// ReconcileService checks stored balances against the transactions table
+type ReconcileService struct {
+        db           *gorm.DB
+        journal      *JournalService
+        transactions *TransactionService
+}
+
+// NewReconcileService creates a new reconcile service
+func NewReconcileService(db *gorm.DB) *ReconcileService {
+        return &ReconcileService{
+                db:           db,
+                journal:      NewJournalService(db),
+                transactions: NewTransactionService(db),
+        }
+}
+
+// PartyDrift describes how the stored figures of a party differ from the
+// figures recomputed from its transactions
+type PartyDrift struct {
+        PartyID              string       `json:"party_id"`
+        UserID               string       `json:"user_id"`
+        PartyName            string       `json:"party_name"`
+        StoredBalance        money.Amount `json:"stored_balance"`
+        PostedBalance        money.Amount `json:"posted_balance"`
+        ComputedBalance      money.Amount `json:"computed_balance"`
+        Drift                money.Amount `json:"drift"`
+        RunningBalanceErrors int          `json:"running_balance_errors"`
+        Repaired             bool         `json:"repaired"`
+}
+
+// ReconcileReport summarises a reconciliation run
+type ReconcileReport struct {
+        PartiesChecked int          `json:"parties_checked"`
+        PartiesDrifted int          `json:"parties_drifted"`
+        Repair         bool         `json:"repair"`
+        Drifts         []PartyDrift `json:"drifts"`
+}
+
+// Reconcile recomputes every party balance and running balance from the
+// transactions table and reports the parties that disagree. An empty userID
+// checks all users. With repair set, each drifted party is fixed inside its
+// own DB transaction.
+func (s *ReconcileService) Reconcile(userID string, repair bool) (*ReconcileReport, *apperrors.AppError) {
+        var parties []models.Party
+        query := s.db.Order("user_id, name")
+        if userID != "" {
+                query = query.Where("user_id = ?", userID)
+        }
+        if err := query.Find(&parties).Error; err != nil {
+                return nil, apperrors.Internal("Failed to fetch parties", err)
+        }
+
+        report := &ReconcileReport{Repair: repair, Drifts: []PartyDrift{}}
+        for i := range parties {
+                drift, err := s.checkParty(s.db, &parties[i])
+                if err != nil {
+                        return nil, apperrors.Internal("Failed to reconcile party "+parties[i].ID, err)
+                }
+                report.PartiesChecked++
+                if drift == nil {
+                        continue
+                }
+
+                if repair {
+                        if err := s.db.Transaction(func(tx *gorm.DB) error {
+                                return s.repairParty(tx, &parties[i])
+                        }); err != nil {
+                                return nil, apperrors.Internal("Failed to repair party "+parties[i].ID, err)
+                        }
+                        drift.Repaired = true
+                }
+
+                report.PartiesDrifted++
+                report.Drifts = append(report.Drifts, *drift)
+        }
+
+        return report, nil
+}
+
+// checkParty returns the drift of a party, or nil when its stored balance,
+// journal balance and running balances all match its transactions
+func (s *ReconcileService) checkParty(tx *gorm.DB, party *models.Party) (*PartyDrift, error) {
+        transactions, err := s.transactions.partyLedger(tx, party.ID)
+        if err != nil {
+                return nil, err
+        }
+        posted, err := s.journal.partyBalance(tx, party.ID)
+        if err != nil {
+                return nil, err
+        }
+
+        balances, computed := runningBalances(transactions)
+        runningErrors := 0
+        for i := range transactions {
+                if transactions[i].RunningBalance != balances[i] {
+                        runningErrors++
+                }
+        }
+
+        if party.Balance == computed && posted == computed && runningErrors == 0 {
+                return nil, nil
+        }
+
+        return &PartyDrift{
+                PartyID:              party.ID,
+                UserID:               party.UserID,
+                PartyName:            party.Name,
+                StoredBalance:        party.Balance,
+                PostedBalance:        posted,
+                ComputedBalance:      computed,
+                Drift:                party.Balance - computed,
+                RunningBalanceErrors: runningErrors,
+        }, nil
+}
+
+// repairParty re-posts the journal entries of every transaction of the
+// party and rebuilds its balance and running balances
+func (s *ReconcileService) repairParty(tx *gorm.DB, party *models.Party) error {
+        if _, err := lockParty(tx, party.UserID, party.ID); err != nil {
+                return err
+        }
+
+        var transactions []models.Transaction
+        if err := tx.Where("party_id = ?", party.ID).Find(&transactions).Error; err != nil {
+                return err
+        }
+        for i := range transactions {
+                if err := s.journal.removeTransaction(tx, transactions[i].ID); err != nil {
+                        return err
+                }
+                if err := s.journal.postTransaction(tx, &transactions[i]); err != nil {
+                        return err
+                }
+        }
+
+        return s.transactions.rebalanceParty(tx, party.ID)
+}
@@ -0,0 +1,339 @@
+package services
+
+import (
+        "errors"
+        "net/http"
+        "time"
+
+        "khatabook-go-backend/internal/models"
+        apperrors "khatabook-go-backend/pkg/errors"
+        "khatabook-go-backend/pkg/logger"
+        "khatabook-go-backend/pkg/schedule"
+
+        "gorm.io/gorm"
+        "gorm.io/gorm/clause"
+)
+
+// maxCatchUp bounds the occurrences one template materialises per run, so a
+// long outage is caught up over several runs instead of one huge DB
+// transaction
+const maxCatchUp = 100
+
+// RecurringService manages recurring transaction templates and
+// materialises their due occurrences
+type RecurringService struct {
+        db           *gorm.DB
+        transactions *TransactionService
+}
+
+// NewRecurringService creates a new recurring transaction service
+func NewRecurringService(db *gorm.DB) *RecurringService {
+        return &RecurringService{db: db, transactions: NewTransactionService(db)}
+}
+
+// GetRecurring retrieves the user's recurring transactions
+func (s *RecurringService) GetRecurring(userID, partyID string) ([]models.RecurringTransaction, *apperrors.AppError) {
+        var templates []models.RecurringTransaction
+        query := s.db.Where("user_id = ?", userID)
+        if partyID != "" {
+                query = query.Where("party_id = ?", partyID)
+        }
+        if err := query.Order("created_at DESC").Find(&templates).Error; err != nil {
+                return nil, apperrors.Internal("Failed to fetch recurring transactions", err)
+        }
+        return templates, nil
+}
+
+// GetRecurringByID retrieves a single recurring transaction
+func (s *RecurringService) GetRecurringByID(userID, recurringID string) (*models.RecurringTransaction, *apperrors.AppError) {
+        var template models.RecurringTransaction
+        if err := s.db.Where("id = ? AND user_id = ?", recurringID, userID).First(&template).Error; err != nil {
+                if errors.Is(err, gorm.ErrRecordNotFound) {
+                        return nil, apperrors.NotFound("Recurring transaction not found")
+                }
+                return nil, apperrors.Internal("Database error", err)
+        }
+        return &template, nil
+}
+
+// CreateRecurring creates a recurring transaction template
+func (s *RecurringService) CreateRecurring(userID string, req *models.CreateRecurringRequest) (*models.RecurringTransaction, *apperrors.AppError) {
+        if _, err := loadParty(s.db, userID, req.PartyID); err != nil {
+                return nil, apperrors.FromError(err, "Database error")
+        }
+
+        template := &models.RecurringTransaction{
+                UserID:          userID,
+                PartyID:         req.PartyID,
+                Amount:          req.Amount,
+                TransactionType: req.TransactionType,
+                Description:     &req.Description,
+                Category:        &req.Category,
+                Frequency:       req.Frequency,
+                Interval:        req.Interval,
+                DayOfMonth:      req.DayOfMonth,
+                StartDate:       req.StartDate,
+                Active:          true,
+        }
+        if template.Interval == 0 {
+                template.Interval = 1
+        }
+        if template.StartDate == "" {
+                template.StartDate = time.Now().Format("2006-01-02")
+        }
+        if req.EndDate != "" {
+                template.EndDate = &req.EndDate
+        }
+
+        if appErr := scheduleNext(template, template.StartDate); appErr != nil {
+                return nil, appErr
+        }
+
+        if err := s.db.Create(template).Error; err != nil {
+                return nil, apperrors.Internal("Failed to create recurring transaction", err)
+        }
+        return template, nil
+}
+
+// UpdateRecurring updates a recurring transaction. A changed rule or end
+// date reschedules from the next pending occurrence. The template is locked
+// like a scheduler run locks it, so neither overwrites the other's next date.
+func (s *RecurringService) UpdateRecurring(userID, recurringID string, req *models.UpdateRecurringRequest) (*models.RecurringTransaction, *apperrors.AppError) {
+        var template models.RecurringTransaction
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+                        Where("id = ? AND user_id = ?", recurringID, userID).
+                        First(&template).Error
+                if err != nil {
+                        if errors.Is(err, gorm.ErrRecordNotFound) {
+                                return apperrors.NotFound("Recurring transaction not found")
+                        }
+                        return err
+                }
+
+                columns := []string{}
+                if req.Amount != nil {
+                        template.Amount = *req.Amount
+                        columns = append(columns, "amount")
+                }
+                if req.Description != "" {
+                        template.Description = &req.Description
+                        columns = append(columns, "description")
+                }
+                if req.Category != "" {
+                        template.Category = &req.Category
+                        columns = append(columns, "category")
+                }
+                if req.Active != nil {
+                        template.Active = *req.Active
+                        columns = append(columns, "active")
+                }
+
+                // A template that was switched off without a next date is
+                // scheduled again when switched back on
+                reschedule := template.Active && template.NextDate == nil && req.Active != nil
+                if req.Frequency != "" {
+                        template.Frequency = req.Frequency
+                        reschedule = true
+                }
+                if req.Interval != 0 {
+                        template.Interval = req.Interval
+                        reschedule = true
+                }
+                if req.DayOfMonth != nil {
+                        template.DayOfMonth = *req.DayOfMonth
+                        reschedule = true
+                }
+                if req.EndDate != nil {
+                        template.EndDate = req.EndDate
+                        if *req.EndDate == "" {
+                                template.EndDate = nil
+                        }
+                        reschedule = true
+                }
+
+                if reschedule {
+                        from := time.Now().Format("2006-01-02")
+                        if template.NextDate != nil && *template.NextDate < from {
+                                from = *template.NextDate
+                        }
+                        if from < template.StartDate {
+                                from = template.StartDate
+                        }
+                        if appErr := scheduleNext(&template, from); appErr != nil {
+                                return appErr
+                        }
+                        columns = append(columns, "frequency", "interval", "day_of_month", "end_date", "next_date")
+                }
+
+                if len(columns) == 0 {
+                        return nil
+                }
+                return tx.Model(&template).Select(append(columns, "updated_at")).Updates(&template).Error
+        })
+        if err != nil {
+                return nil, apperrors.FromError(err, "Failed to update recurring transaction")
+        }
+        return &template, nil
+}
+
+// DeleteRecurring deletes a recurring transaction. Transactions it already
+// created stay on the ledger.
+func (s *RecurringService) DeleteRecurring(userID, recurringID string) *apperrors.AppError {
+        if _, appErr := s.GetRecurringByID(userID, recurringID); appErr != nil {
+                return appErr
+        }
+
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                if err := tx.Where("recurring_id = ?", recurringID).Delete(&models.RecurringOccurrence{}).Error; err != nil {
+                        return err
+                }
+                return tx.Unscoped().Where("id = ?", recurringID).Delete(&models.RecurringTransaction{}).Error
+        })
+        if err != nil {
+                return apperrors.Internal("Failed to delete recurring transaction", err)
+        }
+        return nil
+}
+
+// GetOccurrences retrieves the occurrences a recurring transaction has
+// materialised
+func (s *RecurringService) GetOccurrences(userID, recurringID string) ([]models.RecurringOccurrence, *apperrors.AppError) {
+        if _, appErr := s.GetRecurringByID(userID, recurringID); appErr != nil {
+                return nil, appErr
+        }
+
+        var occurrences []models.RecurringOccurrence
+        if err := s.db.Where("recurring_id = ?", recurringID).Order("date DESC").Find(&occurrences).Error; err != nil {
+                return nil, apperrors.Internal("Failed to fetch occurrences", err)
+        }
+        return occurrences, nil
+}
+
+// RunDue materialises every occurrence due on or before today and returns
+// how many transactions it created. Each template is handled in its own DB
+// transaction under a row lock, and the occurrence record, the transaction
+// and the advanced next date commit together, so an occurrence is created
+// exactly once even when runs overlap or the process restarts mid-run.
+func (s *RecurringService) RunDue(today string) (int, error) {
+        var ids []string
+        if err := s.db.Model(&models.RecurringTransaction{}).
+                Where("active = ? AND next_date IS NOT NULL AND next_date <= ?", true, today).
+                Pluck("id", &ids).Error; err != nil {
+                return 0, err
+        }
+
+        created := 0
+        for _, id := range ids {
+                n, err := s.runTemplate(id, today)
+                created += n
+                if err != nil {
+                        logger.Errorf("Recurring transaction %s failed: %v", id, err)
+                }
+        }
+        return created, nil
+}
+
+// runTemplate materialises the due occurrences of one template
+func (s *RecurringService) runTemplate(recurringID, today string) (int, error) {
+        created := 0
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                created = 0
+
+                // Another worker holding the row is already running this template
+                var template models.RecurringTransaction
+                err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+                        Where("id = ? AND active = ? AND next_date IS NOT NULL AND next_date <= ?", recurringID, true, today).
+                        First(&template).Error
+                if errors.Is(err, gorm.ErrRecordNotFound) {
+                        return nil
+                }
+                if err != nil {
+                        return err
+                }
+
+                for template.NextDate != nil && *template.NextDate <= today && created < maxCatchUp {
+                        date := *template.NextDate
+                        transaction, err := s.transactions.createTransaction(tx, template.UserID, &models.CreateTransactionRequest{
+                                PartyID:         template.PartyID,
+                                Amount:          template.Amount,
+                                TransactionType: template.TransactionType,
+                                Description:     stringValue(template.Description),
+                                Date:            date,
+                                Category:        stringValue(template.Category),
+                        })
+                        if err != nil {
+                                var appErr *apperrors.AppError
+                                if errors.As(err, &appErr) && appErr.Code == http.StatusNotFound {
+                                        // The party is gone, so the template can never run again
+                                        template.Active = false
+                                        break
+                                }
+                                if errors.As(err, &appErr) && appErr.Code == http.StatusUnprocessableEntity {
+                                        // The credit policy refuses the charge; hold this and later
+                                        // occurrences until the balance allows them
+                                        if template.CreditHold == nil || *template.CreditHold != appErr.Message {
+                                                logger.Warnf("Recurring transaction %s held on %s: %s", template.ID, date, appErr.Message)
+                                        }
+                                        template.CreditHold = &appErr.Message
+                                        break
+                                }
+                                return err
+                        }
+                        template.CreditHold = nil
+                        for _, warning := range transaction.Warnings {
+                                logger.Warnf("Recurring transaction %s: %s", template.ID, warning)
+                        }
+
+                        if err := tx.Create(&models.RecurringOccurrence{
+                                RecurringID:   template.ID,
+                                Date:          date,
+                                TransactionID: transaction.ID,
+                        }).Error; err != nil {
+                                return err
+                        }
+                        created++
+
+                        rule := schedule.Rule{Frequency: template.Frequency, Interval: template.Interval, DayOfMonth: template.DayOfMonth}
+                        next, err := rule.Next(date, template.StartDate)
+                        if err != nil {
+                                return err
+                        }
+                        template.NextDate = &next
+                        if template.EndDate != nil && next > *template.EndDate {
+                                template.NextDate = nil
+                        }
+                }
+
+                return tx.Model(&template).Select("next_date", "active", "credit_hold").Updates(&template).Error
+        })
+        if err != nil {
+                return 0, err
+        }
+        return created, nil
+}
+
+// scheduleNext validates the template's rule and sets its next date to the
+// first occurrence on or after from
+func scheduleNext(template *models.RecurringTransaction, from string) *apperrors.AppError {
+        rule := schedule.Rule{Frequency: template.Frequency, Interval: template.Interval, DayOfMonth: template.DayOfMonth}
+        if err := rule.Validate(); err != nil {
+                return apperrors.BadRequest(err.Error())
+        }
+        if _, err := time.Parse("2006-01-02", template.StartDate); err != nil {
+                return apperrors.BadRequest("start_date must be a date in YYYY-MM-DD format")
+        }
+        if template.EndDate != nil && *template.EndDate < template.StartDate {
+                return apperrors.BadRequest("end_date must not be before start_date")
+        }
+
+        next, err := rule.First(from, template.StartDate)
+        if err != nil {
+                return apperrors.BadRequest(err.Error())
+        }
+        template.NextDate = &next
+        if template.EndDate != nil && next > *template.EndDate {
+                template.NextDate = nil
+        }
+        return nil
+}
@@ -0,0 +1,281 @@
+package services
+
+import (
+        "context"
+        "errors"
+        "fmt"
+        "time"
+
+        "khatabook-go-backend/internal/models"
+        apperrors "khatabook-go-backend/pkg/errors"
+        "khatabook-go-backend/pkg/notify"
+
+        "gorm.io/gorm"
+        "gorm.io/gorm/clause"
+)
+
+const (
+        // maxDeliveryAttempts is how often a reminder is tried before it is
+        // marked as failed
+        maxDeliveryAttempts = 6
+
+        // retryBackoff is the wait after the first failed attempt; it doubles
+        // with every further failure up to maxRetryBackoff
+        retryBackoff    = time.Minute
+        maxRetryBackoff = 6 * time.Hour
+
+        // deliveryLease is how long a claimed reminder is hidden from other
+        // dispatchers while it is being sent
+        deliveryLease = 5 * time.Minute
+
+        deliveryTimeout = 30 * time.Second
+        dispatchBatch   = 50
+)
+
+// deliverableStatuses are the statuses of reminders waiting to be sent
+var deliverableStatuses = []string{models.ReminderStatusPending, models.ReminderStatusSnoozed}
+
+// ReminderDispatcher delivers due reminders through the configured
+// notification channels
+type ReminderDispatcher struct {
+        db             *gorm.DB
+        notifiers      map[string]notify.Notifier
+        defaultChannel string
+}
+
+// NewReminderDispatcher creates a dispatcher. Reminders without a channel
+// are sent on defaultChannel.
+func NewReminderDispatcher(db *gorm.DB, notifiers map[string]notify.Notifier, defaultChannel string) *ReminderDispatcher {
+        return &ReminderDispatcher{db: db, notifiers: notifiers, defaultChannel: defaultChannel}
+}
+
+// DispatchDue delivers every pending or snoozed reminder that is due and not
+// waiting for a retry, returning how many were sent
+func (d *ReminderDispatcher) DispatchDue(ctx context.Context, now time.Time) (int, error) {
+        sent := 0
+        for ctx.Err() == nil {
+                reminders, err := d.claim(now)
+                if err != nil {
+                        return sent, err
+                }
+                if len(reminders) == 0 {
+                        break
+                }
+
+                for i := range reminders {
+                        ok, err := d.deliver(ctx, &reminders[i], now)
+                        if err != nil {
+                                return sent, err
+                        }
+                        if ok {
+                                sent++
+                        }
+                }
+        }
+        return sent, nil
+}
+
+// Deliver sends a pending or snoozed reminder straight away, starting its
+// retries over if earlier attempts failed. It takes the same lease as claim,
+// so a reminder that a dispatcher is sending is not sent twice.
+func (d *ReminderDispatcher) Deliver(ctx context.Context, userID, reminderID string) (*models.Reminder, *apperrors.AppError) {
+        now := time.Now()
+        var reminder *models.Reminder
+        err := d.db.Transaction(func(tx *gorm.DB) error {
+                var err error
+                reminder, err = lockReminder(tx, userID, reminderID)
+                if err != nil {
+                        return err
+                }
+                if reminder.Status != models.ReminderStatusPending && reminder.Status != models.ReminderStatusSnoozed {
+                        return apperrors.Conflict("Only pending or snoozed reminders can be sent")
+                }
+                if reminder.LeasedUntil != nil && reminder.LeasedUntil.After(now) {
+                        return apperrors.Conflict("Reminder is already being sent")
+                }
+
+                leasedUntil := now.Add(deliveryLease)
+                reminder.Attempts = 0
+                reminder.DeliveryFailed = false
+                reminder.LeasedUntil = &leasedUntil
+                return tx.Model(reminder).Select("attempts", "delivery_failed", "leased_until").Updates(reminder).Error
+        })
+        if err != nil {
+                return nil, apperrors.FromError(err, "Failed to send reminder")
+        }
+
+        if _, err := d.deliver(ctx, reminder, now); err != nil {
+                return nil, apperrors.Internal("Failed to record reminder delivery", err)
+        }
+        return reminder, nil
+}
+
+// claim picks a batch of due reminders and leases them so that concurrent
+// dispatchers skip them
+func (d *ReminderDispatcher) claim(now time.Time) ([]models.Reminder, error) {
+        var reminders []models.Reminder
+        err := d.db.Transaction(func(tx *gorm.DB) error {
+                err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+                        Where("status IN ? AND delivery_failed = ? AND due_date <= ?", deliverableStatuses, false, now.Format("2006-01-02")).
+                        Where("next_attempt_at IS NULL OR next_attempt_at <= ?", now).
+                        Where("leased_until IS NULL OR leased_until <= ?", now).
+                        Order("due_date, created_at").
+                        Limit(dispatchBatch).
+                        Find(&reminders).Error
+                if err != nil || len(reminders) == 0 {
+                        return err
+                }
+
+                ids := make([]string, len(reminders))
+                for i, reminder := range reminders {
+                        ids[i] = reminder.ID
+                }
+                return tx.Model(&models.Reminder{}).Where("id IN ?", ids).
+                        Update("leased_until", now.Add(deliveryLease)).Error
+        })
+        return reminders, err
+}
+
+// deliver sends a reminder once and records the attempt. It reports whether
+// the reminder was sent; the error is only for failures to record.
+func (d *ReminderDispatcher) deliver(ctx context.Context, reminder *models.Reminder, now time.Time) (bool, error) {
+        channel := d.defaultChannel
+        if reminder.Channel != nil && *reminder.Channel != "" {
+                channel = *reminder.Channel
+        }
+
+        recipient, providerID, sendErr := d.send(ctx, reminder, channel)
+        before := *reminder
+        fromStatus := reminder.Status
+
+        reminder.Attempts++
+        delivery := &models.ReminderDelivery{
+                ReminderID: reminder.ID,
+                UserID:     reminder.UserID,
+                Attempt:    reminder.Attempts,
+                Channel:    channel,
+                Recipient:  recipient,
+                Status:     models.ReminderStatusSent,
+        }
+        if providerID != "" {
+                delivery.ProviderID = &providerID
+        }
+
+        reminder.NextAttemptAt = nil
+        reminder.LeasedUntil = nil
+        if sendErr == nil {
+                reminder.Status = models.ReminderStatusSent
+                reminder.SentAt = &now
+                reminder.LastError = nil
+        } else {
+                message := sendErr.Error()
+                delivery.Status = "failed"
+                delivery.Error = &message
+                reminder.LastError = &message
+                if notify.IsPermanent(sendErr) || reminder.Attempts >= maxDeliveryAttempts {
+                        reminder.DeliveryFailed = true
+                } else {
+                        retryAt := now.Add(backoff(reminder.Attempts))
+                        reminder.NextAttemptAt = &retryAt
+                }
+        }
+
+        // The attempt is recorded even if ctx is cancelled meanwhile, so a
+        // sent message is not sent again; ctx still names the audit actor
+        err := d.db.WithContext(context.WithoutCancel(ctx)).Transaction(func(tx *gorm.DB) error {
+                if err := tx.Create(delivery).Error; err != nil {
+                        return err
+                }
+                // The reminder may have been completed while it was being sent;
+                // the lease is released either way
+                result := tx.Model(reminder).Where("status IN ?", deliverableStatuses).
+                        Select("status", "attempts", "next_attempt_at", "leased_until", "last_error", "delivery_failed", "sent_at").
+                        Updates(reminder)
+                if result.Error != nil {
+                        return result.Error
+                }
+                if result.RowsAffected == 0 {
+                        return tx.Model(reminder).Update("leased_until", nil).Error
+                }
+                if err := auditReminderChange(tx, &before, models.AuditActionUpdate); err != nil {
+                        return err
+                }
+                if sendErr != nil {
+                        return nil
+                }
+                return recordReminderEvent(tx, reminder, fromStatus, reminder.DueDate, models.ReminderActorSystem, "Sent by "+channel)
+        })
+        return sendErr == nil, err
+}
+
+// send builds the message for a reminder and sends it on channel, returning
+// the recipient and the provider's message ID
+func (d *ReminderDispatcher) send(ctx context.Context, reminder *models.Reminder, channel string) (string, string, error) {
+        notifier, ok := d.notifiers[channel]
+        if !ok {
+                return "", "", notify.Permanent(fmt.Errorf("channel %s is not configured", channel))
+        }
+
+        var party models.Party
+        if err := d.db.Where("id = ? AND user_id = ?", reminder.PartyID, reminder.UserID).First(&party).Error; err != nil {
+                if errors.Is(err, gorm.ErrRecordNotFound) {
+                        return "", "", notify.Permanent(errors.New("party not found"))
+                }
+                return "", "", err
+        }
+        var user models.User
+        if err := d.db.Select("id", "name", "language").Where("id = ?", reminder.UserID).First(&user).Error; err != nil {
+                return "", "", err
+        }
+
+        recipient := ""
+        switch channel {
+        case notify.Email:
+                if party.Email != nil {
+                        recipient = *party.Email
+                }
+        default:
+                if party.Phone != nil {
+                        recipient = *party.Phone
+                }
+        }
+        if recipient == "" {
+                return "", "", notify.Permanent(fmt.Errorf("party has no contact for %s", channel))
+        }
+
+        rendered, err := renderReminder(d.db, &reminderText{
+                party:      &party,
+                user:       &user,
+                templateID: reminder.TemplateID,
+                message:    stringValue(reminder.Message),
+                amount:     reminder.Outstanding(),
+                dueDate:    reminder.DueDate,
+        })
+        if err != nil {
+                return "", "", err
+        }
+        msg := notify.Message{
+                To:       recipient,
+                Subject:  rendered.Subject,
+                Body:     rendered.Body,
+                Language: rendered.Language,
+                Params:   rendered.Params(),
+        }
+
+        ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+        defer cancel()
+        providerID, err := notifier.Send(ctx, msg)
+        return recipient, providerID, err
+}
+
+// backoff returns the wait before the attempt after the given failed one
+func backoff(attempt int) time.Duration {
+        wait := retryBackoff
+        for i := 1; i < attempt && wait < maxRetryBackoff; i++ {
+                wait *= 2
+        }
+        if wait > maxRetryBackoff {
+                wait = maxRetryBackoff
+        }
+        return wait
+}
@@ -0,0 +1,359 @@
+package services
+
+import (
+        "errors"
+        "log"
+        "time"
+
+        "khatabook-go-backend/internal/models"
+        apperrors "khatabook-go-backend/pkg/errors"
+        "khatabook-go-backend/pkg/money"
+
+        "gorm.io/gorm"
+        "gorm.io/gorm/clause"
+)
+
+// ReminderRuleService manages reminder rules and creates the reminders they
+// call for
+type ReminderRuleService struct {
+        db *gorm.DB
+}
+
+// NewReminderRuleService creates a new reminder rule service
+func NewReminderRuleService(db *gorm.DB) *ReminderRuleService {
+        return &ReminderRuleService{db: db}
+}
+
+// GetRules retrieves the user's reminder rules
+func (s *ReminderRuleService) GetRules(userID string) ([]models.ReminderRule, *apperrors.AppError) {
+        var rules []models.ReminderRule
+        if err := s.db.Where("user_id = ?", userID).Order("party_id NULLS FIRST, created_at").Find(&rules).Error; err != nil {
+                return nil, apperrors.Internal("Failed to fetch reminder rules", err)
+        }
+        return rules, nil
+}
+
+// GetRuleByID retrieves a single reminder rule
+func (s *ReminderRuleService) GetRuleByID(userID, ruleID string) (*models.ReminderRule, *apperrors.AppError) {
+        var rule models.ReminderRule
+        if err := s.db.Where("id = ? AND user_id = ?", ruleID, userID).First(&rule).Error; err != nil {
+                if errors.Is(err, gorm.ErrRecordNotFound) {
+                        return nil, apperrors.NotFound("Reminder rule not found")
+                }
+                return nil, apperrors.Internal("Database error", err)
+        }
+        return &rule, nil
+}
+
+// CreateRule creates a reminder rule for the user or one of their parties.
+// There can be one rule per party and one user-wide rule.
+func (s *ReminderRuleService) CreateRule(userID string, req *models.CreateReminderRuleRequest) (*models.ReminderRule, *apperrors.AppError) {
+        rule := &models.ReminderRule{
+                UserID:      userID,
+                OverdueDays: req.OverdueDays,
+                MinAmount:   req.MinAmount,
+                RepeatDays:  7,
+                Active:      true,
+        }
+        if req.RepeatDays != nil {
+                rule.RepeatDays = *req.RepeatDays
+        }
+        if req.Channel != "" {
+                rule.Channel = &req.Channel
+        }
+        if req.Message != "" {
+                rule.Message = &req.Message
+        }
+        if req.TemplateID != "" {
+                if err := checkTemplateID(s.db, userID, req.TemplateID); err != nil {
+                        return nil, apperrors.FromError(err, "Database error")
+                }
+                rule.TemplateID = &req.TemplateID
+        }
+
+        // The party or user row is locked so concurrent creates cannot both
+        // pass the check; partial unique indexes back this up
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                query := tx.Model(&models.ReminderRule{}).Where("user_id = ?", userID)
+                if req.PartyID != "" {
+                        if _, err := lockParty(tx, userID, req.PartyID); err != nil {
+                                return err
+                        }
+                        rule.PartyID = &req.PartyID
+                        query = query.Where("party_id = ?", req.PartyID)
+                } else {
+                        var user models.User
+                        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+                                Where("id = ?", userID).First(&user).Error; err != nil {
+                                return err
+                        }
+                        query = query.Where("party_id IS NULL")
+                }
+
+                var count int64
+                if err := query.Count(&count).Error; err != nil {
+                        return err
+                }
+                if count > 0 {
+                        if rule.PartyID != nil {
+                                return apperrors.Conflict("The party already has a reminder rule")
+                        }
+                        return apperrors.Conflict("A reminder rule for all parties already exists")
+                }
+                return tx.Create(rule).Error
+        })
+        if err != nil {
+                return nil, apperrors.FromError(err, "Failed to create reminder rule")
+        }
+        return rule, nil
+}
+
+// UpdateRule updates a reminder rule
+func (s *ReminderRuleService) UpdateRule(userID, ruleID string, req *models.UpdateReminderRuleRequest) (*models.ReminderRule, *apperrors.AppError) {
+        if _, err := s.GetRuleByID(userID, ruleID); err != nil {
+                return nil, err
+        }
+
+        updates := map[string]interface{}{}
+        if req.OverdueDays != nil {
+                updates["overdue_days"] = *req.OverdueDays
+        }
+        if req.MinAmount != nil {
+                updates["min_amount"] = *req.MinAmount
+        }
+        if req.RepeatDays != nil {
+                updates["repeat_days"] = *req.RepeatDays
+        }
+        if req.Channel != nil {
+                updates["channel"] = nullIfEmpty(*req.Channel)
+        }
+        if req.Message != nil {
+                updates["message"] = nullIfEmpty(*req.Message)
+        }
+        if req.TemplateID != nil {
+                if *req.TemplateID != "" {
+                        if err := checkTemplateID(s.db, userID, *req.TemplateID); err != nil {
+                                return nil, apperrors.FromError(err, "Database error")
+                        }
+                }
+                updates["template_id"] = nullIfEmpty(*req.TemplateID)
+        }
+        if req.Active != nil {
+                updates["active"] = *req.Active
+        }
+
+        if len(updates) > 0 {
+                if err := s.db.Model(&models.ReminderRule{}).Where("id = ? AND user_id = ?", ruleID, userID).Updates(updates).Error; err != nil {
+                        return nil, apperrors.Internal("Failed to update reminder rule", err)
+                }
+        }
+        return s.GetRuleByID(userID, ruleID)
+}
+
+// DeleteRule deletes a reminder rule. Reminders it created are kept.
+func (s *ReminderRuleService) DeleteRule(userID, ruleID string) *apperrors.AppError {
+        result := s.db.Unscoped().Where("id = ? AND user_id = ?", ruleID, userID).Delete(&models.ReminderRule{})
+        if result.Error != nil {
+                return apperrors.Internal("Failed to delete reminder rule", result.Error)
+        }
+        if result.RowsAffected == 0 {
+                return apperrors.NotFound("Reminder rule not found")
+        }
+        return nil
+}
+
+// RunRules evaluates every active rule as of today and creates a reminder
+// for each party with an overdue balance, returning how many were created.
+// A party that already has an open reminder is skipped, so running it again
+// creates nothing new. The minimum amount of a user-wide rule is in the
+// user's base currency, so balances are converted at today's rate before
+// they are compared with it.
+func (s *ReminderRuleService) RunRules(today string) (int, error) {
+        var rules []models.ReminderRule
+        if err := s.db.Where("active = ?", true).Order("user_id").Find(&rules).Error; err != nil {
+                return 0, err
+        }
+        if len(rules) == 0 {
+                return 0, nil
+        }
+
+        // A party's own rule takes precedence over its user's rule
+        userRules := map[string]*models.ReminderRule{}
+        partyRules := map[string]*models.ReminderRule{}
+        for i := range rules {
+                if rules[i].PartyID == nil {
+                        userRules[rules[i].UserID] = &rules[i]
+                } else {
+                        partyRules[*rules[i].PartyID] = &rules[i]
+                }
+        }
+
+        // Inactive party rules still shield their party from the user's rule
+        var disabled []string
+        if err := s.db.Model(&models.ReminderRule{}).Where("active = ? AND party_id IS NOT NULL", false).Pluck("party_id", &disabled).Error; err != nil {
+                return 0, err
+        }
+        skip := map[string]bool{}
+        for _, partyID := range disabled {
+                skip[partyID] = true
+        }
+
+        userIDs := make([]string, 0, len(userRules))
+        for userID := range userRules {
+                userIDs = append(userIDs, userID)
+        }
+        partyIDs := make([]string, 0, len(partyRules))
+        for partyID := range partyRules {
+                partyIDs = append(partyIDs, partyID)
+        }
+
+        var parties []models.Party
+        err := s.db.Select("id", "user_id").
+                Where("balance > 0").
+                Where(s.db.Where("user_id IN ?", userIDs).Or("id IN ?", partyIDs)).
+                Order("user_id, id").
+                Find(&parties).Error
+        if err != nil {
+                return 0, err
+        }
+
+        fx := NewFXService(s.db)
+        userRates := map[string]*RateTable{}
+        created := 0
+        for _, party := range parties {
+                rule := partyRules[party.ID]
+                var rates *RateTable
+                if rule == nil && !skip[party.ID] {
+                        rule = userRules[party.UserID]
+                        if rule != nil && userRates[party.UserID] == nil {
+                                table, appErr := fx.LoadRates(party.UserID)
+                                if appErr != nil {
+                                        return created, appErr
+                                }
+                                userRates[party.UserID] = table
+                        }
+                        rates = userRates[party.UserID]
+                }
+                if rule == nil {
+                        continue
+                }
+
+                ok, err := s.applyRule(rule, rates, party.ID, today)
+                if err != nil {
+                        return created, err
+                }
+                if ok {
+                        created++
+                }
+        }
+        return created, nil
+}
+
+// applyRule creates a reminder for the party's overdue balance unless it
+// already has an open one or the rule reminded it too recently. rates is set
+// for a user-wide rule, whose minimum amount is in the base currency. The
+// party row is locked so concurrent runs cannot both create one.
+func (s *ReminderRuleService) applyRule(rule *models.ReminderRule, rates *RateTable, partyID, today string) (bool, error) {
+        day, err := time.Parse("2006-01-02", today)
+        if err != nil {
+                return false, err
+        }
+        cutoff := day.AddDate(0, 0, -rule.OverdueDays).Format("2006-01-02")
+
+        created := false
+        err = s.db.Transaction(func(tx *gorm.DB) error {
+                party, err := lockParty(tx, rule.UserID, partyID)
+                if err != nil {
+                        var appErr *apperrors.AppError
+                        if errors.As(err, &appErr) {
+                                return nil
+                        }
+                        return err
+                }
+
+                var open int64
+                if err := tx.Model(&models.Reminder{}).Where("party_id = ? AND status IN ?", party.ID, openReminderStatuses).Count(&open).Error; err != nil {
+                        return err
+                }
+                if open > 0 {
+                        return nil
+                }
+
+                repeatFrom := day.AddDate(0, 0, -rule.RepeatDays+1)
+                var recent int64
+                if err := tx.Model(&models.Reminder{}).Where("party_id = ? AND rule_id IS NOT NULL AND created_at >= ?", party.ID, repeatFrom).Count(&recent).Error; err != nil {
+                        return err
+                }
+                if recent > 0 {
+                        return nil
+                }
+
+                overdue, err := overdueBalance(tx, party, cutoff)
+                if err != nil {
+                        return err
+                }
+                if overdue <= 0 {
+                        return nil
+                }
+                threshold := overdue
+                if rates != nil {
+                        if threshold, err = rates.ToBase(overdue, party.Currency, today); err != nil {
+                                log.Printf("Reminder rule %s: skipping party %s: %v", rule.ID, party.ID, err)
+                                return nil
+                        }
+                }
+                if threshold < rule.MinAmount {
+                        return nil
+                }
+
+                reminder := &models.Reminder{
+                        UserID:     party.UserID,
+                        PartyID:    party.ID,
+                        Amount:     overdue,
+                        DueDate:    today,
+                        Message:    rule.Message,
+                        Status:     models.ReminderStatusPending,
+                        Channel:    rule.Channel,
+                        RuleID:     &rule.ID,
+                        TemplateID: rule.TemplateID,
+                }
+                if err := tx.Create(reminder).Error; err != nil {
+                        return err
+                }
+                if err := recordAudit(tx, reminder.UserID, models.AuditEntityReminder, reminder.ID, models.AuditActionCreate, nil, reminder); err != nil {
+                        return err
+                }
+                created = true
+                return recordReminderEvent(tx, reminder, "", "", models.ReminderActorSystem, "Created by reminder rule")
+        })
+        return created, err
+}
+
+// overdueBalance returns the part of the party's balance that is older than
+// cutoff. Payments are taken to settle the oldest charges first, so that is
+// the balance less the charges dated after cutoff.
+func overdueBalance(tx *gorm.DB, party *models.Party, cutoff string) (money.Amount, error) {
+        var recent []models.Transaction
+        if err := tx.Where("party_id = ? AND date > ?", party.ID, cutoff).Find(&recent).Error; err != nil {
+                return 0, err
+        }
+
+        overdue := party.Balance
+        for i := range recent {
+                if signed := recent[i].SignedAmount(); signed > 0 {
+                        overdue -= signed
+                }
+        }
+        if overdue < 0 {
+                return 0, nil
+        }
+        return overdue, nil
+}
+
+// nullIfEmpty maps an empty string to NULL for optional columns
+func nullIfEmpty(s string) interface{} {
+        if s == "" {
+                return nil
+        }
+        return s
+}
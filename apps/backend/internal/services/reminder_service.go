@@ -1,12 +1,16 @@
 package services
 
 import (
+        "context"
         "errors"
+        "fmt"
+        "time"
 
         "khatabook-go-backend/internal/models"
         apperrors "khatabook-go-backend/pkg/errors"
 
         "gorm.io/gorm"
+        "gorm.io/gorm/clause"
 )
 
 // ReminderService handles reminder operations
@@ -19,6 +23,14 @@ func NewReminderService(db *gorm.DB) *ReminderService {
         return &ReminderService{db: db}
 }
 
+// WithContext returns a copy of the service that runs its queries with ctx,
+// which carries the actor its changes are audited as
+func (s *ReminderService) WithContext(ctx context.Context) *ReminderService {
+        service := *s
+        service.db = s.db.WithContext(ctx)
+        return &service
+}
+
 // GetAllReminders retrieves all reminders for a user
 func (s *ReminderService) GetAllReminders(userID string) ([]models.Reminder, *apperrors.AppError) {
         var reminders []models.Reminder
@@ -31,7 +43,10 @@ func (s *ReminderService) GetAllReminders(userID string) ([]models.Reminder, *ap
 // GetReminderByID retrieves a single reminder
 func (s *ReminderService) GetReminderByID(userID, reminderID string) (*models.Reminder, *apperrors.AppError) {
         var reminder models.Reminder
-        if err := s.db.Where("id = ? AND user_id = ?", reminderID, userID).First(&reminder).Error; err != nil {
+        err := s.db.Preload("Settlements", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+                Where("id = ? AND user_id = ?", reminderID, userID).
+                First(&reminder).Error
+        if err != nil {
                 if errors.Is(err, gorm.ErrRecordNotFound) {
                         return nil, apperrors.NotFound("Reminder not found")
                 }
@@ -47,6 +62,17 @@ func (s *ReminderService) CreateReminder(userID string, req *models.CreateRemind
         if err := s.db.Where("id = ? AND user_id = ?", req.PartyID, userID).First(&party).Error; err != nil {
                 return nil, apperrors.NotFound("Party not found")
         }
+        if _, err := time.Parse("2006-01-02", req.DueDate); err != nil {
+                return nil, apperrors.BadRequest("due_date must be a date in YYYY-MM-DD format")
+        }
+        if err := validateTemplateText("", req.Message); err != nil {
+                return nil, err
+        }
+        if req.TemplateID != "" {
+                if err := checkTemplateID(s.db, userID, req.TemplateID); err != nil {
+                        return nil, apperrors.FromError(err, "Database error")
+                }
+        }
 
         reminder := &models.Reminder{
                 UserID:  userID,
@@ -54,37 +80,159 @@ func (s *ReminderService) CreateReminder(userID string, req *models.CreateRemind
                 Amount:  req.Amount,
                 DueDate: req.DueDate,
                 Message: &req.Message,
-                Status:  "pending",
+                Status:  models.ReminderStatusPending,
+        }
+        if req.Channel != "" {
+                reminder.Channel = &req.Channel
+        }
+        if req.TemplateID != "" {
+                reminder.TemplateID = &req.TemplateID
         }
 
-        if err := s.db.Create(reminder).Error; err != nil {
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                if err := tx.Create(reminder).Error; err != nil {
+                        return err
+                }
+                if err := recordAudit(tx, userID, models.AuditEntityReminder, reminder.ID, models.AuditActionCreate, nil, reminder); err != nil {
+                        return err
+                }
+                return recordReminderEvent(tx, reminder, "", "", models.ReminderActorUser, "")
+        })
+        if err != nil {
                 return nil, apperrors.Internal("Failed to create reminder", err)
         }
         return reminder, nil
 }
 
-// UpdateReminder updates a reminder status and/or message
+// UpdateReminder updates a reminder status and/or message. Status changes
+// must follow the reminder lifecycle and are recorded in its history.
 func (s *ReminderService) UpdateReminder(userID, reminderID string, req *models.UpdateReminderRequest) (*models.Reminder, *apperrors.AppError) {
-        // Verify ownership
-        if _, err := s.GetReminderByID(userID, reminderID); err != nil {
-                return nil, err
-        }
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                reminder, err := lockReminder(tx, userID, reminderID)
+                if err != nil {
+                        return err
+                }
+                before := *reminder
+
+                updateMap := map[string]interface{}{}
+                if req.Message != "" {
+                        updateMap["message"] = req.Message
+                }
+                if req.Channel != "" {
+                        updateMap["channel"] = req.Channel
+                }
+
+                if req.Status != reminder.Status {
+                        if !isReminderStatus(req.Status) {
+                                return apperrors.BadRequest("Unknown reminder status " + req.Status)
+                        }
+                        if req.Status == models.ReminderStatusSnoozed {
+                                return apperrors.BadRequest("Use the snooze endpoint to snooze a reminder")
+                        }
+                        if err := checkReminderTransition(reminder.Status, req.Status); err != nil {
+                                return err
+                        }
+
+                        from := reminder.Status
+                        reminder.Status = req.Status
+                        updateMap["status"] = req.Status
+                        if err := recordReminderEvent(tx, reminder, from, reminder.DueDate, models.ReminderActorUser, req.Note); err != nil {
+                                return err
+                        }
+                }
 
-        updateMap := map[string]interface{}{
-                "status": req.Status,
+                if len(updateMap) == 0 {
+                        return nil
+                }
+                if err := tx.Model(&models.Reminder{}).Where("id = ?", reminder.ID).Updates(updateMap).Error; err != nil {
+                        return err
+                }
+                return auditReminderChange(tx, &before, models.AuditActionUpdate)
+        })
+        if err != nil {
+                return nil, apperrors.FromError(err, "Failed to update reminder")
         }
-        if req.Message != "" {
-                updateMap["message"] = req.Message
+
+        return s.GetReminderByID(userID, reminderID)
+}
+
+// SnoozeReminder moves a reminder's due date and snoozes it. It is delivered
+// again, with a fresh set of attempts, once the new due date arrives.
+func (s *ReminderService) SnoozeReminder(userID, reminderID string, req *models.SnoozeReminderRequest) (*models.Reminder, *apperrors.AppError) {
+        if (req.Until == "") == (req.Days == 0) {
+                return nil, apperrors.BadRequest("Give either until or days")
         }
 
-        reminder := &models.Reminder{}
-        if result := s.db.Model(reminder).Where("id = ? AND user_id = ?", reminderID, userID).Updates(updateMap); result.Error != nil {
-                return nil, apperrors.Internal("Failed to update reminder", result.Error)
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                reminder, err := lockReminder(tx, userID, reminderID)
+                if err != nil {
+                        return err
+                }
+                if err := checkReminderTransition(reminder.Status, models.ReminderStatusSnoozed); err != nil {
+                        return err
+                }
+                before := *reminder
+
+                today := time.Now().Format("2006-01-02")
+                until := req.Until
+                if req.Days > 0 {
+                        from := today
+                        if reminder.DueDate > from {
+                                from = reminder.DueDate
+                        }
+                        start, err := time.Parse("2006-01-02", from)
+                        if err != nil {
+                                return apperrors.Unprocessable("Reminder has an invalid due date")
+                        }
+                        until = start.AddDate(0, 0, req.Days).Format("2006-01-02")
+                } else if _, err := time.Parse("2006-01-02", until); err != nil {
+                        return apperrors.BadRequest("until must be a date in YYYY-MM-DD format")
+                }
+                if until <= today || until <= reminder.DueDate {
+                        return apperrors.BadRequest("A reminder can only be snoozed to a later date")
+                }
+
+                fromStatus, fromDueDate := reminder.Status, reminder.DueDate
+                reminder.Status = models.ReminderStatusSnoozed
+                reminder.DueDate = until
+                if err := recordReminderEvent(tx, reminder, fromStatus, fromDueDate, models.ReminderActorUser, req.Note); err != nil {
+                        return err
+                }
+
+                err = tx.Model(&models.Reminder{}).Where("id = ?", reminder.ID).Updates(map[string]interface{}{
+                        "status":          reminder.Status,
+                        "due_date":        reminder.DueDate,
+                        "attempts":        0,
+                        "next_attempt_at": nil,
+                        "delivery_failed": false,
+                        "last_error":      nil,
+                }).Error
+                if err != nil {
+                        return err
+                }
+                return auditReminderChange(tx, &before, models.AuditActionUpdate)
+        })
+        if err != nil {
+                return nil, apperrors.FromError(err, "Failed to snooze reminder")
         }
 
         return s.GetReminderByID(userID, reminderID)
 }
 
+// GetHistory retrieves the status and due date changes of a reminder,
+// oldest first
+func (s *ReminderService) GetHistory(userID, reminderID string) ([]models.ReminderEvent, *apperrors.AppError) {
+        if _, err := s.GetReminderByID(userID, reminderID); err != nil {
+                return nil, err
+        }
+
+        var events []models.ReminderEvent
+        if err := s.db.Where("reminder_id = ? AND user_id = ?", reminderID, userID).Order("created_at").Find(&events).Error; err != nil {
+                return nil, apperrors.Internal("Failed to fetch reminder history", err)
+        }
+        return events, nil
+}
+
 // GetAllRemindersWithFilter retrieves reminders with optional status filter
 func (s *ReminderService) GetAllRemindersWithFilter(userID, status string) ([]models.Reminder, *apperrors.AppError) {
         var reminders []models.Reminder
@@ -100,15 +248,271 @@ func (s *ReminderService) GetAllRemindersWithFilter(userID, status string) ([]mo
         return reminders, nil
 }
 
-// DeleteReminder deletes a reminder
+// DeleteReminder moves a reminder to the trash
 func (s *ReminderService) DeleteReminder(userID, reminderID string) *apperrors.AppError {
-        // Verify ownership
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                reminder, err := lockReminder(tx, userID, reminderID)
+                if err != nil {
+                        return err
+                }
+                if err := tx.Delete(reminder).Error; err != nil {
+                        return err
+                }
+                return recordAudit(tx, userID, models.AuditEntityReminder, reminder.ID, models.AuditActionDelete, reminder, nil)
+        })
+
+        if err != nil {
+                return apperrors.FromError(err, "Failed to delete reminder")
+        }
+        return nil
+}
+
+// RestoreReminder brings a reminder back from the trash
+func (s *ReminderService) RestoreReminder(userID, reminderID string) (*models.Reminder, *apperrors.AppError) {
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                var reminder models.Reminder
+                err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+                        Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", reminderID, userID).
+                        First(&reminder).Error
+                if err != nil {
+                        if errors.Is(err, gorm.ErrRecordNotFound) {
+                                return apperrors.NotFound("Reminder not found in trash")
+                        }
+                        return err
+                }
+
+                var parties int64
+                if err := tx.Model(&models.Party{}).Where("id = ?", reminder.PartyID).Count(&parties).Error; err != nil {
+                        return err
+                }
+                if parties == 0 {
+                        return apperrors.Conflict("The party of this reminder is in the trash; restore the party first")
+                }
+
+                if err := tx.Unscoped().Model(&models.Reminder{}).Where("id = ?", reminder.ID).Update("deleted_at", nil).Error; err != nil {
+                        return err
+                }
+                return auditReminderChange(tx, &reminder, models.AuditActionRestore)
+        })
+
+        if err != nil {
+                return nil, apperrors.FromError(err, "Failed to restore reminder")
+        }
+        return s.GetReminderByID(userID, reminderID)
+}
+
+// GetDeliveries retrieves the delivery attempts of a reminder, oldest first
+func (s *ReminderService) GetDeliveries(userID, reminderID string) ([]models.ReminderDelivery, *apperrors.AppError) {
         if _, err := s.GetReminderByID(userID, reminderID); err != nil {
+                return nil, err
+        }
+
+        var deliveries []models.ReminderDelivery
+        if err := s.db.Where("reminder_id = ? AND user_id = ?", reminderID, userID).Order("created_at").Find(&deliveries).Error; err != nil {
+                return nil, apperrors.Internal("Failed to fetch reminder deliveries", err)
+        }
+        return deliveries, nil
+}
+
+// reminderTransitions lists the statuses each status may move to. Completed
+// and cancelled reminders are final.
+var reminderTransitions = map[string][]string{
+        models.ReminderStatusPending: {models.ReminderStatusSent, models.ReminderStatusSnoozed, models.ReminderStatusCompleted, models.ReminderStatusCancelled},
+        models.ReminderStatusSent:    {models.ReminderStatusSnoozed, models.ReminderStatusCompleted, models.ReminderStatusCancelled},
+        models.ReminderStatusSnoozed: {models.ReminderStatusSent, models.ReminderStatusSnoozed, models.ReminderStatusCompleted, models.ReminderStatusCancelled},
+}
+
+// reminderReopenTransitions lists the moves made only when the payment that
+// completed a reminder is changed or deleted
+var reminderReopenTransitions = map[string][]string{
+        models.ReminderStatusCompleted: {models.ReminderStatusPending},
+}
+
+// isReminderStatus reports whether status is a reminder status
+func isReminderStatus(status string) bool {
+        switch status {
+        case models.ReminderStatusPending, models.ReminderStatusSent, models.ReminderStatusSnoozed,
+                models.ReminderStatusCompleted, models.ReminderStatusCancelled:
+                return true
+        }
+        return false
+}
+
+// checkReminderTransition returns a conflict error unless a reminder may move
+// from one status to the other
+func checkReminderTransition(from, to string) *apperrors.AppError {
+        if transitionAllowed(reminderTransitions, from, to) {
+                return nil
+        }
+        return apperrors.Conflict(fmt.Sprintf("A %s reminder cannot be marked %s", from, to))
+}
+
+// transitionAllowed reports whether transitions lets a status move to another
+func transitionAllowed(transitions map[string][]string, from, to string) bool {
+        for _, allowed := range transitions[from] {
+                if allowed == to {
+                        return true
+                }
+        }
+        return false
+}
+
+// recordReminderEvent adds the reminder's current status and due date to its
+// history
+func recordReminderEvent(tx *gorm.DB, reminder *models.Reminder, fromStatus, fromDueDate, actor, note string) error {
+        event := &models.ReminderEvent{
+                ReminderID:  reminder.ID,
+                UserID:      reminder.UserID,
+                FromStatus:  fromStatus,
+                ToStatus:    reminder.Status,
+                FromDueDate: fromDueDate,
+                ToDueDate:   reminder.DueDate,
+                Actor:       actor,
+        }
+        if note != "" {
+                event.Note = &note
+        }
+        return tx.Create(event).Error
+}
+
+// lockReminder loads a reminder for update
+func lockReminder(tx *gorm.DB, userID, reminderID string) (*models.Reminder, error) {
+        var reminder models.Reminder
+        err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+                Where("id = ? AND user_id = ?", reminderID, userID).
+                First(&reminder).Error
+        if err != nil {
+                if errors.Is(err, gorm.ErrRecordNotFound) {
+                        return nil, apperrors.NotFound("Reminder not found")
+                }
+                return nil, err
+        }
+        return &reminder, nil
+}
+
+// openReminderStatuses are the statuses of reminders still awaiting payment
+var openReminderStatuses = []string{models.ReminderStatusPending, models.ReminderStatusSent, models.ReminderStatusSnoozed}
+
+// settleReminders applies a payment from the party to its open reminders,
+// oldest due date first, and completes the reminders it pays in full. Bills
+// and opening balances settle nothing.
+func settleReminders(tx *gorm.DB, party *models.Party, payment *models.Transaction) error {
+        if payment.TransactionType == models.TransactionTypeOpening || isBill(party, payment) {
+                return nil
+        }
+        available := payment.SignedAmount()
+        if available < 0 {
+                available = -available
+        }
+        if available == 0 {
+                return nil
+        }
+
+        var reminders []models.Reminder
+        err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+                Where("party_id = ? AND status IN ?", party.ID, openReminderStatuses).
+                Order("due_date, created_at").
+                Find(&reminders).Error
+        if err != nil {
                 return err
         }
 
-        if result := s.db.Where("id = ? AND user_id = ?", reminderID, userID).Delete(&models.Reminder{}); result.Error != nil {
-                return apperrors.Internal("Failed to delete reminder", result.Error)
+        for i := range reminders {
+                if available == 0 {
+                        break
+                }
+                reminder := &reminders[i]
+                amount := reminder.Outstanding()
+                if amount <= 0 {
+                        continue
+                }
+                if amount > available {
+                        amount = available
+                }
+                before := *reminder
+                available -= amount
+                reminder.Settled += amount
+
+                settlement := &models.ReminderSettlement{
+                        ReminderID:    reminder.ID,
+                        UserID:        reminder.UserID,
+                        TransactionID: payment.ID,
+                        Amount:        amount,
+                        Completed:     reminder.Outstanding() == 0,
+                }
+                if err := tx.Create(settlement).Error; err != nil {
+                        return err
+                }
+
+                updates := map[string]interface{}{"settled": reminder.Settled}
+                if settlement.Completed {
+                        from := reminder.Status
+                        reminder.Status = models.ReminderStatusCompleted
+                        updates["status"] = reminder.Status
+                        updates["next_attempt_at"] = nil
+                        if err := recordReminderEvent(tx, reminder, from, reminder.DueDate, models.ReminderActorSystem, "Settled by payment"); err != nil {
+                                return err
+                        }
+                }
+                if err := tx.Model(&models.Reminder{}).Where("id = ?", reminder.ID).Updates(updates).Error; err != nil {
+                        return err
+                }
+                if err := auditReminderChange(tx, &before, models.AuditActionUpdate); err != nil {
+                        return err
+                }
         }
         return nil
 }
+
+// unsettleReminders reverses the settlements made by a payment that is being
+// changed or deleted, reopening the reminders it completed
+func unsettleReminders(tx *gorm.DB, transactionID string) error {
+        var settlements []models.ReminderSettlement
+        if err := tx.Where("transaction_id = ?", transactionID).Find(&settlements).Error; err != nil {
+                return err
+        }
+
+        for _, settlement := range settlements {
+                var reminder models.Reminder
+                // Reminders in the trash are corrected too, so they come back right
+                err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", settlement.ReminderID).First(&reminder).Error
+                if errors.Is(err, gorm.ErrRecordNotFound) {
+                        continue
+                }
+                if err != nil {
+                        return err
+                }
+
+                before := reminder
+                reminder.Settled -= settlement.Amount
+                updates := map[string]interface{}{"settled": reminder.Settled}
+                // Only the completion this payment made is undone; a reminder
+                // cancelled since then stays cancelled
+                if settlement.Completed && transitionAllowed(reminderReopenTransitions, reminder.Status, models.ReminderStatusPending) {
+                        from := reminder.Status
+                        reminder.Status = models.ReminderStatusPending
+                        updates["status"] = reminder.Status
+                        if err := recordReminderEvent(tx, &reminder, from, reminder.DueDate, models.ReminderActorSystem, "Reopened: payment removed"); err != nil {
+                                return err
+                        }
+                }
+                if err := tx.Unscoped().Model(&models.Reminder{}).Where("id = ?", reminder.ID).Updates(updates).Error; err != nil {
+                        return err
+                }
+                if err := auditReminderChange(tx, &before, models.AuditActionUpdate); err != nil {
+                        return err
+                }
+        }
+
+        return tx.Where("transaction_id = ?", transactionID).Delete(&models.ReminderSettlement{}).Error
+}
+
+// auditReminderChange records a change to a reminder in the audit log,
+// reloading the reminder as it is now
+func auditReminderChange(tx *gorm.DB, before *models.Reminder, action string) error {
+        var after models.Reminder
+        if err := tx.Unscoped().First(&after, "id = ?", before.ID).Error; err != nil {
+                return err
+        }
+        return recordAudit(tx, after.UserID, models.AuditEntityReminder, after.ID, action, before, &after)
+}
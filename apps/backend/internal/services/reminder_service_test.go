@@ -0,0 +1,31 @@
+package services
+
+import (
+        "testing"
+
+        "khatabook-go-backend/internal/models"
+)
+
+func TestReminderTransitions(t *testing.T) {
+        tests := []struct {
+                from, to   string
+                user, paid bool // allowed as a user change, allowed when a payment is removed
+        }{
+                {models.ReminderStatusPending, models.ReminderStatusSent, true, false},
+                {models.ReminderStatusPending, models.ReminderStatusCompleted, true, false},
+                {models.ReminderStatusSent, models.ReminderStatusSnoozed, true, false},
+                {models.ReminderStatusSnoozed, models.ReminderStatusSnoozed, true, false},
+                {models.ReminderStatusSent, models.ReminderStatusPending, false, false},
+                {models.ReminderStatusCompleted, models.ReminderStatusPending, false, true},
+                {models.ReminderStatusCompleted, models.ReminderStatusSent, false, false},
+                {models.ReminderStatusCancelled, models.ReminderStatusPending, false, false},
+        }
+        for _, tt := range tests {
+                if got := checkReminderTransition(tt.from, tt.to) == nil; got != tt.user {
+                        t.Errorf("checkReminderTransition(%s, %s) allowed = %v, want %v", tt.from, tt.to, got, tt.user)
+                }
+                if got := transitionAllowed(reminderReopenTransitions, tt.from, tt.to); got != tt.paid {
+                        t.Errorf("reopening %s as %s allowed = %v, want %v", tt.from, tt.to, got, tt.paid)
+                }
+        }
+}
@@ -0,0 +1,375 @@
+package services
+
+import (
+        "errors"
+        "time"
+
+        "khatabook-go-backend/internal/models"
+        apperrors "khatabook-go-backend/pkg/errors"
+        "khatabook-go-backend/pkg/i18n"
+        "khatabook-go-backend/pkg/money"
+
+        "gorm.io/gorm"
+)
+
+// samplePartyName is shown in previews that are not for a real party
+const samplePartyName = "Ramesh Kumar"
+
+// ReminderTemplateService manages reminder message templates and renders
+// reminders with them
+type ReminderTemplateService struct {
+        db *gorm.DB
+}
+
+// NewReminderTemplateService creates a new reminder template service
+func NewReminderTemplateService(db *gorm.DB) *ReminderTemplateService {
+        return &ReminderTemplateService{db: db}
+}
+
+// RenderedReminder is a reminder message ready to send
+type RenderedReminder struct {
+        Language   string            `json:"language"`
+        TemplateID *string           `json:"template_id"` // nil when the built-in message was used
+        Subject    string            `json:"subject"`
+        Body       string            `json:"body"`
+        Values     map[string]string `json:"values"`
+}
+
+// Params returns the placeholder values in the order of i18n.Placeholders,
+// for channels that fill in the template themselves
+func (r *RenderedReminder) Params() []string {
+        params := make([]string, len(i18n.Placeholders))
+        for i, name := range i18n.Placeholders {
+                params[i] = r.Values[name]
+        }
+        return params
+}
+
+// GetTemplates retrieves the user's reminder templates, optionally in one
+// language
+func (s *ReminderTemplateService) GetTemplates(userID, language string) ([]models.ReminderTemplate, *apperrors.AppError) {
+        var templates []models.ReminderTemplate
+        query := s.db.Where("user_id = ?", userID)
+        if language != "" {
+                query = query.Where("language = ?", language)
+        }
+        if err := query.Order("language, is_default DESC, name").Find(&templates).Error; err != nil {
+                return nil, apperrors.Internal("Failed to fetch reminder templates", err)
+        }
+        return templates, nil
+}
+
+// GetTemplateByID retrieves a single reminder template
+func (s *ReminderTemplateService) GetTemplateByID(userID, templateID string) (*models.ReminderTemplate, *apperrors.AppError) {
+        var template models.ReminderTemplate
+        if err := s.db.Where("id = ? AND user_id = ?", templateID, userID).First(&template).Error; err != nil {
+                if errors.Is(err, gorm.ErrRecordNotFound) {
+                        return nil, apperrors.NotFound("Reminder template not found")
+                }
+                return nil, apperrors.Internal("Database error", err)
+        }
+        return &template, nil
+}
+
+// CreateTemplate creates a reminder template. It defaults to the user's
+// language, and making it the default replaces the language's previous one.
+func (s *ReminderTemplateService) CreateTemplate(userID string, req *models.CreateReminderTemplateRequest) (*models.ReminderTemplate, *apperrors.AppError) {
+        if err := validateTemplateText(req.Subject, req.Body); err != nil {
+                return nil, err
+        }
+
+        template := &models.ReminderTemplate{
+                UserID:    userID,
+                Name:      req.Name,
+                Language:  req.Language,
+                Body:      req.Body,
+                IsDefault: req.IsDefault,
+        }
+        if req.Subject != "" {
+                template.Subject = &req.Subject
+        }
+        if template.Language == "" {
+                var user models.User
+                if err := s.db.Select("id", "language").Where("id = ?", userID).First(&user).Error; err != nil {
+                        return nil, apperrors.Internal("Database error", err)
+                }
+                template.Language = languageOf(nil, &user)
+        }
+
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                if template.IsDefault {
+                        if err := clearDefaultTemplate(tx, userID, template.Language); err != nil {
+                                return err
+                        }
+                }
+                return tx.Create(template).Error
+        })
+        if err != nil {
+                return nil, apperrors.Internal("Failed to create reminder template", err)
+        }
+        return template, nil
+}
+
+// UpdateTemplate updates a reminder template
+func (s *ReminderTemplateService) UpdateTemplate(userID, templateID string, req *models.UpdateReminderTemplateRequest) (*models.ReminderTemplate, *apperrors.AppError) {
+        template, appErr := s.GetTemplateByID(userID, templateID)
+        if appErr != nil {
+                return nil, appErr
+        }
+
+        updates := map[string]interface{}{}
+        if req.Name != "" {
+                updates["name"] = req.Name
+        }
+        if req.Language != "" {
+                updates["language"] = req.Language
+                template.Language = req.Language
+        }
+        if req.Subject != nil {
+                updates["subject"] = nullIfEmpty(*req.Subject)
+        }
+        if req.Body != "" {
+                updates["body"] = req.Body
+        }
+        if req.IsDefault != nil {
+                updates["is_default"] = *req.IsDefault
+                template.IsDefault = *req.IsDefault
+        }
+
+        subject := ""
+        if req.Subject != nil {
+                subject = *req.Subject
+        }
+        if err := validateTemplateText(subject, req.Body); err != nil {
+                return nil, err
+        }
+
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                if template.IsDefault {
+                        if err := clearDefaultTemplate(tx, userID, template.Language); err != nil {
+                                return err
+                        }
+                        updates["is_default"] = true
+                }
+                if len(updates) == 0 {
+                        return nil
+                }
+                return tx.Model(&models.ReminderTemplate{}).Where("id = ?", templateID).Updates(updates).Error
+        })
+        if err != nil {
+                return nil, apperrors.Internal("Failed to update reminder template", err)
+        }
+        return s.GetTemplateByID(userID, templateID)
+}
+
+// DeleteTemplate deletes a reminder template. Reminders and rules that used
+// it fall back to the default template of their language.
+func (s *ReminderTemplateService) DeleteTemplate(userID, templateID string) *apperrors.AppError {
+        if _, err := s.GetTemplateByID(userID, templateID); err != nil {
+                return err
+        }
+
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                if err := tx.Model(&models.Reminder{}).Where("template_id = ?", templateID).Update("template_id", nil).Error; err != nil {
+                        return err
+                }
+                if err := tx.Unscoped().Model(&models.ReminderRule{}).Where("template_id = ?", templateID).Update("template_id", nil).Error; err != nil {
+                        return err
+                }
+                return tx.Where("id = ? AND user_id = ?", templateID, userID).Delete(&models.ReminderTemplate{}).Error
+        })
+        if err != nil {
+                return apperrors.Internal("Failed to delete reminder template", err)
+        }
+        return nil
+}
+
+// Preview renders a template with the values of a reminder or party, using
+// sample values for anything not given
+func (s *ReminderTemplateService) Preview(userID string, req *models.PreviewReminderTemplateRequest) (*RenderedReminder, *apperrors.AppError) {
+        if err := validateTemplateText(req.Subject, req.Body); err != nil {
+                return nil, err
+        }
+
+        var user models.User
+        if err := s.db.Select("id", "name", "language", "base_currency").Where("id = ?", userID).First(&user).Error; err != nil {
+                if errors.Is(err, gorm.ErrRecordNotFound) {
+                        return nil, apperrors.NotFound("User not found")
+                }
+                return nil, apperrors.Internal("Database error", err)
+        }
+
+        in := reminderText{
+                user:     &user,
+                message:  req.Body,
+                subject:  req.Subject,
+                amount:   req.Amount,
+                dueDate:  req.DueDate,
+                language: req.Language,
+        }
+        if req.TemplateID != "" {
+                if _, err := s.GetTemplateByID(userID, req.TemplateID); err != nil {
+                        return nil, err
+                }
+                in.templateID = &req.TemplateID
+        }
+
+        partyID := req.PartyID
+        if req.ReminderID != "" {
+                var reminder models.Reminder
+                if err := s.db.Where("id = ? AND user_id = ?", req.ReminderID, userID).First(&reminder).Error; err != nil {
+                        if errors.Is(err, gorm.ErrRecordNotFound) {
+                                return nil, apperrors.NotFound("Reminder not found")
+                        }
+                        return nil, apperrors.Internal("Database error", err)
+                }
+                partyID = reminder.PartyID
+                if in.amount == 0 {
+                        in.amount = reminder.Outstanding()
+                }
+                if in.dueDate == "" {
+                        in.dueDate = reminder.DueDate
+                }
+                if in.templateID == nil && in.message == "" {
+                        in.templateID = reminder.TemplateID
+                        in.message = stringValue(reminder.Message)
+                }
+        }
+
+        if partyID != "" {
+                var party models.Party
+                if err := s.db.Where("id = ? AND user_id = ?", partyID, userID).First(&party).Error; err != nil {
+                        return nil, apperrors.NotFound("Party not found")
+                }
+                in.party = &party
+                if in.amount == 0 && party.Balance > 0 {
+                        in.amount = party.Balance
+                }
+        } else {
+                in.party = &models.Party{Name: samplePartyName, Currency: user.BaseCurrency}
+        }
+        if in.amount == 0 {
+                in.amount = 1234567
+        }
+        if in.dueDate == "" {
+                in.dueDate = time.Now().Format("2006-01-02")
+        }
+
+        rendered, err := renderReminder(s.db, &in)
+        if err != nil {
+                return nil, apperrors.Internal("Failed to render reminder", err)
+        }
+        return rendered, nil
+}
+
+// reminderText is what a reminder message is rendered from. An empty
+// language uses the party's, then the user's.
+type reminderText struct {
+        party      *models.Party
+        user       *models.User
+        templateID *string
+        message    string
+        subject    string
+        amount     money.Amount
+        dueDate    string
+        language   string
+}
+
+// renderReminder renders a reminder from its own message, its template or
+// the default template of its language, falling back to the built-in
+// message when the user has none
+func renderReminder(db *gorm.DB, in *reminderText) (*RenderedReminder, error) {
+        language := in.language
+        if language == "" {
+                language = languageOf(in.party, in.user)
+        }
+
+        var template *models.ReminderTemplate
+        if in.templateID != nil {
+                var found models.ReminderTemplate
+                err := db.Where("id = ? AND user_id = ?", *in.templateID, in.user.ID).First(&found).Error
+                if err == nil {
+                        template = &found
+                } else if !errors.Is(err, gorm.ErrRecordNotFound) {
+                        return nil, err
+                }
+        }
+        if template == nil {
+                var found []models.ReminderTemplate
+                if err := db.Where("user_id = ? AND language = ? AND is_default = ?", in.user.ID, language, true).Limit(1).Find(&found).Error; err != nil {
+                        return nil, err
+                }
+                if len(found) > 0 {
+                        template = &found[0]
+                }
+        }
+
+        builtIn := i18n.DefaultReminder(language)
+        rendered := &RenderedReminder{Language: language, Subject: builtIn.Subject, Body: builtIn.Body}
+        if template != nil {
+                rendered.Language = template.Language
+                rendered.TemplateID = &template.ID
+                rendered.Body = template.Body
+                if template.Subject != nil {
+                        rendered.Subject = *template.Subject
+                } else {
+                        rendered.Subject = i18n.DefaultReminder(template.Language).Subject
+                }
+        }
+        if in.message != "" {
+                rendered.Body = in.message
+        }
+        if in.subject != "" {
+                rendered.Subject = in.subject
+        }
+
+        rendered.Values = map[string]string{
+                i18n.PartyName:    in.party.Name,
+                i18n.Amount:       i18n.FormatAmount(in.amount, in.party.Currency),
+                i18n.DueDate:      i18n.FormatDate(in.dueDate),
+                i18n.BusinessName: in.user.Name,
+        }
+        rendered.Subject = i18n.Render(rendered.Subject, rendered.Values)
+        rendered.Body = i18n.Render(rendered.Body, rendered.Values)
+        return rendered, nil
+}
+
+// languageOf returns the language reminders to a party are written in
+func languageOf(party *models.Party, user *models.User) string {
+        if party != nil && party.Language != nil && *party.Language != "" {
+                return *party.Language
+        }
+        if user != nil && user.Language != "" {
+                return user.Language
+        }
+        return i18n.DefaultLanguage
+}
+
+// checkTemplateID returns a not found error unless the user owns the template
+func checkTemplateID(tx *gorm.DB, userID, templateID string) error {
+        var count int64
+        if err := tx.Model(&models.ReminderTemplate{}).Where("id = ? AND user_id = ?", templateID, userID).Count(&count).Error; err != nil {
+                return err
+        }
+        if count == 0 {
+                return apperrors.NotFound("Reminder template not found")
+        }
+        return nil
+}
+
+// validateTemplateText rejects subjects and bodies with unknown placeholders
+func validateTemplateText(subject, body string) *apperrors.AppError {
+        for _, text := range []string{subject, body} {
+                if err := i18n.Validate(text); err != nil {
+                        return apperrors.BadRequest(err.Error())
+                }
+        }
+        return nil
+}
+
+// clearDefaultTemplate unmarks the user's default template in a language
+func clearDefaultTemplate(tx *gorm.DB, userID, language string) error {
+        return tx.Model(&models.ReminderTemplate{}).
+                Where("user_id = ? AND language = ? AND is_default = ?", userID, language, true).
+                Update("is_default", false).Error
+}
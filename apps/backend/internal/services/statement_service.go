@@ -0,0 +1,245 @@
+package services
+
+import (
+        "bytes"
+        "fmt"
+        "time"
+
+        "khatabook-go-backend/internal/models"
+        apperrors "khatabook-go-backend/pkg/errors"
+        "khatabook-go-backend/pkg/money"
+        "khatabook-go-backend/pkg/pdf"
+)
+
+// StatementLine is one transaction on a party statement
+type StatementLine struct {
+        Date        string       `json:"date"`
+        Description string       `json:"description"`
+        Type        string       `json:"transaction_type"`
+        Credit      money.Amount `json:"credit"`
+        Debit       money.Amount `json:"debit"`
+        Balance     money.Amount `json:"balance"`
+}
+
+// Statement is a party's account statement for a period
+type Statement struct {
+        Business       models.User     `json:"business"`
+        Party          models.Party    `json:"party"`
+        From           string          `json:"from"`
+        To             string          `json:"to"`
+        OpeningBalance money.Amount    `json:"opening_balance"`
+        Lines          []StatementLine `json:"lines"`
+        TotalCredit    money.Amount    `json:"total_credit"`
+        TotalDebit     money.Amount    `json:"total_debit"`
+        ClosingBalance money.Amount    `json:"closing_balance"`
+}
+
+// GetStatement builds the statement of a party between two dates. An empty
+// from starts at the first transaction and an empty to ends today. The
+// opening balance is everything dated before from.
+func (s *PartyService) GetStatement(userID, partyID, from, to string) (*Statement, *apperrors.AppError) {
+        party, appErr := s.GetPartyByID(userID, partyID)
+        if appErr != nil {
+                return nil, appErr
+        }
+        if to == "" {
+                to = time.Now().Format("2006-01-02")
+        }
+        if from != "" && from > to {
+                return nil, apperrors.BadRequest("from must not be after to")
+        }
+
+        var business models.User
+        if err := s.db.Where("id = ?", userID).First(&business).Error; err != nil {
+                return nil, apperrors.Internal("Database error", err)
+        }
+
+        transactions, err := s.transactions.partyLedger(s.db, partyID)
+        if err != nil {
+                return nil, apperrors.Internal("Failed to fetch transactions", err)
+        }
+
+        statement := &Statement{Business: business, Party: *party, From: from, To: to, Lines: []StatementLine{}}
+        for _, t := range transactions {
+                if t.Date < from {
+                        statement.OpeningBalance += t.SignedAmount()
+                }
+        }
+
+        balance := statement.OpeningBalance
+        for _, t := range transactions {
+                if t.Date < from || t.Date > to {
+                        continue
+                }
+
+                line := StatementLine{Date: t.Date, Type: t.TransactionType}
+                if t.Description != nil {
+                        line.Description = *t.Description
+                }
+                if signed := t.SignedAmount(); signed >= 0 {
+                        line.Credit = signed
+                } else {
+                        line.Debit = -signed
+                }
+                balance += t.SignedAmount()
+                line.Balance = balance
+
+                statement.TotalCredit += line.Credit
+                statement.TotalDebit += line.Debit
+                statement.Lines = append(statement.Lines, line)
+        }
+        statement.ClosingBalance = balance
+
+        return statement, nil
+}
+
+// Statement page layout, in points
+const (
+        statementMargin   = 40.0
+        statementRowGap   = 16.0
+        statementFontSize = 9.0
+        statementBottom   = pdf.PageHeight - 60
+)
+
+// statementColumns are the left edges of the date and description columns
+// and the right edges of the amount columns
+var statementColumns = struct {
+        date, description, credit, debit, balance float64
+}{40, 110, 385, 465, 555}
+
+// StatementPDF renders the statement of a party between two dates as a PDF
+func (s *PartyService) StatementPDF(userID, partyID, from, to string) ([]byte, *apperrors.AppError) {
+        statement, appErr := s.GetStatement(userID, partyID, from, to)
+        if appErr != nil {
+                return nil, appErr
+        }
+
+        doc := pdf.New("Statement - " + statement.Party.Name)
+        page := doc.AddPage()
+        y := drawStatementHeader(page, statement)
+        y = drawStatementTableHeader(page, y)
+
+        row := func(date, description string, credit, debit, balance string, bold bool) {
+                if y > statementBottom {
+                        page = doc.AddPage()
+                        y = drawStatementTableHeader(page, statementMargin+10)
+                }
+                c := statementColumns
+                page.Text(c.date, y, statementFontSize, bold, date)
+                page.Text(c.description, y, statementFontSize, bold, pdf.Truncate(description, c.credit-c.description-70, statementFontSize, bold))
+                page.TextRight(c.credit, y, statementFontSize, bold, credit)
+                page.TextRight(c.debit, y, statementFontSize, bold, debit)
+                page.TextRight(c.balance, y, statementFontSize, bold, balance)
+                y += statementRowGap
+        }
+
+        openingDate := statement.From
+        row(openingDate, "Opening balance", "", "", statement.OpeningBalance.String(), true)
+        for _, line := range statement.Lines {
+                description := line.Description
+                if description == "" {
+                        description = line.Type
+                }
+                row(line.Date, description, amountOrBlank(line.Credit), amountOrBlank(line.Debit), line.Balance.String(), false)
+        }
+
+        page.Line(statementMargin, y-statementRowGap+4, pdf.PageWidth-statementMargin, y-statementRowGap+4, 0.5)
+        row("", "Total", statement.TotalCredit.String(), statement.TotalDebit.String(), "", true)
+        row(statement.To, "Closing balance", "", "", statement.ClosingBalance.String(), true)
+
+        y += statementRowGap / 2
+        page.Text(statementMargin, y, statementFontSize, false, closingNote(statement))
+
+        pages := doc.Pages()
+        for i, p := range pages {
+                p.Line(statementMargin, pdf.PageHeight-45, pdf.PageWidth-statementMargin, pdf.PageHeight-45, 0.5)
+                p.Text(statementMargin, pdf.PageHeight-32, 8, false, "Generated on "+time.Now().Format("02 Jan 2006"))
+                p.TextRight(pdf.PageWidth-statementMargin, pdf.PageHeight-32, 8, false, fmt.Sprintf("Page %d of %d", i+1, len(pages)))
+        }
+
+        var buf bytes.Buffer
+        if _, err := doc.WriteTo(&buf); err != nil {
+                return nil, apperrors.Internal("Failed to render statement", err)
+        }
+        return buf.Bytes(), nil
+}
+
+// drawStatementHeader draws the business and party details and returns the
+// y position below them
+func drawStatementHeader(page *pdf.Page, statement *Statement) float64 {
+        business, party := statement.Business, statement.Party
+        right := pdf.PageWidth - statementMargin
+
+        y := statementMargin + 14
+        page.Text(statementMargin, y, 16, true, business.Name)
+        page.TextRight(right, y, 14, true, "Account Statement")
+        y += 14
+        for _, detail := range []string{stringValue(business.Phone), business.Email, gstinLine(business.GSTIN)} {
+                if detail == "" {
+                        continue
+                }
+                page.Text(statementMargin, y, statementFontSize, false, detail)
+                y += 12
+        }
+
+        period := "Up to " + statement.To
+        if statement.From != "" {
+                period = statement.From + " to " + statement.To
+        }
+        page.TextRight(right, statementMargin+28, statementFontSize, false, period)
+        page.TextRight(right, statementMargin+40, statementFontSize, false, "Amounts in "+party.Currency)
+
+        y += 10
+        page.Line(statementMargin, y, right, y, 0.75)
+        y += 18
+        page.Text(statementMargin, y, 11, true, party.Name)
+        y += 13
+        for _, detail := range []string{stringValue(party.Phone), stringValue(party.Address), gstinLine(party.GSTIN)} {
+                if detail == "" {
+                        continue
+                }
+                page.Text(statementMargin, y, statementFontSize, false, detail)
+                y += 12
+        }
+        return y + 14
+}
+
+// drawStatementTableHeader draws the column titles at y and returns the y
+// position of the first row
+func drawStatementTableHeader(page *pdf.Page, y float64) float64 {
+        c := statementColumns
+        page.FillRect(statementMargin, y-12, pdf.PageWidth-2*statementMargin, 18, 0.9)
+        page.Text(c.date, y, statementFontSize, true, "Date")
+        page.Text(c.description, y, statementFontSize, true, "Description")
+        page.TextRight(c.credit, y, statementFontSize, true, "Credit")
+        page.TextRight(c.debit, y, statementFontSize, true, "Debit")
+        page.TextRight(c.balance, y, statementFontSize, true, "Balance")
+        return y + 20
+}
+
+// closingNote explains which way the closing balance runs
+func closingNote(statement *Statement) string {
+        balance := statement.ClosingBalance
+        switch {
+        case balance > 0:
+                return fmt.Sprintf("%s %s is due from %s.", statement.Party.Currency, balance, statement.Party.Name)
+        case balance < 0:
+                return fmt.Sprintf("%s %s is due to %s.", statement.Party.Currency, balance.Abs(), statement.Party.Name)
+        default:
+                return "The account is settled."
+        }
+}
+
+func gstinLine(gstin *string) string {
+        if gstin == nil || *gstin == "" {
+                return ""
+        }
+        return "GSTIN: " + *gstin
+}
+
+func amountOrBlank(amount money.Amount) string {
+        if amount == 0 {
+                return ""
+        }
+        return amount.String()
+}
@@ -1,22 +1,37 @@
 package services
 
 import (
+        "context"
         "errors"
+        "fmt"
+        "net/http"
+        "time"
 
         "khatabook-go-backend/internal/models"
         apperrors "khatabook-go-backend/pkg/errors"
+        "khatabook-go-backend/pkg/money"
 
         "gorm.io/gorm"
+        "gorm.io/gorm/clause"
 )
 
 // TransactionService handles transaction operations
 type TransactionService struct {
-        db *gorm.DB
+        db      *gorm.DB
+        journal *JournalService
 }
 
 // NewTransactionService creates a new transaction service
 func NewTransactionService(db *gorm.DB) *TransactionService {
-        return &TransactionService{db: db}
+        return &TransactionService{db: db, journal: NewJournalService(db)}
+}
+
+// WithContext returns a copy of the service that runs its queries with ctx,
+// which carries the actor its changes are audited as
+func (s *TransactionService) WithContext(ctx context.Context) *TransactionService {
+        service := *s
+        service.db = s.db.WithContext(ctx)
+        return &service
 }
 
 // GetAllTransactions retrieves all transactions for a user
@@ -42,69 +57,184 @@ func (s *TransactionService) GetTransactionByID(userID, transactionID string) (*
 
 // CreateTransaction creates a new transaction and updates party balance
 func (s *TransactionService) CreateTransaction(userID string, req *models.CreateTransactionRequest) (*models.Transaction, *apperrors.AppError) {
-        // Verify party ownership
-        var party models.Party
-        if err := s.db.Where("id = ? AND user_id = ?", req.PartyID, userID).First(&party).Error; err != nil {
-                return nil, apperrors.NotFound("Party not found")
+        var transaction *models.Transaction
+
+        // Start transaction
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                var err error
+                transaction, err = s.createTransaction(tx, userID, req)
+                return err
+        })
+
+        if err != nil {
+                return nil, apperrors.FromError(err, "Failed to create transaction")
         }
+        return transaction, nil
+}
 
+// createTransaction creates a transaction inside an open DB transaction,
+// applying the party's currency, credit limit and payment allocation
+func (s *TransactionService) createTransaction(tx *gorm.DB, userID string, req *models.CreateTransactionRequest) (*models.Transaction, error) {
         // Set default date to today if not provided
         date := req.Date
         if date == "" {
-                date = "2025-12-29" // TODO: Use time.Now().Format("2006-01-02")
+                date = time.Now().Format("2006-01-02")
         }
 
-        // Create transaction
         transaction := &models.Transaction{
-                UserID:           userID,
-                PartyID:          req.PartyID,
-                Amount:           req.Amount,
-                TransactionType:  req.TransactionType,
-                Description:      &req.Description,
-                Date:             date,
-                Category:         &req.Category,
-                RunningBalance:   party.Balance + req.Amount,
+                UserID:          userID,
+                PartyID:         req.PartyID,
+                Amount:          req.Amount,
+                TransactionType: req.TransactionType,
+                Description:     &req.Description,
+                Date:            date,
+                Category:        &req.Category,
         }
 
-        // Start transaction
+        // Verify party ownership and serialise balance changes on the party row
+        party, err := lockParty(tx, userID, req.PartyID)
+        if err != nil {
+                return nil, err
+        }
+
+        // Transactions are always in the currency of the party's ledger
+        if req.Currency != "" && req.Currency != party.Currency {
+                return nil, apperrors.BadRequest("Transaction currency must match the party currency " + party.Currency)
+        }
+        transaction.Currency = party.Currency
+
+        warning, err := s.checkCreditLimit(tx, party, transaction.SignedAmount(), req.OverrideCredit)
+        if err != nil {
+                return nil, err
+        }
+
+        if err := s.insertTransaction(tx, transaction); err != nil {
+                return nil, err
+        }
+        if warning != "" {
+                transaction.Warnings = append(transaction.Warnings, warning)
+        }
+
+        // Settle open bills with payments oldest first unless the caller
+        // wants to allocate by hand
+        if req.AutoAllocate == nil || *req.AutoAllocate {
+                if _, err := autoAllocate(tx, party); err != nil {
+                        return nil, err
+                }
+        }
+
+        // Payments settle the party's open reminders
+        if err := settleReminders(tx, party, transaction); err != nil {
+                return nil, err
+        }
+        return transaction, nil
+}
+
+// UpdateTransaction updates an existing transaction and rebuilds the party balance
+func (s *TransactionService) UpdateTransaction(userID, transactionID string, req *models.UpdateTransactionRequest) (*models.Transaction, *apperrors.AppError) {
+        // Verify ownership
+        transaction, appErr := s.GetTransactionByID(userID, transactionID)
+        if appErr != nil {
+                return nil, appErr
+        }
+
+        // Opening balances keep their type and carry a signed amount
+        opening := transaction.TransactionType == models.TransactionTypeOpening
+        if opening && req.TransactionType != "" && req.TransactionType != models.TransactionTypeOpening {
+                return nil, apperrors.BadRequest("The type of an opening balance cannot be changed")
+        }
+        if !opening && req.Amount != nil && *req.Amount <= 0 {
+                return nil, apperrors.BadRequest("Amount must be positive")
+        }
+
+        updates := map[string]interface{}{}
+        if req.Amount != nil {
+                updates["amount"] = *req.Amount
+        }
+        if req.TransactionType != "" && !opening {
+                updates["transaction_type"] = req.TransactionType
+        }
+        if req.Description != "" {
+                updates["description"] = req.Description
+        }
+        if req.Date != "" {
+                updates["date"] = req.Date
+        }
+        if req.Category != "" {
+                updates["category"] = req.Category
+        }
+
+        var warning string
         err := s.db.Transaction(func(tx *gorm.DB) error {
-                if err := tx.Create(transaction).Error; err != nil {
+                party, err := lockParty(tx, userID, transaction.PartyID)
+                if err != nil {
+                        return err
+                }
+                if err := checkNotInvoiced(tx, transaction.ID); err != nil {
+                        return err
+                }
+                if err := checkNotStrict(tx, userID); err != nil {
+                        return err
+                }
+
+                var before models.Transaction
+                if err := tx.First(&before, "id = ?", transaction.ID).Error; err != nil {
+                        return err
+                }
+                if err := tx.Model(transaction).Updates(updates).Error; err != nil {
+                        return err
+                }
+                if err := tx.First(transaction, "id = ?", transaction.ID).Error; err != nil {
+                        return err
+                }
+                // A larger charge, or a payment turned into a charge, raises the balance
+                warning, err = s.checkCreditLimit(tx, party, transaction.SignedAmount()-before.SignedAmount(), req.OverrideCredit)
+                if err != nil {
+                        return err
+                }
+                if err := trimAllocations(tx, party, transaction); err != nil {
+                        return err
+                }
+                // What the trim freed, or a larger payment, settles open bills again
+                if _, err := autoAllocate(tx, party); err != nil {
+                        return err
+                }
+                if err := unsettleReminders(tx, transaction.ID); err != nil {
+                        return err
+                }
+                if err := settleReminders(tx, party, transaction); err != nil {
                         return err
                 }
 
-                // Update party balance
-                newBalance := party.Balance
-                if req.TransactionType == "credit" {
-                        newBalance += req.Amount
-                } else {
-                        newBalance -= req.Amount
+                // Replace the journal entry so postings follow the new amount and type
+                if err := s.journal.removeTransaction(tx, transaction.ID); err != nil {
+                        return err
+                }
+                if err := s.journal.postTransaction(tx, transaction); err != nil {
+                        return err
                 }
 
-                if err := tx.Model(&models.Party{}).Where("id = ?", req.PartyID).Update("balance", newBalance).Error; err != nil {
+                if err := s.rebalanceParty(tx, transaction.PartyID); err != nil {
                         return err
                 }
-                return nil
+                if err := chainTransaction(tx, transaction, models.AuditActionUpdate); err != nil {
+                        return err
+                }
+                return auditTransactionChange(tx, &before, models.AuditActionUpdate)
         })
 
         if err != nil {
-                return nil, apperrors.Internal("Failed to create transaction", err)
+                return nil, apperrors.FromError(err, "Failed to update transaction")
         }
-        return transaction, nil
-}
 
-// UpdateTransaction updates an existing transaction
-func (s *TransactionService) UpdateTransaction(userID, transactionID string, req *models.UpdateTransactionRequest) (*models.Transaction, *apperrors.AppError) {
-        // Verify ownership
-        if _, err := s.GetTransactionByID(userID, transactionID); err != nil {
-                return nil, err
+        transaction, appErr = s.GetTransactionByID(userID, transactionID)
+        if appErr != nil {
+                return nil, appErr
         }
-
-        transaction := &models.Transaction{}
-        if result := s.db.Model(transaction).Where("id = ? AND user_id = ?", transactionID, userID).Updates(req); result.Error != nil {
-                return nil, apperrors.Internal("Failed to update transaction", result.Error)
+        if warning != "" {
+                transaction.Warnings = append(transaction.Warnings, warning)
         }
-
-        return s.GetTransactionByID(userID, transactionID)
+        return transaction, nil
 }
 
 // GetAllTransactionsWithFilters retrieves transactions with optional filters
@@ -125,16 +255,506 @@ func (s *TransactionService) GetAllTransactionsWithFilters(userID string, filter
                 query = query.Where("date <= ?", endDate)
         }
 
-        if err := query.Find(&transactions).Error; err != nil {
+        if err := query.Order(ledgerOrder).Find(&transactions).Error; err != nil {
                 return nil, apperrors.Internal("Failed to fetch transactions", err)
         }
         return transactions, nil
 }
 
-// DeleteTransaction deletes a transaction
+// DeleteTransaction moves a transaction to the trash and rebuilds the party
+// balance without it
 func (s *TransactionService) DeleteTransaction(userID, transactionID string) *apperrors.AppError {
-        if err := s.db.Where("id = ? AND user_id = ?", transactionID, userID).Delete(&models.Transaction{}).Error; err != nil {
-                return apperrors.Internal("Failed to delete transaction", err)
+        // Verify ownership
+        transaction, appErr := s.GetTransactionByID(userID, transactionID)
+        if appErr != nil {
+                return appErr
+        }
+
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                party, err := lockParty(tx, userID, transaction.PartyID)
+                if err != nil {
+                        return err
+                }
+                if err := checkNotInvoiced(tx, transaction.ID); err != nil {
+                        return err
+                }
+                if err := checkNotStrict(tx, userID); err != nil {
+                        return err
+                }
+
+                if err := s.deleteTransaction(tx, transaction); err != nil {
+                        return err
+                }
+                // Payments freed from a deleted bill settle the remaining open bills
+                _, err = autoAllocate(tx, party)
+                return err
+        })
+
+        if err != nil {
+                return apperrors.FromError(err, "Failed to delete transaction")
+        }
+        return nil
+}
+
+// RestoreTransaction brings a transaction back from the trash, posts it to
+// the journal again and lets it settle open bills and reminders. Restoring a
+// charge is subject to the party's credit limit.
+func (s *TransactionService) RestoreTransaction(userID, transactionID string, overrideCredit bool) (*models.Transaction, *apperrors.AppError) {
+        var transaction models.Transaction
+        if err := s.db.Unscoped().Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", transactionID, userID).First(&transaction).Error; err != nil {
+                if errors.Is(err, gorm.ErrRecordNotFound) {
+                        return nil, apperrors.NotFound("Transaction not found in trash")
+                }
+                return nil, apperrors.Internal("Database error", err)
+        }
+
+        var warning string
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                party, err := lockParty(tx, userID, transaction.PartyID)
+                if err != nil {
+                        var appErr *apperrors.AppError
+                        if errors.As(err, &appErr) && appErr.Code == http.StatusNotFound {
+                                return apperrors.Conflict("The party of this transaction is in the trash; restore the party first")
+                        }
+                        return err
+                }
+                warning, err = s.checkCreditLimit(tx, party, transaction.SignedAmount(), overrideCredit)
+                if err != nil {
+                        return err
+                }
+
+                result := tx.Unscoped().Model(&models.Transaction{}).
+                        Where("id = ? AND deleted_at IS NOT NULL", transaction.ID).
+                        Update("deleted_at", nil)
+                if result.Error != nil {
+                        return result.Error
+                }
+                if result.RowsAffected == 0 {
+                        return apperrors.NotFound("Transaction not found in trash")
+                }
+
+                if err := s.journal.postTransaction(tx, &transaction); err != nil {
+                        return err
+                }
+                if err := s.rebalanceParty(tx, transaction.PartyID); err != nil {
+                        return err
+                }
+                restored := transaction
+                if err := chainTransaction(tx, &restored, models.AuditActionRestore); err != nil {
+                        return err
+                }
+                if err := auditTransactionChange(tx, &transaction, models.AuditActionRestore); err != nil {
+                        return err
+                }
+                if _, err := autoAllocate(tx, party); err != nil {
+                        return err
+                }
+                return settleReminders(tx, party, &transaction)
+        })
+
+        if err != nil {
+                return nil, apperrors.FromError(err, "Failed to restore transaction")
+        }
+        restored, appErr := s.GetTransactionByID(userID, transactionID)
+        if appErr != nil {
+                return nil, appErr
+        }
+        if warning != "" {
+                restored.Warnings = append(restored.Warnings, warning)
+        }
+        return restored, nil
+}
+
+// Reversal is the result of reversing a transaction
+type Reversal struct {
+        Original    *models.Transaction `json:"original"`
+        Reversal    *models.Transaction `json:"reversal"`
+        Replacement *models.Transaction `json:"replacement,omitempty"`
+}
+
+// ReverseTransaction cancels a transaction with a linked entry of the
+// opposite effect, leaving the original untouched, and optionally posts a
+// corrected replacement. The original and its reversal are allocated to
+// each other so neither settles anything else.
+func (s *TransactionService) ReverseTransaction(userID, transactionID string, req *models.ReverseTransactionRequest) (*Reversal, *apperrors.AppError) {
+        // Verify ownership
+        original, appErr := s.GetTransactionByID(userID, transactionID)
+        if appErr != nil {
+                return nil, appErr
+        }
+
+        result := &Reversal{Original: original}
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+                party, err := lockParty(tx, userID, original.PartyID)
+                if err != nil {
+                        return err
+                }
+                if err := tx.First(original, "id = ?", original.ID).Error; err != nil {
+                        if errors.Is(err, gorm.ErrRecordNotFound) {
+                                return apperrors.NotFound("Transaction not found")
+                        }
+                        return err
+                }
+                if err := checkNotInvoiced(tx, original.ID); err != nil {
+                        return err
+                }
+                if original.ReversalOf != nil {
+                        return apperrors.BadRequest("A reversing entry cannot itself be reversed")
+                }
+
+                // Reversals in the trash count too, so restoring one cannot reverse twice
+                var reversals int64
+                if err := tx.Unscoped().Model(&models.Transaction{}).Where("reversal_of = ?", original.ID).Count(&reversals).Error; err != nil {
+                        return err
+                }
+                if reversals > 0 {
+                        return apperrors.Conflict("Transaction has already been reversed")
+                }
+
+                result.Reversal, err = s.reverse(tx, party, original, req)
+                if err != nil {
+                        return err
+                }
+
+                if req.Replacement != nil {
+                        // The reversal moved the balance the credit limit is checked against
+                        if err := tx.First(party, "id = ?", party.ID).Error; err != nil {
+                                return err
+                        }
+                        replacement, err := s.postReplacement(tx, party, original, req.Replacement)
+                        if err != nil {
+                                return err
+                        }
+                        result.Replacement = replacement
+                }
+
+                _, err = autoAllocate(tx, party)
+                return err
+        })
+
+        if err != nil {
+                return nil, apperrors.FromError(err, "Failed to reverse transaction")
+        }
+
+        // Running balances may have moved for a backdated entry
+        for _, transaction := range []*models.Transaction{result.Original, result.Reversal, result.Replacement} {
+                if transaction == nil {
+                        continue
+                }
+                if err := s.db.First(transaction, "id = ?", transaction.ID).Error; err != nil {
+                        return nil, apperrors.Internal("Failed to fetch transaction", err)
+                }
+        }
+        return result, nil
+}
+
+// reverse posts the entry that cancels original and allocates the two to
+// each other. The caller must hold the party lock.
+func (s *TransactionService) reverse(tx *gorm.DB, party *models.Party, original *models.Transaction, req *models.ReverseTransactionRequest) (*models.Transaction, error) {
+        reversal := reversalOf(original, req)
+        // Undoing an entry only brings the balance back, so the credit limit is not checked
+        if err := s.insertTransaction(tx, reversal); err != nil {
+                return nil, err
+        }
+
+        // The pair cancels out: free what the original settled and match it
+        // against its reversal instead
+        if err := deleteAllocations(tx, original.ID); err != nil {
+                return nil, err
+        }
+        allocation := &models.Allocation{
+                UserID:    original.UserID,
+                PartyID:   party.ID,
+                PaymentID: reversal.ID,
+                BillID:    original.ID,
+                Amount:    original.Amount.Abs(),
+        }
+        if !isBill(party, original) {
+                allocation.PaymentID, allocation.BillID = original.ID, reversal.ID
+        }
+        if err := tx.Create(allocation).Error; err != nil {
+                return nil, err
+        }
+        if err := unsettleReminders(tx, original.ID); err != nil {
+                return nil, err
+        }
+        if err := settleReminders(tx, party, reversal); err != nil {
+                return nil, err
+        }
+        return reversal, nil
+}
+
+// reversalOf builds the entry that cancels a transaction. Credits and debits
+// are reversed by the other type; opening balances by one of opposite sign.
+func reversalOf(original *models.Transaction, req *models.ReverseTransactionRequest) *models.Transaction {
+        date := req.Date
+        if date == "" {
+                date = time.Now().Format("2006-01-02")
+        }
+        description := req.Description
+        if description == "" {
+                description = "Reversal of " + original.TransactionType
+                if d := stringValue(original.Description); d != "" {
+                        description = "Reversal of " + d
+                }
+        }
+
+        reversal := &models.Transaction{
+                UserID:          original.UserID,
+                PartyID:         original.PartyID,
+                Amount:          original.Amount,
+                Currency:        original.Currency,
+                TransactionType: models.TransactionTypeCredit,
+                Description:     &description,
+                Date:            date,
+                Category:        original.Category,
+                ReversalOf:      &original.ID,
+        }
+        switch original.TransactionType {
+        case models.TransactionTypeOpening:
+                reversal.Amount = -original.Amount
+                reversal.TransactionType = models.TransactionTypeOpening
+        case models.TransactionTypeCredit:
+                reversal.TransactionType = models.TransactionTypeDebit
+        }
+        return reversal
+}
+
+// postReplacement posts the corrected entry for a reversed transaction,
+// taking whatever the request leaves empty from the original. The caller
+// must hold the party lock.
+func (s *TransactionService) postReplacement(tx *gorm.DB, party *models.Party, original *models.Transaction, req *models.ReplacementTransactionRequest) (*models.Transaction, error) {
+        opening := original.TransactionType == models.TransactionTypeOpening
+        if opening && req.TransactionType != "" {
+                return nil, apperrors.BadRequest("The replacement of an opening balance is an opening balance")
+        }
+        if !opening && req.Amount != nil && *req.Amount <= 0 {
+                return nil, apperrors.BadRequest("Amount must be positive")
+        }
+
+        replacement := &models.Transaction{
+                UserID:          original.UserID,
+                PartyID:         original.PartyID,
+                Amount:          original.Amount,
+                Currency:        party.Currency,
+                TransactionType: original.TransactionType,
+                Description:     original.Description,
+                Date:            original.Date,
+                Category:        original.Category,
+                ReplacementOf:   &original.ID,
+        }
+        if req.Amount != nil {
+                replacement.Amount = *req.Amount
+        }
+        if req.TransactionType != "" {
+                replacement.TransactionType = req.TransactionType
+        }
+        if req.Description != "" {
+                replacement.Description = &req.Description
+        }
+        if req.Date != "" {
+                replacement.Date = req.Date
+        }
+        if req.Category != "" {
+                replacement.Category = &req.Category
+        }
+
+        warning, err := s.checkCreditLimit(tx, party, replacement.SignedAmount(), req.OverrideCredit)
+        if err != nil {
+                return nil, err
+        }
+        if err := s.insertTransaction(tx, replacement); err != nil {
+                return nil, err
+        }
+        if warning != "" {
+                replacement.Warnings = append(replacement.Warnings, warning)
+        }
+        if err := settleReminders(tx, party, replacement); err != nil {
+                return nil, err
+        }
+        return replacement, nil
+}
+
+// checkCreditLimit applies the user's credit policy to a change that
+// would take the party past its credit limit. It returns a warning for the
+// response, or an error when the change must be refused. The party must be
+// locked so its balance is current.
+func (s *TransactionService) checkCreditLimit(tx *gorm.DB, party *models.Party, change money.Amount, override bool) (string, error) {
+        balance := party.Balance + change
+        if party.CreditLimit <= 0 || change <= 0 || balance <= party.CreditLimit {
+                return "", nil
+        }
+
+        var user models.User
+        if err := tx.Select("id", "credit_policy").Where("id = ?", party.UserID).First(&user).Error; err != nil {
+                return "", err
+        }
+
+        message := fmt.Sprintf("Balance %s would exceed the credit limit %s of %s by %s",
+                balance, party.CreditLimit, party.Name, balance-party.CreditLimit)
+        switch user.CreditPolicy {
+        case models.CreditPolicyReject:
+                return "", apperrors.Unprocessable(message)
+        case models.CreditPolicyOverride:
+                if !override {
+                        return "", apperrors.Unprocessable(message + "; set override_credit_limit to proceed")
+                }
+        }
+        return message, nil
+}
+
+// deleteTransaction removes a transaction with its journal entry and
+// allocations and rebuilds the party balance. The caller must hold the party lock.
+func (s *TransactionService) deleteTransaction(tx *gorm.DB, transaction *models.Transaction) error {
+        if err := tx.Delete(transaction).Error; err != nil {
+                return err
+        }
+        if err := recordAudit(tx, transaction.UserID, models.AuditEntityTransaction, transaction.ID, models.AuditActionDelete, transaction, nil); err != nil {
+                return err
+        }
+        if err := chainTransaction(tx, transaction, models.AuditActionDelete); err != nil {
+                return err
+        }
+        if err := deleteAllocations(tx, transaction.ID); err != nil {
+                return err
+        }
+        if err := unsettleReminders(tx, transaction.ID); err != nil {
+                return err
+        }
+        if err := s.journal.removeTransaction(tx, transaction.ID); err != nil {
+                return err
+        }
+        return s.rebalanceParty(tx, transaction.PartyID)
+}
+
+// checkNotInvoiced rejects changes to a transaction posted from an invoice,
+// which must be changed through the invoice instead
+func checkNotInvoiced(tx *gorm.DB, transactionID string) error {
+        var count int64
+        if err := tx.Model(&models.Invoice{}).Where("transaction_id = ?", transactionID).Count(&count).Error; err != nil {
+                return err
+        }
+        if count > 0 {
+                return apperrors.BadRequest("Transaction was posted from an invoice; cancel the invoice instead")
         }
         return nil
 }
+
+// checkNotStrict rejects edits and deletions of transactions for users in
+// strict mode, whose posted transactions can only be reversed
+func checkNotStrict(tx *gorm.DB, userID string) error {
+        strict, err := strictMode(tx, userID)
+        if err != nil {
+                return err
+        }
+        if strict {
+                return apperrors.Forbidden("Strict mode is on; reverse the transaction instead")
+        }
+        return nil
+}
+
+// strictMode reports whether the user is in strict mode
+func strictMode(tx *gorm.DB, userID string) (bool, error) {
+        var user models.User
+        if err := tx.Select("id", "strict_mode").Where("id = ?", userID).First(&user).Error; err != nil {
+                return false, err
+        }
+        return user.StrictMode, nil
+}
+
+// rebalanceParty derives the party balance from its postings and rewrites
+// the running balance of every transaction in ledger order
+func (s *TransactionService) rebalanceParty(tx *gorm.DB, partyID string) error {
+        transactions, err := s.partyLedger(tx, partyID)
+        if err != nil {
+                return err
+        }
+
+        balances, _ := runningBalances(transactions)
+        for i := range transactions {
+                if transactions[i].RunningBalance != balances[i] {
+                        if err := tx.Model(&transactions[i]).UpdateColumn("running_balance", balances[i]).Error; err != nil {
+                                return err
+                        }
+                }
+        }
+
+        _, err = s.journal.syncPartyBalance(tx, partyID)
+        return err
+}
+
+// partyLedger loads the transactions of a party in ledger order
+func (s *TransactionService) partyLedger(tx *gorm.DB, partyID string) ([]models.Transaction, error) {
+        var transactions []models.Transaction
+        if err := tx.Where("party_id = ?", partyID).Order(ledgerOrder).Find(&transactions).Error; err != nil {
+                return nil, err
+        }
+        return transactions, nil
+}
+
+// runningBalances returns the party balance after each transaction and the
+// closing balance
+func runningBalances(transactions []models.Transaction) ([]money.Amount, money.Amount) {
+        balances := make([]money.Amount, len(transactions))
+        var running money.Amount
+        for i := range transactions {
+                running += transactions[i].SignedAmount()
+                balances[i] = running
+        }
+        return balances, running
+}
+
+// insertTransaction creates a transaction, posts it to the journal and
+// rebuilds the party's running balances. The caller must hold the party lock.
+func (s *TransactionService) insertTransaction(tx *gorm.DB, transaction *models.Transaction) error {
+        if err := tx.Create(transaction).Error; err != nil {
+                return err
+        }
+
+        // Post to the journal and derive the party balance from it
+        if err := s.journal.postTransaction(tx, transaction); err != nil {
+                return err
+        }
+
+        // The transaction may be backdated, so rebuild running balances in date order
+        if err := s.rebalanceParty(tx, transaction.PartyID); err != nil {
+                return err
+        }
+        if err := tx.First(transaction, "id = ?", transaction.ID).Error; err != nil {
+                return err
+        }
+        if err := chainTransaction(tx, transaction, models.AuditActionCreate); err != nil {
+                return err
+        }
+        return recordAudit(tx, transaction.UserID, models.AuditEntityTransaction, transaction.ID, models.AuditActionCreate, nil, transaction)
+}
+
+// ledgerOrder sorts a party's transactions the way its statement reads:
+// the opening balance first, then by date and entry time
+const ledgerOrder = "(transaction_type = 'opening') DESC, date, created_at, id"
+
+// lockParty loads a party owned by the user with SELECT ... FOR UPDATE, so
+// concurrent balance changes on the same party run one after another
+func lockParty(tx *gorm.DB, userID, partyID string) (*models.Party, error) {
+        var party models.Party
+        err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+                Where("id = ? AND user_id = ?", partyID, userID).
+                First(&party).Error
+        if err != nil {
+                if errors.Is(err, gorm.ErrRecordNotFound) {
+                        return nil, apperrors.NotFound("Party not found")
+                }
+                return nil, err
+        }
+        return &party, nil
+}
+
+// auditTransactionChange records a change to a transaction in the audit
+// log, reloading the transaction as it is now
+func auditTransactionChange(tx *gorm.DB, before *models.Transaction, action string) error {
+        var after models.Transaction
+        if err := tx.Unscoped().First(&after, "id = ?", before.ID).Error; err != nil {
+                return err
+        }
+        return recordAudit(tx, after.UserID, models.AuditEntityTransaction, after.ID, action, before, &after)
+}
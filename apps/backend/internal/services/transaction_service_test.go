@@ -0,0 +1,130 @@
+package services
+
+import (
+        "os"
+        "sync"
+        "testing"
+
+        "khatabook-go-backend/internal/database"
+        "khatabook-go-backend/internal/models"
+        apperrors "khatabook-go-backend/pkg/errors"
+        "khatabook-go-backend/pkg/money"
+
+        "github.com/google/uuid"
+        "gorm.io/gorm"
+)
+
+// openTestDB connects to the disposable Postgres database named by
+// TEST_DATABASE_URL, skipping the test when there is none
+func openTestDB(t *testing.T) *gorm.DB {
+        t.Helper()
+        url := os.Getenv("TEST_DATABASE_URL")
+        if url == "" {
+                t.Skip("TEST_DATABASE_URL is not set")
+        }
+        db, err := database.InitDB(url)
+        if err != nil {
+                t.Fatalf("open test database: %v", err)
+        }
+        return db
+}
+
+// createTestUser creates a user of its own for a test
+func createTestUser(t *testing.T, db *gorm.DB) *models.User {
+        t.Helper()
+        user := &models.User{
+                Email:        uuid.New().String() + "@example.test",
+                PasswordHash: "-",
+                Name:         t.Name(),
+        }
+        if err := db.Create(user).Error; err != nil {
+                t.Fatalf("create user: %v", err)
+        }
+        return user
+}
+
+func TestCreateTransactionConcurrent(t *testing.T) {
+        db := openTestDB(t)
+        user := createTestUser(t, db)
+
+        party, appErr := NewPartyService(db).CreateParty(user.ID, &models.CreatePartyRequest{
+                Name:      "Concurrent customer",
+                PartyType: models.PartyTypeCustomer,
+        })
+        if appErr != nil {
+                t.Fatalf("create party: %v", appErr)
+        }
+
+        const workers = 32
+        service := NewTransactionService(db)
+        errs := make(chan *apperrors.AppError, workers)
+        var want money.Amount
+        var wg sync.WaitGroup
+        for i := 0; i < workers; i++ {
+                req := &models.CreateTransactionRequest{
+                        PartyID:         party.ID,
+                        Amount:          money.Amount(100 * (i + 1)),
+                        TransactionType: models.TransactionTypeCredit,
+                }
+                if i%3 == 0 {
+                        req.TransactionType = models.TransactionTypeDebit
+                        want -= req.Amount
+                } else {
+                        want += req.Amount
+                }
+
+                wg.Add(1)
+                go func() {
+                        defer wg.Done()
+                        if _, appErr := service.CreateTransaction(user.ID, req); appErr != nil {
+                                errs <- appErr
+                        }
+                }()
+        }
+        wg.Wait()
+        close(errs)
+        for appErr := range errs {
+                t.Errorf("create transaction: %v", appErr)
+        }
+
+        var stored models.Party
+        if err := db.First(&stored, "id = ?", party.ID).Error; err != nil {
+                t.Fatalf("load party: %v", err)
+        }
+        if stored.Balance != want {
+                t.Errorf("party balance = %s, want %s", stored.Balance, want)
+        }
+
+        var posted money.Amount
+        var entries int64
+        if err := db.Table("postings p").
+                Select("COALESCE(SUM(p.amount), 0), COUNT(DISTINCT p.entry_id)").
+                Joins("JOIN accounts a ON a.id = p.account_id").
+                Where("a.party_id = ?", party.ID).
+                Row().Scan(&posted, &entries); err != nil {
+                t.Fatalf("sum postings: %v", err)
+        }
+        if posted != want {
+                t.Errorf("party postings = %s, want %s", posted, want)
+        }
+        if entries != workers {
+                t.Errorf("journal entries = %d, want %d", entries, workers)
+        }
+
+        ledger, err := service.partyLedger(db, party.ID)
+        if err != nil {
+                t.Fatalf("load ledger: %v", err)
+        }
+        if len(ledger) != workers {
+                t.Fatalf("transactions = %d, want %d", len(ledger), workers)
+        }
+        balances, closing := runningBalances(ledger)
+        for i := range ledger {
+                if ledger[i].RunningBalance != balances[i] {
+                        t.Errorf("running balance of transaction %d = %s, want %s", i, ledger[i].RunningBalance, balances[i])
+                }
+        }
+        if closing != want {
+                t.Errorf("closing balance = %s, want %s", closing, want)
+        }
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+        "errors"
+        "fmt"
+        "time"
+
+        "khatabook-go-backend/internal/models"
+        apperrors "khatabook-go-backend/pkg/errors"
+        "khatabook-go-backend/pkg/logger"
+
+        "gorm.io/gorm"
+        "gorm.io/gorm/clause"
+)
+
+// TrashService lists deleted parties, transactions and reminders and purges
+// them for good once they have been in the trash long enough
+type TrashService struct {
+        db *gorm.DB
+}
+
+// NewTrashService creates a new trash service
+func NewTrashService(db *gorm.DB) *TrashService {
+        return &TrashService{db: db}
+}
+
+// Trash holds the deleted items of a user, most recently deleted first.
+// Transactions and reminders trashed together with their party are listed
+// under the party only, since they come back when it is restored.
+type Trash struct {
+        Parties      []models.Party       `json:"parties"`
+        Transactions []models.Transaction `json:"transactions"`
+        Reminders    []models.Reminder    `json:"reminders"`
+}
+
+// trashedWithParty matches rows deleted in the same step as their party
+const trashedWithParty = "EXISTS (SELECT 1 FROM parties p WHERE p.id = %[1]s.party_id AND p.deleted_at = %[1]s.deleted_at)"
+
+// GetTrash retrieves the deleted items of a user
+func (s *TrashService) GetTrash(userID string) (*Trash, *apperrors.AppError) {
+        trash := &Trash{}
+        if err := s.db.Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+                Order("deleted_at DESC").Find(&trash.Parties).Error; err != nil {
+                return nil, apperrors.Internal("Failed to fetch trash", err)
+        }
+        if err := s.db.Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+                Where("NOT " + fmt.Sprintf(trashedWithParty, "transactions")).
+                Order("deleted_at DESC").Find(&trash.Transactions).Error; err != nil {
+                return nil, apperrors.Internal("Failed to fetch trash", err)
+        }
+        if err := s.db.Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+                Where("NOT " + fmt.Sprintf(trashedWithParty, "reminders")).
+                Order("deleted_at DESC").Find(&trash.Reminders).Error; err != nil {
+                return nil, apperrors.Internal("Failed to fetch trash", err)
+        }
+        return trash, nil
+}
+
+// Purge permanently deletes everything that was moved to the trash before
+// cutoff and returns how many parties, transactions and reminders went.
+// Each party goes in its own DB transaction with everything that belongs
+// to it; what was trashed with a party is only purged along with it.
+func (s *TrashService) Purge(cutoff time.Time) (int, error) {
+        purged := 0
+
+        var partyIDs []string
+        if err := s.db.Unscoped().Model(&models.Party{}).
+                Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+                Pluck("id", &partyIDs).Error; err != nil {
+                return 0, err
+        }
+        for _, partyID := range partyIDs {
+                var done bool
+                if err := s.db.Transaction(func(tx *gorm.DB) error {
+                        var err error
+                        done, err = purgeParty(tx, partyID)
+                        return err
+                }); err != nil {
+                        logger.Errorf("Purging party %s failed: %v", partyID, err)
+                        continue
+                }
+                if done {
+                        purged++
+                }
+        }
+
+        var transactionIDs []string
+        if err := s.db.Unscoped().Model(&models.Transaction{}).
+                Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+                Where("NOT "+fmt.Sprintf(trashedWithParty, "transactions")).
+                Pluck("id", &transactionIDs).Error; err != nil {
+                return purged, err
+        }
+        if len(transactionIDs) > 0 {
+                var n int
+                if err := s.db.Transaction(func(tx *gorm.DB) error {
+                        var err error
+                        n, err = purgeTransactions(tx, transactionIDs)
+                        return err
+                }); err != nil {
+                        return purged, err
+                }
+                purged += n
+        }
+
+        var reminderIDs []string
+        if err := s.db.Unscoped().Model(&models.Reminder{}).
+                Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+                Where("NOT "+fmt.Sprintf(trashedWithParty, "reminders")).
+                Pluck("id", &reminderIDs).Error; err != nil {
+                return purged, err
+        }
+        if len(reminderIDs) > 0 {
+                var n int
+                if err := s.db.Transaction(func(tx *gorm.DB) error {
+                        var err error
+                        n, err = purgeReminders(tx, reminderIDs)
+                        return err
+                }); err != nil {
+                        return purged, err
+                }
+                purged += n
+        }
+
+        return purged, nil
+}
+
+// purgeParty permanently deletes a party with its transactions, reminders,
+// invoices, recurring templates, interest terms, reminder rules and journal
+// accounts
+func purgeParty(tx *gorm.DB, partyID string) (bool, error) {
+        // The party may have been restored since it was picked
+        var party models.Party
+        err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+                Where("id = ? AND deleted_at IS NOT NULL", partyID).
+                First(&party).Error
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+                return false, nil
+        }
+        if err != nil {
+                return false, err
+        }
+
+        var transactionIDs, reminderIDs []string
+        if err := tx.Unscoped().Model(&models.Transaction{}).Where("party_id = ?", partyID).Pluck("id", &transactionIDs).Error; err != nil {
+                return false, err
+        }
+        if err := tx.Unscoped().Model(&models.Reminder{}).Where("party_id = ?", partyID).Pluck("id", &reminderIDs).Error; err != nil {
+                return false, err
+        }
+        if _, err := purgeReminders(tx, reminderIDs); err != nil {
+                return false, err
+        }
+        if _, err := purgeTransactions(tx, transactionIDs); err != nil {
+                return false, err
+        }
+
+        invoices := tx.Model(&models.Invoice{}).Select("id").Where("party_id = ?", partyID)
+        if err := tx.Where("invoice_id IN (?)", invoices).Delete(&models.InvoiceItem{}).Error; err != nil {
+                return false, err
+        }
+        if err := tx.Where("party_id = ?", partyID).Delete(&models.Invoice{}).Error; err != nil {
+                return false, err
+        }
+
+        templates := tx.Unscoped().Model(&models.RecurringTransaction{}).Select("id").Where("party_id = ?", partyID)
+        if err := tx.Where("recurring_id IN (?)", templates).Delete(&models.RecurringOccurrence{}).Error; err != nil {
+                return false, err
+        }
+        if err := tx.Unscoped().Where("party_id = ?", partyID).Delete(&models.RecurringTransaction{}).Error; err != nil {
+                return false, err
+        }
+
+        if err := tx.Unscoped().Where("party_id = ?", partyID).Delete(&models.InterestTerms{}).Error; err != nil {
+                return false, err
+        }
+        if err := tx.Unscoped().Where("party_id = ?", partyID).Delete(&models.ReminderRule{}).Error; err != nil {
+                return false, err
+        }
+        if err := tx.Where("party_id = ?", partyID).Delete(&models.Allocation{}).Error; err != nil {
+                return false, err
+        }
+
+        // Accounts still carrying postings are left for reconciliation to report
+        posted := tx.Model(&models.Posting{}).Select("account_id")
+        if err := tx.Where("party_id = ? AND id NOT IN (?)", partyID, posted).Delete(&models.Account{}).Error; err != nil {
+                return false, err
+        }
+
+        if err := recordAudit(tx, party.UserID, models.AuditEntityParty, party.ID, models.AuditActionPurge, &party, nil); err != nil {
+                return false, err
+        }
+        if err := tx.Unscoped().Where("id = ?", partyID).Delete(&models.Party{}).Error; err != nil {
+                return false, err
+        }
+        return true, nil
+}
+
+// purgeTransactions permanently deletes those of the transactions that are
+// still in the trash, with whatever still refers to them, and returns how
+// many it deleted
+func purgeTransactions(tx *gorm.DB, ids []string) (int, error) {
+        if len(ids) == 0 {
+                return 0, nil
+        }
+
+        var transactions []models.Transaction
+        if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+                Where("id IN ? AND deleted_at IS NOT NULL", ids).
+                Order("id").Find(&transactions).Error; err != nil {
+                return 0, err
+        }
+        ids = make([]string, len(transactions))
+        for i := range transactions {
+                ids[i] = transactions[i].ID
+                if err := recordAudit(tx, transactions[i].UserID, models.AuditEntityTransaction, transactions[i].ID, models.AuditActionPurge, &transactions[i], nil); err != nil {
+                        return 0, err
+                }
+                if err := chainTransaction(tx, &transactions[i], models.AuditActionPurge); err != nil {
+                        return 0, err
+                }
+        }
+        if len(ids) == 0 {
+                return 0, nil
+        }
+
+        entries := tx.Model(&models.JournalEntry{}).Select("id").Where("transaction_id IN ?", ids)
+        if err := tx.Where("entry_id IN (?)", entries).Delete(&models.Posting{}).Error; err != nil {
+                return 0, err
+        }
+        if err := tx.Where("transaction_id IN ?", ids).Delete(&models.JournalEntry{}).Error; err != nil {
+                return 0, err
+        }
+        if err := tx.Where("payment_id IN ? OR bill_id IN ?", ids, ids).Delete(&models.Allocation{}).Error; err != nil {
+                return 0, err
+        }
+        if err := tx.Where("transaction_id IN ?", ids).Delete(&models.ReminderSettlement{}).Error; err != nil {
+                return 0, err
+        }
+        if err := tx.Where("transaction_id IN ?", ids).Delete(&models.RecurringOccurrence{}).Error; err != nil {
+                return 0, err
+        }
+        if err := tx.Unscoped().Where("id IN ?", ids).Delete(&models.Transaction{}).Error; err != nil {
+                return 0, err
+        }
+        return len(ids), nil
+}
+
+// purgeReminders permanently deletes those of the reminders that are still
+// in the trash, with their deliveries, history and settlements, and returns
+// how many it deleted
+func purgeReminders(tx *gorm.DB, ids []string) (int, error) {
+        if len(ids) == 0 {
+                return 0, nil
+        }
+
+        var reminders []models.Reminder
+        if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+                Where("id IN ? AND deleted_at IS NOT NULL", ids).
+                Order("id").Find(&reminders).Error; err != nil {
+                return 0, err
+        }
+        ids = make([]string, len(reminders))
+        for i := range reminders {
+                ids[i] = reminders[i].ID
+                if err := recordAudit(tx, reminders[i].UserID, models.AuditEntityReminder, reminders[i].ID, models.AuditActionPurge, &reminders[i], nil); err != nil {
+                        return 0, err
+                }
+        }
+        if len(ids) == 0 {
+                return 0, nil
+        }
+
+        if err := tx.Where("reminder_id IN ?", ids).Delete(&models.ReminderDelivery{}).Error; err != nil {
+                return 0, err
+        }
+        if err := tx.Where("reminder_id IN ?", ids).Delete(&models.ReminderEvent{}).Error; err != nil {
+                return 0, err
+        }
+        if err := tx.Where("reminder_id IN ?", ids).Delete(&models.ReminderSettlement{}).Error; err != nil {
+                return 0, err
+        }
+        if err := tx.Unscoped().Where("id IN ?", ids).Delete(&models.Reminder{}).Error; err != nil {
+                return 0, err
+        }
+        return len(ids), nil
+}
@@ -0,0 +1,33 @@
+// Package audit carries the actor behind a change through a context, so the
+// services that record the audit log know who made it and from where.
+package audit
+
+import "context"
+
+// Actor is the person or job a change is attributed to
+type Actor struct {
+	// System is set for changes made by background jobs
+	System bool
+	UserID string
+	Device string
+	IP     string
+}
+
+// System is the actor of background jobs
+var System = Actor{System: true}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx that carries the actor
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// FromContext returns the actor carried by ctx, if any
+func FromContext(ctx context.Context) (Actor, bool) {
+	if ctx == nil {
+		return Actor{}, false
+	}
+	actor, ok := ctx.Value(actorKey{}).(Actor)
+	return actor, ok
+}
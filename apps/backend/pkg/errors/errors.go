@@ -1,6 +1,7 @@
 package errors
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 )
@@ -45,6 +46,14 @@ func Unauthorized(message string) *AppError {
 	}
 }
 
+// Forbidden creates a 403 Forbidden error
+func Forbidden(message string) *AppError {
+	return &AppError{
+		Code:    http.StatusForbidden,
+		Message: message,
+	}
+}
+
 // NotFound creates a 404 Not Found error
 func NotFound(message string) *AppError {
 	return &AppError{
@@ -61,6 +70,14 @@ func Conflict(message string) *AppError {
 	}
 }
 
+// Unprocessable creates a 422 Unprocessable Entity error
+func Unprocessable(message string) *AppError {
+	return &AppError{
+		Code:    http.StatusUnprocessableEntity,
+		Message: message,
+	}
+}
+
 // Internal creates a 500 Internal Server Error
 func Internal(message string, err error) *AppError {
 	return &AppError{
@@ -70,6 +87,16 @@ func Internal(message string, err error) *AppError {
 	}
 }
 
+// FromError returns err as an AppError when it already is one, otherwise it
+// wraps it as a 500 Internal Server Error with the given message
+func FromError(err error, message string) *AppError {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(message, err)
+}
+
 // ToResponse converts error to response map
 func (e *AppError) ToResponse() map[string]interface{} {
 	return map[string]interface{}{
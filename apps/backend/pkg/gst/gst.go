@@ -0,0 +1,142 @@
+// Package gst computes Indian Goods and Services Tax on invoice lines.
+//
+// Tax is computed per line on the taxable value and rounded to the paisa,
+// half away from zero. An intra-state supply splits the rate equally into
+// CGST and SGST, each rounded on its own so both halves are always equal; an
+// inter-state supply charges the whole rate as IGST. The invoice total may
+// then be rounded to the nearest rupee, with the difference shown as a
+// round-off amount.
+package gst
+
+import (
+	"fmt"
+	"strconv"
+
+	"khatabook-go-backend/pkg/money"
+)
+
+// validRates lists the GST slabs, in hundredths of a percent
+var validRates = map[money.Percent]bool{
+	0: true, 10: true, 25: true, 100: true, 150: true, 300: true,
+	500: true, 600: true, 750: true, 1200: true, 1800: true, 2800: true,
+}
+
+// ValidRate reports whether a percentage is a GST slab
+func ValidRate(rate money.Percent) bool {
+	return validRates[rate]
+}
+
+// ValidStateCode reports whether code is a two-digit GST state code. Codes
+// 01-38 are states and union territories, 96 is a supply outside India and
+// 97 is other territory.
+func ValidStateCode(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	n, err := strconv.Atoi(code)
+	if err != nil {
+		return false
+	}
+	return (n >= 1 && n <= 38) || n == 96 || n == 97
+}
+
+// ValidHSN reports whether code is a plausible HSN or SAC code of 4 to 8 digits
+func ValidHSN(code string) bool {
+	if len(code) < 4 || len(code) > 8 {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Interstate reports whether a supply from one state to a place of supply
+// is charged IGST. An unknown supplier state is treated as intra-state.
+func Interstate(supplierState, placeOfSupply string) bool {
+	return supplierState != "" && placeOfSupply != "" && supplierState != placeOfSupply
+}
+
+// Line is the input of a single invoice line
+type Line struct {
+	Quantity money.Quantity
+	Rate     money.Amount
+	Discount money.Amount
+	GSTRate  money.Percent
+}
+
+// LineTax is the computed value of a single invoice line
+type LineTax struct {
+	Taxable money.Amount
+	CGST    money.Amount
+	SGST    money.Amount
+	IGST    money.Amount
+	Total   money.Amount
+}
+
+// ComputeLine computes the taxable value and tax of a line
+func ComputeLine(line Line, interstate bool) (LineTax, error) {
+	if !ValidRate(line.GSTRate) {
+		return LineTax{}, fmt.Errorf("%s%% is not a GST rate", line.GSTRate)
+	}
+
+	gross := line.Quantity.Times(line.Rate)
+	if line.Discount > gross {
+		return LineTax{}, fmt.Errorf("discount %s exceeds line value %s", line.Discount, gross)
+	}
+
+	tax := LineTax{Taxable: gross - line.Discount}
+	if interstate {
+		tax.IGST = line.GSTRate.Of(tax.Taxable)
+	} else {
+		// Each half of the rate is applied separately so CGST equals SGST
+		tax.CGST = tax.Taxable.MulDiv(int64(line.GSTRate), 2*10000)
+		tax.SGST = tax.CGST
+	}
+	tax.Total = tax.Taxable + tax.CGST + tax.SGST + tax.IGST
+	return tax, nil
+}
+
+// Totals is the computed summary of an invoice
+type Totals struct {
+	Taxable  money.Amount
+	CGST     money.Amount
+	SGST     money.Amount
+	IGST     money.Amount
+	RoundOff money.Amount
+	Total    money.Amount
+}
+
+// Sum adds up computed lines. With roundOff set, the total is rounded to the
+// nearest rupee, half away from zero.
+func Sum(lines []LineTax, roundOff bool) Totals {
+	var totals Totals
+	for _, line := range lines {
+		totals.Taxable += line.Taxable
+		totals.CGST += line.CGST
+		totals.SGST += line.SGST
+		totals.IGST += line.IGST
+	}
+
+	exact := totals.Taxable + totals.CGST + totals.SGST + totals.IGST
+	totals.Total = exact
+	if roundOff {
+		totals.Total = roundRupee(exact)
+		totals.RoundOff = totals.Total - exact
+	}
+	return totals
+}
+
+// roundRupee rounds an amount to a whole rupee, half away from zero
+func roundRupee(a money.Amount) money.Amount {
+	rupees := a / 100
+	rem := a % 100
+	if rem >= 50 {
+		rupees++
+	} else if rem <= -50 {
+		rupees--
+	}
+	return rupees * 100
+}
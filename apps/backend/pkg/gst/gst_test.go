@@ -0,0 +1,156 @@
+package gst
+
+import (
+	"testing"
+
+	"khatabook-go-backend/pkg/money"
+)
+
+func TestComputeLine(t *testing.T) {
+	tests := []struct {
+		name       string
+		line       Line
+		interstate bool
+		want       LineTax
+		wantErr    bool
+	}{
+		{
+			name: "intra-state 18%",
+			line: Line{Quantity: 1000, Rate: 10000, GSTRate: 1800},
+			want: LineTax{Taxable: 10000, CGST: 900, SGST: 900, Total: 11800},
+		},
+		{
+			name:       "inter-state 18%",
+			line:       Line{Quantity: 1000, Rate: 10000, GSTRate: 1800},
+			interstate: true,
+			want:       LineTax{Taxable: 10000, IGST: 1800, Total: 11800},
+		},
+		{
+			name: "halves rounded separately",
+			line: Line{Quantity: 3000, Rate: 333, GSTRate: 500},
+			want: LineTax{Taxable: 999, CGST: 25, SGST: 25, Total: 1049},
+		},
+		{
+			name:       "whole rate rounded once",
+			line:       Line{Quantity: 3000, Rate: 333, GSTRate: 500},
+			interstate: true,
+			want:       LineTax{Taxable: 999, IGST: 50, Total: 1049},
+		},
+		{
+			name: "fractional quantity and discount",
+			line: Line{Quantity: 2500, Rate: 199, Discount: 98, GSTRate: 1200},
+			want: LineTax{Taxable: 400, CGST: 24, SGST: 24, Total: 448},
+		},
+		{
+			name: "quarter percent slab",
+			line: Line{Quantity: 1000, Rate: 10000, GSTRate: 25},
+			want: LineTax{Taxable: 10000, CGST: 13, SGST: 13, Total: 10026},
+		},
+		{
+			name: "exempt",
+			line: Line{Quantity: 1000, Rate: 10000, GSTRate: 0},
+			want: LineTax{Taxable: 10000, Total: 10000},
+		},
+		{
+			name:    "rate that is not a slab",
+			line:    Line{Quantity: 1000, Rate: 10000, GSTRate: 1300},
+			wantErr: true,
+		},
+		{
+			name:    "discount above line value",
+			line:    Line{Quantity: 1000, Rate: 100, Discount: 101, GSTRate: 1800},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ComputeLine(tt.line, tt.interstate)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ComputeLine() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ComputeLine() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSum(t *testing.T) {
+	lines := []LineTax{
+		{Taxable: 10000, CGST: 900, SGST: 900, Total: 11800},
+		{Taxable: 41, CGST: 4, SGST: 4, Total: 49},
+	}
+	tests := []struct {
+		name     string
+		lines    []LineTax
+		roundOff bool
+		want     Totals
+	}{
+		{"exact", lines, false, Totals{Taxable: 10041, CGST: 904, SGST: 904, Total: 11849}},
+		{"rounded down", lines, true, Totals{Taxable: 10041, CGST: 904, SGST: 904, RoundOff: -49, Total: 11800}},
+		{"rounded up at half", []LineTax{{Taxable: 150}}, true, Totals{Taxable: 150, RoundOff: 50, Total: 200}},
+		{"negative half away from zero", []LineTax{{Taxable: -150}}, true, Totals{Taxable: -150, RoundOff: -50, Total: -200}},
+		{"no lines", nil, true, Totals{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sum(tt.lines, tt.roundOff); got != tt.want {
+				t.Errorf("Sum() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidStateCode(t *testing.T) {
+	tests := map[string]bool{
+		"01": true, "27": true, "38": true, "96": true, "97": true,
+		"00": false, "39": false, "95": false, "7": false, "ab": false, "270": false,
+	}
+	for code, want := range tests {
+		if got := ValidStateCode(code); got != want {
+			t.Errorf("ValidStateCode(%q) = %t, want %t", code, got, want)
+		}
+	}
+}
+
+func TestValidHSN(t *testing.T) {
+	tests := map[string]bool{
+		"1234": true, "998314": true, "12345678": true,
+		"123": false, "123456789": false, "12a4": false, "": false,
+	}
+	for code, want := range tests {
+		if got := ValidHSN(code); got != want {
+			t.Errorf("ValidHSN(%q) = %t, want %t", code, got, want)
+		}
+	}
+}
+
+func TestValidRate(t *testing.T) {
+	for _, rate := range []money.Percent{0, 25, 300, 500, 1200, 1800, 2800} {
+		if !ValidRate(rate) {
+			t.Errorf("ValidRate(%s) = false", rate)
+		}
+	}
+	for _, rate := range []money.Percent{1, 1300, 2000, -1800} {
+		if ValidRate(rate) {
+			t.Errorf("ValidRate(%s) = true", rate)
+		}
+	}
+}
+
+func TestInterstate(t *testing.T) {
+	tests := []struct {
+		supplier, place string
+		want            bool
+	}{
+		{"27", "27", false},
+		{"27", "29", true},
+		{"", "29", false},
+		{"27", "", false},
+	}
+	for _, tt := range tests {
+		if got := Interstate(tt.supplier, tt.place); got != tt.want {
+			t.Errorf("Interstate(%q, %q) = %t, want %t", tt.supplier, tt.place, got, tt.want)
+		}
+	}
+}
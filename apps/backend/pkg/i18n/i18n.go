@@ -0,0 +1,122 @@
+// Package i18n renders reminder messages in the languages the app supports.
+// Messages are templates with {{placeholder}} fields; amounts use Indian
+// digit grouping.
+package i18n
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"khatabook-go-backend/pkg/money"
+)
+
+// DefaultLanguage is used when neither the party nor the user has one
+const DefaultLanguage = "en"
+
+// Placeholders that reminder templates may use
+const (
+	PartyName    = "party_name"
+	Amount       = "amount"
+	DueDate      = "due_date"
+	BusinessName = "business_name"
+)
+
+// Placeholders lists every placeholder a template may use
+var Placeholders = []string{PartyName, Amount, DueDate, BusinessName}
+
+// Template is a reminder message with an email subject
+type Template struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// defaultReminders are the built-in reminder messages by language
+var defaultReminders = map[string]Template{
+	"en": {
+		Subject: "Payment reminder from {{business_name}}",
+		Body:    "Dear {{party_name}}, this is a reminder that {{amount}} is due on {{due_date}}. Please pay at the earliest. - {{business_name}}",
+	},
+	"hi": {
+		Subject: "{{business_name}} की ओर से भुगतान अनुस्मारक",
+		Body:    "प्रिय {{party_name}}, आपका {{amount}} का भुगतान {{due_date}} को देय है। कृपया जल्द से जल्द भुगतान करें। - {{business_name}}",
+	},
+	"mr": {
+		Subject: "{{business_name}} कडून देय रकमेची आठवण",
+		Body:    "प्रिय {{party_name}}, आपली {{amount}} ही रक्कम {{due_date}} रोजी देय आहे. कृपया लवकरात लवकर भरणा करावा. - {{business_name}}",
+	},
+}
+
+// Languages returns the languages with a built-in reminder, sorted
+func Languages() []string {
+	languages := make([]string, 0, len(defaultReminders))
+	for language := range defaultReminders {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// DefaultReminder returns the built-in reminder in language, falling back to
+// English
+func DefaultReminder(language string) Template {
+	if t, ok := defaultReminders[language]; ok {
+		return t
+	}
+	return defaultReminders[DefaultLanguage]
+}
+
+var placeholder = regexp.MustCompile(`{{\s*([a-z_]+)\s*}}`)
+
+// Validate checks that text uses only known placeholders
+func Validate(text string) error {
+	for _, match := range placeholder.FindAllStringSubmatch(text, -1) {
+		if !isPlaceholder(match[1]) {
+			return fmt.Errorf("unknown placeholder {{%s}}; use one of %s", match[1], strings.Join(Placeholders, ", "))
+		}
+	}
+	return nil
+}
+
+// Render replaces the placeholders in text with their values. Unknown
+// placeholders are left as they are.
+func Render(text string, values map[string]string) string {
+	return placeholder.ReplaceAllStringFunc(text, func(match string) string {
+		name := placeholder.FindStringSubmatch(match)[1]
+		if value, ok := values[name]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// FormatAmount formats an amount for a message. Rupees use the rupee sign and
+// Indian digit grouping; other currencies use their code and thousands
+// separators.
+func FormatAmount(amount money.Amount, currency string) string {
+	if currency == "" || currency == "INR" {
+		return "₹" + amount.Indian()
+	}
+	return currency + " " + amount.Grouped()
+}
+
+// FormatDate formats a YYYY-MM-DD date as DD/MM/YYYY, the usual order in
+// India. Other strings are returned unchanged.
+func FormatDate(date string) string {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return day.Format("02/01/2006")
+}
+
+func isPlaceholder(name string) bool {
+	for _, p := range Placeholders {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{`a\b`, `a\\b`},
+		{"Pay 500; thanks, Ravi", `Pay 500\; thanks\, Ravi`},
+		{"line one\nline two", `line one\nline two`},
+		{"crlf\r\nend", `crlf\nend`},
+		{"stray\rcr", "straycr"},
+	}
+	for _, tt := range tests {
+		if got := escape(tt.in); got != tt.want {
+			t.Errorf("escape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteFolded(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"short", "SUMMARY:Pay"},
+		{"exactly 75 octets", "SUMMARY:" + strings.Repeat("x", 67)},
+		{"76 octets", "SUMMARY:" + strings.Repeat("x", 68)},
+		{"several folds", "DESCRIPTION:" + strings.Repeat("abcdefghij", 30)},
+		{"multi-byte characters", "SUMMARY:" + strings.Repeat("₹ बकाया ", 20)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writeFolded(&buf, tt.line)
+			out := buf.String()
+			if !strings.HasSuffix(out, "\r\n") {
+				t.Fatalf("line does not end with CRLF: %q", out)
+			}
+
+			lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			var unfolded strings.Builder
+			for i, l := range lines {
+				if len(l) > maxLineOctets {
+					t.Errorf("line %d is %d octets", i, len(l))
+				}
+				if !utf8.ValidString(l) {
+					t.Errorf("line %d splits a character: %q", i, l)
+				}
+				if i > 0 {
+					if !strings.HasPrefix(l, " ") {
+						t.Fatalf("continuation line %d does not start with a space", i)
+					}
+					l = l[1:]
+				}
+				unfolded.WriteString(l)
+			}
+			if unfolded.String() != tt.line {
+				t.Errorf("unfolded line = %q, want %q", unfolded.String(), tt.line)
+			}
+			if len(tt.line) <= maxLineOctets && len(lines) != 1 {
+				t.Errorf("a %d octet line was folded", len(tt.line))
+			}
+		})
+	}
+}
+
+func TestCalendarBytes(t *testing.T) {
+	modified := time.Date(2024, 3, 1, 10, 30, 0, 0, time.FixedZone("IST", 19800))
+	cal := &Calendar{
+		ProductID: "-//Khatabook Pro//Reminders//EN",
+		Name:      "Reminders, shop",
+		Events: []Event{{
+			UID:          "r-1@khatabook",
+			Date:         time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+			Summary:      "Collect 500; Ravi",
+			LastModified: modified,
+		}},
+	}
+	out := string(cal.Bytes())
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:Reminders\\, shop\r\n",
+		"UID:r-1@khatabook\r\n",
+		"DTSTAMP:20240301T050000Z\r\n",
+		"DTSTART;VALUE=DATE:20240331\r\n",
+		"DTEND;VALUE=DATE:20240401\r\n",
+		"SUMMARY:Collect 500\\; Ravi\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("feed lacks %q", want)
+		}
+	}
+	if strings.Contains(out, "DESCRIPTION") {
+		t.Error("empty description was written")
+	}
+	if strings.Count(out, "BEGIN:VEVENT") != 1 || strings.Count(out, "END:VEVENT") != 1 {
+		t.Error("feed does not hold exactly one event")
+	}
+}
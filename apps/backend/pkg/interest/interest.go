@@ -0,0 +1,154 @@
+// Package interest computes interest on overdue party balances.
+//
+// Every charge to the party becomes interest-bearing once its grace period
+// has run out, on the day after the last grace day. Payments reduce the
+// interest-bearing balance from the day they are made. Interest accrues
+// daily on the positive part of that balance at an annual rate on a
+// 365-day year. Compound terms capitalise the accrued interest at the end
+// of every calendar month, so it bears interest from the following day.
+package interest
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"khatabook-go-backend/pkg/money"
+)
+
+// Methods
+const (
+	Simple   = "simple"
+	Compound = "compound"
+)
+
+const dateLayout = "2006-01-02"
+
+// Terms are the interest terms agreed with a party
+type Terms struct {
+	Rate      money.Percent // annual rate
+	GraceDays int
+	Method    string
+}
+
+// Movement is a dated change of the party balance. Positive amounts are
+// charges to the party and negative amounts are payments.
+type Movement struct {
+	Date   string
+	Amount money.Amount
+}
+
+// Segment is a run of days on which the interest-bearing balance did not
+// change
+type Segment struct {
+	From     string       `json:"from"`
+	To       string       `json:"to"`
+	Days     int          `json:"days"`
+	Balance  money.Amount `json:"balance"`
+	Interest money.Amount `json:"interest"`
+}
+
+// Result is the interest for a period
+type Result struct {
+	From     string       `json:"from"`
+	To       string       `json:"to"`
+	Interest money.Amount `json:"interest"`
+	Segments []Segment    `json:"segments"`
+}
+
+type event struct {
+	day    time.Time
+	amount money.Amount
+}
+
+// Compute returns the interest accrued from the start of from to the end of
+// to. Movements may be in any order and may precede from.
+func Compute(movements []Movement, terms Terms, from, to string) (*Result, error) {
+	start, err := time.Parse(dateLayout, from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from date %q", from)
+	}
+	end, err := time.Parse(dateLayout, to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to date %q", to)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("from must not be after to")
+	}
+	if terms.Method != Simple && terms.Method != Compound {
+		return nil, fmt.Errorf("unknown interest method %q", terms.Method)
+	}
+
+	events := make([]event, 0, len(movements))
+	for _, m := range movements {
+		day, err := time.Parse(dateLayout, m.Date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid movement date %q", m.Date)
+		}
+		if m.Amount > 0 {
+			day = day.AddDate(0, 0, terms.GraceDays+1)
+		}
+		events = append(events, event{day: day, amount: m.Amount})
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].day.Before(events[j].day) })
+
+	result := &Result{From: from, To: to, Segments: []Segment{}}
+	var balance, accrued money.Amount
+	next := 0
+
+	// Apply everything effective before the period
+	for next < len(events) && !events[next].day.After(start) {
+		balance += events[next].amount
+		next++
+	}
+
+	day := start
+	for !day.After(end) {
+		// The segment runs until the next balance change, month end or period end
+		segmentEnd := end
+		if next < len(events) && events[next].day.AddDate(0, 0, -1).Before(segmentEnd) {
+			segmentEnd = events[next].day.AddDate(0, 0, -1)
+		}
+		if terms.Method == Compound {
+			if monthEnd := endOfMonth(day); monthEnd.Before(segmentEnd) {
+				segmentEnd = monthEnd
+			}
+		}
+
+		days := int(segmentEnd.Sub(day).Hours()/24) + 1
+		bearing := balance
+		if bearing < 0 {
+			bearing = 0
+		}
+		amount := bearing.MulDiv(int64(terms.Rate)*int64(days), 365*10000)
+		if bearing > 0 {
+			result.Segments = append(result.Segments, Segment{
+				From:     day.Format(dateLayout),
+				To:       segmentEnd.Format(dateLayout),
+				Days:     days,
+				Balance:  bearing,
+				Interest: amount,
+			})
+		}
+		result.Interest += amount
+		accrued += amount
+
+		// Capitalise at month end so the interest bears interest itself
+		if terms.Method == Compound && segmentEnd.Equal(endOfMonth(segmentEnd)) {
+			balance += accrued
+			accrued = 0
+		}
+
+		day = segmentEnd.AddDate(0, 0, 1)
+		for next < len(events) && !events[next].day.After(day) {
+			balance += events[next].amount
+			next++
+		}
+	}
+
+	return result, nil
+}
+
+func endOfMonth(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,115 @@
+package interest
+
+import "testing"
+
+func TestCompute(t *testing.T) {
+	// 18.25% a year on 1000.00 is 0.50 a day
+	charge := Movement{Date: "2024-01-01", Amount: 100000}
+	tests := []struct {
+		name      string
+		movements []Movement
+		terms     Terms
+		from, to  string
+		want      int64
+		segments  int
+	}{
+		{
+			name:      "simple from the day after the charge",
+			movements: []Movement{charge},
+			terms:     Terms{Rate: 1825, Method: Simple},
+			from:      "2024-01-01", to: "2024-01-31",
+			want: 1500, segments: 1,
+		},
+		{
+			name:      "grace period",
+			movements: []Movement{charge},
+			terms:     Terms{Rate: 1825, GraceDays: 10, Method: Simple},
+			from:      "2024-01-01", to: "2024-01-31",
+			want: 1000, segments: 1,
+		},
+		{
+			name:      "payment reduces the balance from its day",
+			movements: []Movement{{Date: "2024-01-16", Amount: -50000}, charge},
+			terms:     Terms{Rate: 1825, Method: Simple},
+			from:      "2024-01-01", to: "2024-01-31",
+			want: 700 + 400, segments: 2,
+		},
+		{
+			name:      "overpaid balance bears nothing",
+			movements: []Movement{charge, {Date: "2024-01-01", Amount: -150000}},
+			terms:     Terms{Rate: 1825, Method: Simple},
+			from:      "2024-01-01", to: "2024-01-31",
+			want: 0, segments: 0,
+		},
+		{
+			name:      "charge before the period",
+			movements: []Movement{{Date: "2023-12-01", Amount: 100000}},
+			terms:     Terms{Rate: 1825, Method: Simple},
+			from:      "2024-01-01", to: "2024-01-31",
+			want: 1550, segments: 1,
+		},
+		{
+			name:      "simple over a month end",
+			movements: []Movement{charge},
+			terms:     Terms{Rate: 1825, Method: Simple},
+			from:      "2024-01-01", to: "2024-02-29",
+			want: 1500 + 1450, segments: 1,
+		},
+		{
+			name:      "compound capitalises at month end",
+			movements: []Movement{charge},
+			terms:     Terms{Rate: 1825, Method: Compound},
+			from:      "2024-01-01", to: "2024-02-29",
+			want: 1500 + 1472, segments: 2,
+		},
+		{
+			name:      "single day",
+			movements: []Movement{charge},
+			terms:     Terms{Rate: 1825, Method: Simple},
+			from:      "2024-01-02", to: "2024-01-02",
+			want: 50, segments: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Compute(tt.movements, tt.terms, tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if int64(result.Interest) != tt.want {
+				t.Errorf("interest = %d, want %d", result.Interest, tt.want)
+			}
+			if len(result.Segments) != tt.segments {
+				t.Errorf("segments = %+v, want %d", result.Segments, tt.segments)
+			}
+			var sum int64
+			for _, segment := range result.Segments {
+				sum += int64(segment.Interest)
+			}
+			if sum != int64(result.Interest) {
+				t.Errorf("segments add up to %d, interest is %d", sum, result.Interest)
+			}
+		})
+	}
+}
+
+func TestComputeErrors(t *testing.T) {
+	terms := Terms{Rate: 1800, Method: Simple}
+	tests := []struct {
+		name      string
+		movements []Movement
+		terms     Terms
+		from, to  string
+	}{
+		{"bad from", nil, terms, "2024-13-01", "2024-12-31"},
+		{"bad to", nil, terms, "2024-01-01", "tomorrow"},
+		{"to before from", nil, terms, "2024-02-01", "2024-01-31"},
+		{"unknown method", nil, Terms{Rate: 1800, Method: "daily"}, "2024-01-01", "2024-01-31"},
+		{"bad movement date", []Movement{{Date: "01/01/2024", Amount: 100}}, terms, "2024-01-01", "2024-01-31"},
+	}
+	for _, tt := range tests {
+		if _, err := Compute(tt.movements, tt.terms, tt.from, tt.to); err == nil {
+			t.Errorf("%s: Compute succeeded", tt.name)
+		}
+	}
+}
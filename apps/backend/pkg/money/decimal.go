@@ -0,0 +1,149 @@
+package money
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// quantityDigits is the number of fraction digits kept for quantities
+const quantityDigits = 3
+
+// percentDigits is the number of fraction digits kept for percentages
+const percentDigits = 2
+
+// Quantity is a count of units held as an integer scaled by 10^3, so that
+// weights such as 1.250 kg are exact
+type Quantity int64
+
+// ParseQuantity parses a decimal string such as "2.5" into a quantity
+func ParseQuantity(s string) (Quantity, error) {
+	v, err := parseFixed(s, quantityDigits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	return Quantity(v), nil
+}
+
+// String formats the quantity as a decimal without trailing zeros
+func (q Quantity) String() string {
+	return trimFixed(int64(q), quantityDigits)
+}
+
+// Times returns the amount for q units at a unit price, rounded half away
+// from zero to the nearest minor unit
+func (q Quantity) Times(price Amount) Amount {
+	return Amount(mulScaled(int64(price), int64(q), quantityDigits))
+}
+
+// MarshalJSON encodes the quantity as a JSON number
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return []byte(q.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a quoted decimal string
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	s, ok := jsonDecimal(data)
+	if !ok {
+		return nil
+	}
+	v, err := ParseQuantity(s)
+	if err != nil {
+		return err
+	}
+	*q = v
+	return nil
+}
+
+// Value stores the quantity as a scaled bigint
+func (q Quantity) Value() (driver.Value, error) {
+	return int64(q), nil
+}
+
+// Scan reads a scaled quantity from the database
+func (q *Quantity) Scan(src interface{}) error {
+	var a Amount
+	if err := a.Scan(src); err != nil {
+		return fmt.Errorf("cannot scan %v into money.Quantity", src)
+	}
+	*q = Quantity(a)
+	return nil
+}
+
+// Percent is a percentage held as an integer scaled by 10^2, so 18% is 1800
+// and 0.25% is 25
+type Percent int64
+
+// ParsePercent parses a decimal string such as "12.5" into a percentage
+func ParsePercent(s string) (Percent, error) {
+	v, err := parseFixed(s, percentDigits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid percentage %q", s)
+	}
+	return Percent(v), nil
+}
+
+// String formats the percentage as a decimal without trailing zeros
+func (p Percent) String() string {
+	return trimFixed(int64(p), percentDigits)
+}
+
+// Of returns p percent of an amount, rounded half away from zero to the
+// nearest minor unit
+func (p Percent) Of(a Amount) Amount {
+	return Amount(mulScaled(int64(a), int64(p), percentDigits+2))
+}
+
+// MarshalJSON encodes the percentage as a JSON number
+func (p Percent) MarshalJSON() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a quoted decimal string
+func (p *Percent) UnmarshalJSON(data []byte) error {
+	s, ok := jsonDecimal(data)
+	if !ok {
+		return nil
+	}
+	v, err := ParsePercent(s)
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
+
+// Value stores the percentage as a scaled bigint
+func (p Percent) Value() (driver.Value, error) {
+	return int64(p), nil
+}
+
+// Scan reads a scaled percentage from the database
+func (p *Percent) Scan(src interface{}) error {
+	var a Amount
+	if err := a.Scan(src); err != nil {
+		return fmt.Errorf("cannot scan %v into money.Percent", src)
+	}
+	*p = Percent(a)
+	return nil
+}
+
+// trimFixed formats a scaled integer without trailing fraction zeros
+func trimFixed(v int64, scale int) string {
+	s := strings.TrimRight(formatFixed(v, scale), "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// jsonDecimal extracts the decimal text of a JSON number or quoted string,
+// reporting false for null
+func jsonDecimal(data []byte) (string, bool) {
+	s := string(data)
+	if s == "null" {
+		return "", false
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	return s, true
+}
@@ -0,0 +1,110 @@
+package money
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var errInvalidNumber = errors.New("invalid number")
+
+// parseFixed parses a decimal string into an integer scaled by 10^scale,
+// rounding half away from zero. Exponent notation is accepted.
+func parseFixed(s string, scale int) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errInvalidNumber
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, errInvalidNumber
+	}
+	r.Mul(r, new(big.Rat).SetInt(pow10(scale)))
+
+	num := new(big.Int).Set(r.Num())
+	den := r.Denom()
+	neg := num.Sign() < 0
+	num.Abs(num)
+
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Mul(rem, big.NewInt(2)).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if neg {
+		q.Neg(q)
+	}
+	if !q.IsInt64() {
+		return 0, errInvalidNumber
+	}
+	return q.Int64(), nil
+}
+
+// formatFixed renders an integer scaled by 10^scale as a decimal string
+func formatFixed(v int64, scale int) string {
+	if scale == 0 {
+		return strconv.FormatInt(v, 10)
+	}
+	sign := ""
+	u := new(big.Int).SetInt64(v)
+	if u.Sign() < 0 {
+		sign = "-"
+		u.Abs(u)
+	}
+	digits := u.String()
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	cut := len(digits) - scale
+	return sign + digits[:cut] + "." + digits[cut:]
+}
+
+// group inserts commas into the integer part of a decimal string. The last
+// three digits form one group and the rest are split into groups of size.
+func group(s string, size int) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, fraction := s, ""
+	if dot := strings.IndexByte(s, '.'); dot >= 0 {
+		whole, fraction = s[:dot], s[dot:]
+	}
+	if len(whole) <= 3 {
+		return sign + whole + fraction
+	}
+
+	head, tail := whole[:len(whole)-3], whole[len(whole)-3:]
+	var groups []string
+	for len(head) > size {
+		groups = append([]string{head[len(head)-size:]}, groups...)
+		head = head[:len(head)-size]
+	}
+	groups = append([]string{head}, groups...)
+	return sign + strings.Join(groups, ",") + "," + tail + fraction
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// mulScaled returns a*b/10^scale rounded half away from zero
+func mulScaled(a, b int64, scale int) int64 {
+	return mulDiv(a, b, pow10(scale))
+}
+
+// mulDiv returns a*b/den rounded half away from zero
+func mulDiv(a, b int64, den *big.Int) int64 {
+	product := new(big.Int).Mul(big.NewInt(a), big.NewInt(b))
+	neg := product.Sign() < 0
+	product.Abs(product)
+
+	q, rem := new(big.Int).QuoRem(product, den, new(big.Int))
+	if rem.Mul(rem, big.NewInt(2)).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if neg {
+		q.Neg(q)
+	}
+	return q.Int64()
+}
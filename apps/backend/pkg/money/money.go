@@ -0,0 +1,135 @@
+package money
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+// DefaultCurrency is the currency assumed for amounts that carry no code
+const DefaultCurrency = "INR"
+
+// minorDigits is the number of minor-unit digits (paise, cents, fils)
+const minorDigits = 2
+
+// Amount is a monetary value held as an integer number of minor units
+// (paise for INR). It serialises to JSON as a plain decimal number so API
+// clients keep seeing values like 1250.50.
+type Amount int64
+
+// FromMinor creates an amount from a count of minor units
+func FromMinor(minor int64) Amount {
+	return Amount(minor)
+}
+
+// Parse parses a decimal string such as "1250.5" into an amount, rounding
+// half away from zero to the nearest minor unit
+func Parse(s string) (Amount, error) {
+	v, err := parseFixed(s, minorDigits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return Amount(v), nil
+}
+
+// Minor returns the amount as a count of minor units
+func (a Amount) Minor() int64 {
+	return int64(a)
+}
+
+// Abs returns the absolute value of the amount
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// Neg returns the amount with its sign flipped
+func (a Amount) Neg() Amount {
+	return -a
+}
+
+// MulDiv returns a*num/den rounded half away from zero to the nearest minor
+// unit. It is used to take fractional shares of an amount without losing
+// precision to an intermediate rounding.
+func (a Amount) MulDiv(num, den int64) Amount {
+	return Amount(mulDiv(int64(a), num, big.NewInt(den)))
+}
+
+// String formats the amount as a decimal with two fraction digits
+func (a Amount) String() string {
+	return formatFixed(int64(a), minorDigits)
+}
+
+// Indian formats the amount with Indian digit grouping, where the last
+// three digits are followed by groups of two: 12,34,567.89
+func (a Amount) Indian() string {
+	return group(a.String(), 2)
+}
+
+// Grouped formats the amount with thousands separators: 1,234,567.89
+func (a Amount) Grouped() string {
+	return group(a.String(), 3)
+}
+
+// MarshalJSON encodes the amount as a JSON number
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a quoted decimal string
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// Value stores the amount as a bigint of minor units
+func (a Amount) Value() (driver.Value, error) {
+	return int64(a), nil
+}
+
+// Scan reads an amount of minor units from the database. Aggregates such as
+// SUM(bigint) come back as numeric text and are accepted as well.
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = 0
+	case int64:
+		*a = Amount(v)
+	case float64:
+		minor, err := parseFixed(strconv.FormatFloat(v, 'f', -1, 64), 0)
+		if err != nil {
+			return err
+		}
+		*a = Amount(minor)
+	case []byte:
+		return a.scanText(string(v))
+	case string:
+		return a.scanText(v)
+	default:
+		return fmt.Errorf("cannot scan %T into money.Amount", src)
+	}
+	return nil
+}
+
+func (a *Amount) scanText(s string) error {
+	minor, err := parseFixed(s, 0)
+	if err != nil {
+		return fmt.Errorf("cannot scan %q into money.Amount", s)
+	}
+	*a = Amount(minor)
+	return nil
+}
@@ -0,0 +1,179 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Amount
+		wantErr bool
+	}{
+		{"0", 0, false},
+		{"1250.5", 125050, false},
+		{"1250.50", 125050, false},
+		{"-3.2", -320, false},
+		{"0.005", 1, false},
+		{"0.004", 0, false},
+		{"-0.005", -1, false},
+		{"1.235", 124, false},
+		{" 12 ", 1200, false},
+		{"1e3", 100000, false},
+		{"", 0, true},
+		{"abc", 0, true},
+		{"1,000", 0, true},
+		{"1e30", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAmountFormatting(t *testing.T) {
+	tests := []struct {
+		amount  Amount
+		str     string
+		indian  string
+		grouped string
+	}{
+		{0, "0.00", "0.00", "0.00"},
+		{5, "0.05", "0.05", "0.05"},
+		{-5, "-0.05", "-0.05", "-0.05"},
+		{99999, "999.99", "999.99", "999.99"},
+		{123456789, "1234567.89", "12,34,567.89", "1,234,567.89"},
+		{-123456789, "-1234567.89", "-12,34,567.89", "-1,234,567.89"},
+		{10000000000, "100000000.00", "10,00,00,000.00", "100,000,000.00"},
+	}
+	for _, tt := range tests {
+		if got := tt.amount.String(); got != tt.str {
+			t.Errorf("Amount(%d).String() = %q, want %q", tt.amount, got, tt.str)
+		}
+		if got := tt.amount.Indian(); got != tt.indian {
+			t.Errorf("Amount(%d).Indian() = %q, want %q", tt.amount, got, tt.indian)
+		}
+		if got := tt.amount.Grouped(); got != tt.grouped {
+			t.Errorf("Amount(%d).Grouped() = %q, want %q", tt.amount, got, tt.grouped)
+		}
+	}
+}
+
+func TestMulDiv(t *testing.T) {
+	tests := []struct {
+		amount   Amount
+		num, den int64
+		want     Amount
+	}{
+		{10000, 18, 100, 1800},
+		{333, 1, 2, 167},
+		{-333, 1, 2, -167},
+		{100, 1, 3, 33},
+		{200, 1, 3, 67},
+		{9223372036854775, 1000, 1000, 9223372036854775},
+	}
+	for _, tt := range tests {
+		if got := tt.amount.MulDiv(tt.num, tt.den); got != tt.want {
+			t.Errorf("Amount(%d).MulDiv(%d, %d) = %d, want %d", tt.amount, tt.num, tt.den, got, tt.want)
+		}
+	}
+}
+
+func TestAmountJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Amount
+		wantErr bool
+	}{
+		{`1250.5`, 125050, false},
+		{`"1250.5"`, 125050, false},
+		{`-7`, -700, false},
+		{`null`, 0, false},
+		{`"x"`, 0, true},
+	}
+	for _, tt := range tests {
+		var got Amount
+		err := json.Unmarshal([]byte(tt.in), &got)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Unmarshal(%s) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	out, err := json.Marshal(struct {
+		Amount Amount `json:"amount"`
+	}{125050})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"amount":1250.50}` {
+		t.Errorf("Marshal = %s", out)
+	}
+}
+
+func TestAmountScan(t *testing.T) {
+	tests := []struct {
+		src     interface{}
+		want    Amount
+		wantErr bool
+	}{
+		{nil, 0, false},
+		{int64(125050), 125050, false},
+		{float64(42), 42, false},
+		{[]byte("125050"), 125050, false},
+		{"-300", -300, false},
+		{"12.5", 13, false},
+		{"x", 0, true},
+		{true, 0, true},
+	}
+	for _, tt := range tests {
+		var got Amount
+		err := got.Scan(tt.src)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Scan(%v) error = %v, wantErr %v", tt.src, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Scan(%v) = %d, want %d", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestRate(t *testing.T) {
+	tests := []struct {
+		rate   string
+		amount Amount
+		want   Amount
+		str    string
+	}{
+		{"1", 12345, 12345, "1"},
+		{"83.125", 100, 8313, "83.125"},
+		{"0.012", 100000, 1200, "0.012"},
+		{"0.00000001", 100, 0, "0.00000001"},
+	}
+	for _, tt := range tests {
+		r, err := ParseRate(tt.rate)
+		if err != nil {
+			t.Fatalf("ParseRate(%q): %v", tt.rate, err)
+		}
+		if got := r.Convert(tt.amount); got != tt.want {
+			t.Errorf("Rate(%s).Convert(%d) = %d, want %d", tt.rate, tt.amount, got, tt.want)
+		}
+		if got := r.String(); got != tt.str {
+			t.Errorf("Rate(%s).String() = %q, want %q", tt.rate, got, tt.str)
+		}
+	}
+	if _, err := ParseRate("abc"); err == nil {
+		t.Error("ParseRate(\"abc\") succeeded")
+	}
+}
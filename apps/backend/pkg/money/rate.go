@@ -0,0 +1,84 @@
+package money
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// rateDigits is the number of fraction digits kept for exchange rates
+const rateDigits = 8
+
+// supportedCurrencies lists the ISO 4217 codes that use two minor-unit digits,
+// which is what Amount assumes
+var supportedCurrencies = map[string]bool{
+	"INR": true, "USD": true, "EUR": true, "GBP": true, "AED": true,
+	"SAR": true, "QAR": true, "SGD": true, "AUD": true, "CAD": true,
+	"CHF": true, "CNY": true, "HKD": true, "NZD": true, "MYR": true,
+	"THB": true, "ZAR": true, "NPR": true, "LKR": true, "BDT": true,
+}
+
+// IsSupported reports whether a currency code can be used for amounts
+func IsSupported(currency string) bool {
+	return supportedCurrencies[currency]
+}
+
+// Rate is an exchange rate held as an integer scaled by 10^8. It serialises
+// to JSON as a decimal number.
+type Rate int64
+
+// One is the identity rate used when converting a currency to itself
+const One Rate = 100000000
+
+// ParseRate parses a decimal string such as "83.125" into a rate
+func ParseRate(s string) (Rate, error) {
+	v, err := parseFixed(s, rateDigits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	return Rate(v), nil
+}
+
+// String formats the rate as a decimal without trailing zeros
+func (r Rate) String() string {
+	return trimFixed(int64(r), rateDigits)
+}
+
+// Convert multiplies an amount by the rate, rounding half away from zero to
+// the nearest minor unit
+func (r Rate) Convert(a Amount) Amount {
+	return Amount(mulScaled(int64(a), int64(r), rateDigits))
+}
+
+// MarshalJSON encodes the rate as a JSON number
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a quoted decimal string
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	s, ok := jsonDecimal(data)
+	if !ok {
+		return nil
+	}
+	v, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
+
+// Value stores the rate as a scaled bigint
+func (r Rate) Value() (driver.Value, error) {
+	return int64(r), nil
+}
+
+// Scan reads a scaled rate from the database
+func (r *Rate) Scan(src interface{}) error {
+	var a Amount
+	if err := a.Scan(src); err != nil {
+		return fmt.Errorf("cannot scan %v into money.Rate", src)
+	}
+	*r = Rate(a)
+	return nil
+}
@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+var defaultClient = &http.Client{Timeout: 30 * time.Second}
+
+// postJSON posts body to url and decodes a successful response into out.
+// Client errors other than rate limiting are permanent; server and network
+// errors are worth retrying.
+func postJSON(ctx context.Context, client *http.Client, url, token string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(respBody))
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return Permanent(err)
+		}
+		return err
+	}
+
+	if out != nil && len(respBody) > 0 {
+		// The message was accepted; an unexpected body only loses the ID
+		_ = json.Unmarshal(respBody, out)
+	}
+	return nil
+}
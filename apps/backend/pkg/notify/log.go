@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"khatabook-go-backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// LogNotifier records messages instead of sending them. It writes one JSON
+// line per message to a writer, or to the application log when there is
+// none.
+type LogNotifier struct {
+	channel string
+	mu      sync.Mutex
+	w       io.Writer
+}
+
+// NewLog creates a notifier that stands in for channel. A nil writer logs
+// the messages.
+func NewLog(channel string, w io.Writer) *LogNotifier {
+	return &LogNotifier{channel: channel, w: w}
+}
+
+// OpenLogFile opens path for appending messages written by log notifiers
+func OpenLogFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+}
+
+// Send records msg and returns a generated message ID
+func (n *LogNotifier) Send(ctx context.Context, msg Message) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	id := uuid.New().String()
+	if n.w == nil {
+		logger.Infof("[%s] to %s: %s", n.channel, msg.To, msg.Body)
+		return id, nil
+	}
+
+	line, err := json.Marshal(map[string]interface{}{
+		"id":       id,
+		"time":     time.Now().Format(time.RFC3339),
+		"channel":  n.channel,
+		"to":       msg.To,
+		"subject":  msg.Subject,
+		"body":     msg.Body,
+		"template": msg.Template,
+		"language": msg.Language,
+		"params":   msg.Params,
+	})
+	if err != nil {
+		return "", Permanent(err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, err := n.w.Write(append(line, '\n')); err != nil {
+		return "", err
+	}
+	return id, nil
+}
@@ -0,0 +1,87 @@
+// Package notify delivers messages over email, SMS and WhatsApp. Each
+// channel is a Notifier; the Log notifier stands in for the real ones during
+// development.
+package notify
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// Channels
+const (
+	Email    = "email"
+	SMS      = "sms"
+	WhatsApp = "whatsapp"
+	Log      = "log"
+)
+
+// Channels lists every channel a message can be sent on
+var Channels = []string{Email, SMS, WhatsApp, Log}
+
+// Message is a notification to a single recipient. Subject is used by email
+// only. WhatsApp sends Template in Language with Params as the body
+// parameters, since business-initiated WhatsApp messages must use an
+// approved template; the other channels send Body.
+type Message struct {
+	To       string
+	Subject  string
+	Body     string
+	Template string
+	Language string
+	Params   []string
+}
+
+// Notifier sends messages on one channel. Send returns the provider's
+// message ID when it gives one.
+type Notifier interface {
+	Send(ctx context.Context, msg Message) (string, error)
+}
+
+// permanentError marks a failure that will not go away on retry
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as a failure that retrying cannot fix, such as an
+// invalid recipient or a rejected request
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// NormalizePhone reduces a phone number to digits with a country code,
+// assuming India for ten-digit numbers. It returns an empty string when the
+// number cannot be valid.
+func NormalizePhone(phone string) string {
+	var digits strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	number := digits.String()
+
+	switch {
+	case len(number) == 11 && number[0] == '0':
+		number = "91" + number[1:]
+	case len(number) == 10:
+		number = "91" + number
+	}
+	if len(number) < 11 || len(number) > 15 {
+		return ""
+	}
+	return number
+}
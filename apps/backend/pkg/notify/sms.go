@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// SMSConfig holds the settings of an HTTP SMS gateway
+type SMSConfig struct {
+	URL    string
+	APIKey string
+	Sender string
+}
+
+// SMSNotifier sends text messages through an HTTP SMS gateway. The gateway
+// receives a JSON body with to, from and message and may answer with the
+// message ID as id or message_id.
+type SMSNotifier struct {
+	cfg    SMSConfig
+	client *http.Client
+}
+
+// NewSMS creates an SMS notifier
+func NewSMS(cfg SMSConfig) *SMSNotifier {
+	return &SMSNotifier{cfg: cfg, client: defaultClient}
+}
+
+// Send sends msg.Body as a text message
+func (n *SMSNotifier) Send(ctx context.Context, msg Message) (string, error) {
+	to := NormalizePhone(msg.To)
+	if to == "" {
+		return "", Permanent(fmt.Errorf("invalid phone number %q", msg.To))
+	}
+
+	body := map[string]string{
+		"to":      to,
+		"from":    n.cfg.Sender,
+		"message": msg.Body,
+	}
+	var resp struct {
+		ID        string `json:"id"`
+		MessageID string `json:"message_id"`
+	}
+	if err := postJSON(ctx, n.client, n.cfg.URL, n.cfg.APIKey, body, &resp); err != nil {
+		return "", err
+	}
+
+	if resp.MessageID != "" {
+		return resp.MessageID, nil
+	}
+	return resp.ID, nil
+}
@@ -0,0 +1,96 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SMTPConfig holds the settings of an SMTP server
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPNotifier sends plain-text email through an SMTP server
+type SMTPNotifier struct {
+	cfg SMTPConfig
+}
+
+// NewSMTP creates an email notifier
+func NewSMTP(cfg SMTPConfig) *SMTPNotifier {
+	if cfg.Port == "" {
+		cfg.Port = "587"
+	}
+	return &SMTPNotifier{cfg: cfg}
+}
+
+// Send sends msg as an email. SMTP offers no cancellation, so ctx is only
+// checked before connecting.
+func (n *SMTPNotifier) Send(ctx context.Context, msg Message) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	from, err := mail.ParseAddress(n.cfg.From)
+	if err != nil {
+		return "", Permanent(fmt.Errorf("invalid sender address %q", n.cfg.From))
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return "", Permanent(fmt.Errorf("invalid email address %q", msg.To))
+	}
+
+	domain := from.Address[strings.LastIndex(from.Address, "@")+1:]
+	messageID := fmt.Sprintf("<%s@%s>", uuid.New().String(), domain)
+
+	var body bytes.Buffer
+	qp := quotedprintable.NewWriter(&body)
+	if _, err := qp.Write([]byte(msg.Body)); err != nil {
+		return "", Permanent(err)
+	}
+	if err := qp.Close(); err != nil {
+		return "", Permanent(err)
+	}
+
+	var data bytes.Buffer
+	fmt.Fprintf(&data, "From: %s\r\n", from.String())
+	fmt.Fprintf(&data, "To: %s\r\n", to.String())
+	fmt.Fprintf(&data, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&data, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&data, "Message-ID: %s\r\n", messageID)
+	data.WriteString("MIME-Version: 1.0\r\n")
+	data.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	data.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	data.Write(body.Bytes())
+
+	var auth smtp.Auth
+	if n.cfg.Username != "" {
+		auth = smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)
+	}
+
+	addr := net.JoinHostPort(n.cfg.Host, n.cfg.Port)
+	if err := smtp.SendMail(addr, auth, from.Address, []string{to.Address}, data.Bytes()); err != nil {
+		// 5xx replies such as an unknown mailbox will fail again
+		var reply *textproto.Error
+		if errors.As(err, &reply) && reply.Code >= 500 {
+			return "", Permanent(err)
+		}
+		return "", err
+	}
+	return messageID, nil
+}
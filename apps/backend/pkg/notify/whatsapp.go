@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DefaultWhatsAppURL is the WhatsApp Cloud API endpoint
+const DefaultWhatsAppURL = "https://graph.facebook.com/v19.0"
+
+// WhatsAppConfig holds the WhatsApp Cloud API settings. Template and
+// Language are used for messages that do not name their own.
+type WhatsAppConfig struct {
+	URL           string
+	Token         string
+	PhoneNumberID string
+	Template      string
+	Language      string
+}
+
+// WhatsAppNotifier sends approved template messages through the WhatsApp
+// Cloud API
+type WhatsAppNotifier struct {
+	cfg    WhatsAppConfig
+	client *http.Client
+}
+
+// NewWhatsApp creates a WhatsApp notifier
+func NewWhatsApp(cfg WhatsAppConfig) *WhatsAppNotifier {
+	if cfg.URL == "" {
+		cfg.URL = DefaultWhatsAppURL
+	}
+	if cfg.Language == "" {
+		cfg.Language = "en"
+	}
+	return &WhatsAppNotifier{cfg: cfg, client: defaultClient}
+}
+
+// Send sends msg as a template message with msg.Params as the body
+// parameters
+func (n *WhatsAppNotifier) Send(ctx context.Context, msg Message) (string, error) {
+	to := NormalizePhone(msg.To)
+	if to == "" {
+		return "", Permanent(fmt.Errorf("invalid phone number %q", msg.To))
+	}
+
+	template := msg.Template
+	if template == "" {
+		template = n.cfg.Template
+	}
+	if template == "" {
+		return "", Permanent(errors.New("no WhatsApp template configured"))
+	}
+
+	language := msg.Language
+	if language == "" {
+		language = n.cfg.Language
+	}
+
+	parameters := make([]map[string]string, len(msg.Params))
+	for i, param := range msg.Params {
+		parameters[i] = map[string]string{"type": "text", "text": param}
+	}
+	body := map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"to":                to,
+		"type":              "template",
+		"template": map[string]interface{}{
+			"name":     template,
+			"language": map[string]string{"code": language},
+			"components": []map[string]interface{}{
+				{"type": "body", "parameters": parameters},
+			},
+		},
+	}
+
+	var resp struct {
+		Messages []struct {
+			ID string `json:"id"`
+		} `json:"messages"`
+	}
+	url := strings.TrimRight(n.cfg.URL, "/") + "/" + n.cfg.PhoneNumberID + "/messages"
+	if err := postJSON(ctx, n.client, url, n.cfg.Token, body, &resp); err != nil {
+		return "", err
+	}
+
+	if len(resp.Messages) > 0 {
+		return resp.Messages[0].ID, nil
+	}
+	return "", nil
+}
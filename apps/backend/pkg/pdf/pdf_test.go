@@ -0,0 +1,115 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"a(b)c", `a\(b\)c`},
+		{`back\slash`, `back\\slash`},
+		{"two\nlines\r", "two lines "},
+		{"₹ 500", "? 500"},
+		{"café", "caf\xe9"},
+	}
+	for _, tt := range tests {
+		if got := escape(tt.in); got != tt.want {
+			t.Errorf("escape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		size float64
+		bold bool
+		want float64
+	}{
+		{"", 10, false, 0},
+		{"A", 10, false, 6.67},
+		{"A", 10, true, 7.22},
+		{"Il1", 12, false, (278 + 222 + 556) * 12.0 / 1000},
+		{"é", 10, false, 5.56},
+		{"₹", 10, false, 5.56},
+	}
+	for _, tt := range tests {
+		got := TextWidth(tt.s, tt.size, tt.bold)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("TextWidth(%q, %v, %t) = %v, want %v", tt.s, tt.size, tt.bold, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := Truncate("Short", 100, 10, false); got != "Short" {
+		t.Errorf("Truncate kept %q, want it unchanged", got)
+	}
+
+	long := "A party name far too long for its column"
+	for _, width := range []float64{20, 50, 100} {
+		got := Truncate(long, width, 10, false)
+		if !strings.HasSuffix(got, "...") {
+			t.Errorf("Truncate(width %v) = %q, want an ellipsis", width, got)
+		}
+		if w := TextWidth(got, 10, false); w > width {
+			t.Errorf("Truncate(width %v) = %q is %v wide", width, got, w)
+		}
+		if !strings.HasPrefix(long, strings.TrimSuffix(got, "...")) {
+			t.Errorf("Truncate(width %v) = %q is not a prefix of the text", width, got)
+		}
+	}
+}
+
+func TestDocumentStructure(t *testing.T) {
+	doc := New("Statement (May)")
+	for i := 0; i < 3; i++ {
+		page := doc.AddPage()
+		page.Text(40, 40, 12, i == 0, fmt.Sprintf("Page %d", i+1))
+		page.Line(40, 50, 200, 50, 0.5)
+	}
+
+	out, err := doc.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatalf("document is not framed as a PDF")
+	}
+	if !bytes.Contains(out, []byte("/Count 3")) {
+		t.Errorf("page tree does not count 3 pages")
+	}
+	if !bytes.Contains(out, []byte(`/Title (Statement \(May\))`)) {
+		t.Errorf("title is not escaped")
+	}
+
+	// Every xref offset must point at the start of its object
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	if m == nil {
+		t.Fatal("no startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[xref:], -1)
+	if len(entries) != 4+2*3+1 {
+		t.Fatalf("xref has %d objects, want %d", len(entries), 4+2*3+1)
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		want := fmt.Sprintf("%d 0 obj\n", i+1)
+		if !bytes.HasPrefix(out[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q", i+1, out[offset:offset+len(want)])
+		}
+	}
+}
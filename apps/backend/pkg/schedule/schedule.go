@@ -0,0 +1,141 @@
+// Package schedule computes the occurrence dates of simple recurrence
+// rules, a subset of iCalendar RRULE: every N days, every N weeks, or every N
+// months on a given day of the month.
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+// Frequencies
+const (
+	Daily   = "daily"
+	Weekly  = "weekly"
+	Monthly = "monthly"
+)
+
+const dateLayout = "2006-01-02"
+
+// Rule describes when something recurs. DayOfMonth only applies to monthly
+// rules; 0 means the day of the start date, and days past the end of a
+// short month fall on its last day.
+type Rule struct {
+	Frequency  string
+	Interval   int
+	DayOfMonth int
+}
+
+// Validate reports whether the rule can produce occurrences
+func (r Rule) Validate() error {
+	switch r.Frequency {
+	case Daily, Weekly, Monthly:
+	default:
+		return fmt.Errorf("unknown frequency %q", r.Frequency)
+	}
+	if r.Interval < 1 {
+		return fmt.Errorf("interval must be at least 1")
+	}
+	if r.DayOfMonth < 0 || r.DayOfMonth > 31 {
+		return fmt.Errorf("day of month must be between 1 and 31")
+	}
+	return nil
+}
+
+// First returns the first occurrence on or after from of a rule that starts
+// on anchor. The occurrences keep the interval counted from the anchor, so a
+// fortnightly or two-monthly rule does not shift when it is rescheduled.
+// Weekly rules keep the anchor's weekday and monthly rules the anchor's day
+// unless DayOfMonth is set.
+func (r Rule) First(from, anchor string) (string, error) {
+	day, err := time.Parse(dateLayout, from)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q", from)
+	}
+	start, err := time.Parse(dateLayout, anchor)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q", anchor)
+	}
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	// The first occurrence of all is on or after the anchor
+	dom := r.DayOfMonth
+	if dom == 0 {
+		dom = start.Day()
+	}
+	if r.Frequency == Monthly {
+		first := onDay(start.Year(), start.Month(), dom)
+		if first.Before(start) {
+			first = onDay(start.Year(), start.Month()+1, dom)
+		}
+		start = first
+	}
+	if !start.Before(day) {
+		return start.Format(dateLayout), nil
+	}
+
+	// Skip whole intervals up to from
+	switch r.Frequency {
+	case Daily, Weekly:
+		step := interval
+		if r.Frequency == Weekly {
+			step *= 7
+		}
+		days := int(day.Sub(start).Hours() / 24)
+		n := (days + step - 1) / step
+		day = start.AddDate(0, 0, n*step)
+	case Monthly:
+		months := (day.Year()-start.Year())*12 + int(day.Month()-start.Month())
+		k := months / interval * interval
+		next := onDay(start.Year(), start.Month()+time.Month(k), dom)
+		if next.Before(day) {
+			next = onDay(start.Year(), start.Month()+time.Month(k+interval), dom)
+		}
+		day = next
+	}
+	return day.Format(dateLayout), nil
+}
+
+// Next returns the occurrence after the one on date. For monthly rules the
+// anchor is the rule's day of month, or the day of anchor when it is 0, so a
+// rule started on the 31st keeps returning to month ends.
+func (r Rule) Next(date, anchor string) (string, error) {
+	day, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q", date)
+	}
+
+	switch r.Frequency {
+	case Daily:
+		day = day.AddDate(0, 0, r.Interval)
+	case Weekly:
+		day = day.AddDate(0, 0, 7*r.Interval)
+	case Monthly:
+		dom := r.DayOfMonth
+		if dom == 0 {
+			anchorDay, err := time.Parse(dateLayout, anchor)
+			if err != nil {
+				return "", fmt.Errorf("invalid date %q", anchor)
+			}
+			dom = anchorDay.Day()
+		}
+		day = onDay(day.Year(), day.Month()+time.Month(r.Interval), dom)
+	default:
+		return "", fmt.Errorf("unknown frequency %q", r.Frequency)
+	}
+	return day.Format(dateLayout), nil
+}
+
+// onDay returns the given day of a month, or the month's last day if it is
+// shorter. Months past December roll into the following years.
+func onDay(year int, month time.Month, dom int) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	if dom > last {
+		dom = last
+	}
+	return time.Date(first.Year(), first.Month(), dom, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,101 @@
+package schedule
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		rule    Rule
+		wantErr bool
+	}{
+		{Rule{Frequency: Daily, Interval: 1}, false},
+		{Rule{Frequency: Monthly, Interval: 3, DayOfMonth: 31}, false},
+		{Rule{Frequency: "yearly", Interval: 1}, true},
+		{Rule{Frequency: Weekly, Interval: 0}, true},
+		{Rule{Frequency: Monthly, Interval: 1, DayOfMonth: 32}, true},
+		{Rule{Frequency: Monthly, Interval: 1, DayOfMonth: -1}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.rule.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%+v.Validate() error = %v, wantErr %v", tt.rule, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFirst(t *testing.T) {
+	tests := []struct {
+		name         string
+		rule         Rule
+		from, anchor string
+		want         string
+	}{
+		{"daily on an occurrence", Rule{Frequency: Daily, Interval: 2}, "2024-03-05", "2024-01-01", "2024-03-05"},
+		{"daily keeps the interval phase", Rule{Frequency: Daily, Interval: 2}, "2024-03-06", "2024-01-01", "2024-03-07"},
+		{"starts on the anchor", Rule{Frequency: Daily, Interval: 1}, "2024-01-01", "2024-03-05", "2024-03-05"},
+		{"fortnightly keeps the interval phase", Rule{Frequency: Weekly, Interval: 2}, "2024-03-09", "2024-03-01", "2024-03-15"},
+		{"two-monthly keeps the interval phase", Rule{Frequency: Monthly, Interval: 2}, "2024-02-05", "2024-01-31", "2024-03-31"},
+		{"two-monthly on an occurrence", Rule{Frequency: Monthly, Interval: 2}, "2024-03-31", "2024-01-31", "2024-03-31"},
+		{"quarterly across a year", Rule{Frequency: Monthly, Interval: 3, DayOfMonth: 10}, "2024-11-11", "2024-02-01", "2025-02-10"},
+		{"weekly keeps the anchor weekday", Rule{Frequency: Weekly, Interval: 1}, "2024-03-05", "2024-03-01", "2024-03-08"},
+		{"weekly on the anchor weekday", Rule{Frequency: Weekly, Interval: 1}, "2024-03-08", "2024-03-01", "2024-03-08"},
+		{"monthly later this month", Rule{Frequency: Monthly, Interval: 1, DayOfMonth: 20}, "2024-03-05", "2024-03-05", "2024-03-20"},
+		{"monthly next month", Rule{Frequency: Monthly, Interval: 1, DayOfMonth: 2}, "2024-03-05", "2024-03-05", "2024-04-02"},
+		{"monthly anchor day", Rule{Frequency: Monthly, Interval: 1}, "2024-03-05", "2024-01-15", "2024-03-15"},
+		{"monthly clamped to a short month", Rule{Frequency: Monthly, Interval: 1, DayOfMonth: 31}, "2024-02-10", "2024-02-10", "2024-02-29"},
+		{"monthly rolls into the next year", Rule{Frequency: Monthly, Interval: 1, DayOfMonth: 1}, "2024-12-02", "2024-12-02", "2025-01-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rule.First(tt.from, tt.anchor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("First(%s, %s) = %s, want %s", tt.from, tt.anchor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name         string
+		rule         Rule
+		date, anchor string
+		want         string
+	}{
+		{"daily", Rule{Frequency: Daily, Interval: 3}, "2024-02-27", "", "2024-03-01"},
+		{"weekly", Rule{Frequency: Weekly, Interval: 2}, "2024-12-25", "", "2025-01-08"},
+		{"monthly", Rule{Frequency: Monthly, Interval: 1, DayOfMonth: 15}, "2024-01-15", "", "2024-02-15"},
+		{"quarterly across a year", Rule{Frequency: Monthly, Interval: 3, DayOfMonth: 10}, "2024-11-10", "", "2025-02-10"},
+		{"month end clamped", Rule{Frequency: Monthly, Interval: 1}, "2024-01-31", "2024-01-31", "2024-02-29"},
+		{"month end recovers after a short month", Rule{Frequency: Monthly, Interval: 1}, "2024-02-29", "2024-01-31", "2024-03-31"},
+		{"non-leap February", Rule{Frequency: Monthly, Interval: 1, DayOfMonth: 30}, "2023-01-30", "", "2023-02-28"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rule.Next(tt.date, tt.anchor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Next(%s) = %s, want %s", tt.date, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDateErrors(t *testing.T) {
+	rule := Rule{Frequency: Monthly, Interval: 1}
+	if _, err := rule.First("2024-02-30", "2024-01-01"); err == nil {
+		t.Error("First accepted an invalid from date")
+	}
+	if _, err := rule.First("2024-02-01", "x"); err == nil {
+		t.Error("First accepted an invalid anchor")
+	}
+	if _, err := rule.Next("2024-02-01", "x"); err == nil {
+		t.Error("Next accepted an invalid anchor")
+	}
+	if _, err := (Rule{Frequency: "yearly", Interval: 1}).Next("2024-02-01", ""); err == nil {
+		t.Error("Next accepted an unknown frequency")
+	}
+}